@@ -0,0 +1,221 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ident helps to bind an identifier, like table name or column name, in
+// [QueryRequest.WithSqlArgs]. The identifier will be validated and quoted,
+// and it can be qualified by database like `public.monitor`.
+type Ident string
+
+// TimestampArg helps to bind time.Time with the specified precision in
+// [QueryRequest.WithSqlArgs]. time.Time without TimestampArg is bound in
+// millisecond, which is the default precision of [Metric].
+type TimestampArg struct {
+	Time      time.Time
+	Precision time.Duration
+}
+
+var identPartRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_:\-]*$`)
+
+// quoteIdent validates and quotes the identifier in double quotes
+func quoteIdent(ident string) (string, error) {
+	parts := strings.Split(ident, ".")
+	for i, part := range parts {
+		if !identPartRegex.MatchString(part) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidIdentifier, ident)
+		}
+		parts[i] = `"` + part + `"`
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// quoteString quotes the string in single quotes, and the single quote in the
+// string is escaped by doubling it. Backslash is not an escape character in
+// GreptimeDB SQL, so it is kept as is.
+func quoteString(s string) (string, error) {
+	if strings.ContainsRune(s, 0) {
+		return "", fmt.Errorf("string argument MUST NOT contain NUL character: %q", s)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'", nil
+}
+
+func formatTimestamp(t time.Time, precision time.Duration) (string, error) {
+	if precision == 0 {
+		precision = time.Millisecond
+	}
+
+	var layout string
+	switch precision {
+	case time.Second:
+		layout = "2006-01-02 15:04:05Z07:00"
+	case time.Millisecond:
+		layout = "2006-01-02 15:04:05.000Z07:00"
+	case time.Microsecond:
+		layout = "2006-01-02 15:04:05.000000Z07:00"
+	case time.Nanosecond:
+		layout = "2006-01-02 15:04:05.000000000Z07:00"
+	default:
+		return "", ErrInvalidTimePrecision
+	}
+
+	return "'" + t.UTC().Format(layout) + "'", nil
+}
+
+// formatSqlArg formats the argument into SQL literal
+func formatSqlArg(arg any) (string, error) {
+	switch t := arg.(type) {
+	case nil:
+		return "NULL", nil
+	case Ident:
+		return quoteIdent(string(t))
+	case TimestampArg:
+		return formatTimestamp(t.Time, t.Precision)
+	case time.Time:
+		return formatTimestamp(t, time.Millisecond)
+	case string:
+		return quoteString(t)
+	case []byte:
+		if t == nil {
+			return "NULL", nil
+		}
+		return "X'" + hex.EncodeToString(t) + "'", nil
+	case bool:
+		if t {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case float64:
+		return formatFloat(t, 64)
+	case float32:
+		return formatFloat(float64(t), 32)
+	case int:
+		return strconv.FormatInt(int64(t), 10), nil
+	case int8:
+		return strconv.FormatInt(int64(t), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(t), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(t), 10), nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(t), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(t), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(t), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(t), 10), nil
+	case uint64:
+		return strconv.FormatUint(t, 10), nil
+	case driver.Valuer:
+		// the nil pointer of Valuer implemented by value is NULL, like database/sql
+		if rv := reflect.ValueOf(t); rv.Kind() == reflect.Pointer && rv.IsNil() &&
+			rv.Type().Elem().Implements(reflect.TypeOf((*driver.Valuer)(nil)).Elem()) {
+			return "NULL", nil
+		}
+		v, err := t.Value()
+		if err != nil {
+			return "", err
+		}
+		return formatSqlArg(v)
+	}
+
+	// pointers of the supported types, nil pointer is NULL
+	if rv := reflect.ValueOf(arg); rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		if v, err := convert(arg); err == nil {
+			return formatSqlArg(v.val)
+		}
+	}
+
+	return "", fmt.Errorf("the type '%T' is not supported in sql argument", arg)
+}
+
+func formatFloat(f float64, bitSize int) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("float argument '%v' is not supported", f)
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize), nil
+}
+
+// bindSql replaces the `?` placeholders in sql with the formatted arguments.
+// The placeholders in quoted strings, quoted identifiers and comments are ignored.
+func bindSql(sql string, args []any) (string, error) {
+	var sb strings.Builder
+	sb.Grow(len(sql))
+
+	argIdx := 0
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := strings.IndexByte(sql[i+1:], ch)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated quote %q in sql", ch)
+			}
+			end += i + 1
+			sb.WriteString(sql[i : end+1])
+			i = end
+		case ch == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i - 1
+			}
+			sb.WriteString(sql[i : i+end+1])
+			i += end
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated comment in sql")
+			}
+			end += i + 2 + len("*/")
+			sb.WriteString(sql[i:end])
+			i = end - 1
+		case ch == '?':
+			if argIdx >= len(args) {
+				return "", fmt.Errorf("%w: more placeholders than %d arguments", ErrArgsMismatch, len(args))
+			}
+			literal, err := formatSqlArg(args[argIdx])
+			if err != nil {
+				return "", fmt.Errorf("failed to bind argument %d: %w", argIdx+1, err)
+			}
+			sb.WriteString(literal)
+			argIdx++
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+
+	if argIdx != len(args) {
+		return "", fmt.Errorf("%w: %d placeholders but %d arguments", ErrArgsMismatch, argIdx, len(args))
+	}
+
+	return sb.String(), nil
+}
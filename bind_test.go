@@ -0,0 +1,121 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSqlArg(t *testing.T) {
+	var nilInt *int
+	i := 10
+	ts := time.Date(2023, 8, 1, 10, 20, 30, 123456789, time.UTC)
+	tests := []struct {
+		arg      any
+		expected string
+	}{
+		{nil, "NULL"},
+		{nilInt, "NULL"},
+		{&i, "10"},
+		{[]byte(nil), "NULL"},
+		{true, "TRUE"},
+		{false, "FALSE"},
+		{int8(-1), "-1"},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{float32(1.5), "1.5"},
+		{1.25, "1.25"},
+		{"it's", "'it''s'"},
+		{`a\'b`, `'a\''b'`},
+		{[]byte("ab"), "X'6162'"},
+		{ts, "'2023-08-01 10:20:30.123Z'"},
+		{TimestampArg{ts, time.Second}, "'2023-08-01 10:20:30Z'"},
+		{TimestampArg{ts, time.Microsecond}, "'2023-08-01 10:20:30.123456Z'"},
+		{TimestampArg{ts, time.Nanosecond}, "'2023-08-01 10:20:30.123456789Z'"},
+		{Ident("monitor"), `"monitor"`},
+		{Ident("public.monitor"), `"public"."monitor"`},
+		{sql.NullString{String: "a", Valid: true}, "'a'"},
+		{sql.NullInt64{}, "NULL"},
+		{(*sql.NullString)(nil), "NULL"},
+		{&sql.NullString{String: "a", Valid: true}, "'a'"},
+	}
+
+	for _, test := range tests {
+		literal, err := formatSqlArg(test.arg)
+		assert.Nil(t, err, "arg: %v", test.arg)
+		assert.Equal(t, test.expected, literal)
+	}
+}
+
+func TestFormatSqlArgError(t *testing.T) {
+	args := []any{
+		math.NaN(),
+		math.Inf(1),
+		"a\x00b",
+		Ident(`monitor"; DROP TABLE monitor; --`),
+		Ident("1monitor"),
+		Ident(""),
+		TimestampArg{time.Now(), time.Minute},
+		struct{}{},
+	}
+
+	for _, arg := range args {
+		_, err := formatSqlArg(arg)
+		assert.NotNil(t, err, "arg: %v", arg)
+	}
+
+	_, err := formatSqlArg(Ident("a b"))
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+}
+
+func TestBindSql(t *testing.T) {
+	sql, err := bindSql("SELECT * FROM ? WHERE host = ? AND cpu > ?", []any{Ident("monitor"), "' OR 1=1 --", 0.5})
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM "monitor" WHERE host = ''' OR 1=1 --' AND cpu > 0.5`, sql)
+
+	// placeholders in quotes and comments are ignored
+	sql, err = bindSql("SELECT '?', \"?\", `?` -- ?\n FROM t /* ? */ WHERE a = ?", []any{1})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT '?', \"?\", `?` -- ?\n FROM t /* ? */ WHERE a = 1", sql)
+
+	sql, err = bindSql("SELECT 'it''s' WHERE a = ?", []any{"b"})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT 'it''s' WHERE a = 'b'", sql)
+
+	_, err = bindSql("SELECT * FROM t WHERE a = ? AND b = ?", []any{1})
+	assert.ErrorIs(t, err, ErrArgsMismatch)
+
+	_, err = bindSql("SELECT * FROM t WHERE a = ?", []any{1, 2})
+	assert.ErrorIs(t, err, ErrArgsMismatch)
+
+	_, err = bindSql("SELECT * FROM t WHERE a = 'unterminated", nil)
+	assert.NotNil(t, err)
+}
+
+func TestQueryRequestWithSqlArgs(t *testing.T) {
+	rb := NewQueryRequest().WithDatabase("public").WithSqlArgs("SELECT * FROM monitor WHERE host = ?", "localhost")
+	request, err := rb.buildGreptimeRequest(&Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM monitor WHERE host = 'localhost'", request.GetQuery().GetSql())
+
+	rb.WithSqlArgs("SELECT * FROM monitor WHERE host = ?")
+	request, err = rb.buildGreptimeRequest(&Config{})
+	assert.Nil(t, request)
+	assert.ErrorIs(t, err, ErrArgsMismatch)
+}
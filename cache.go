@@ -0,0 +1,115 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// defaultQueryCacheCapacity is the capacity of the LRU cache if not specified
+const defaultQueryCacheCapacity = 1024
+
+// QueryCache is the backend of the query result cache, see [Config.WithQueryCache].
+// It MUST be safe for concurrent use.
+//
+// The values are the results of queries, like [*Metric], which are shared by the
+// callers and are not serializable, so the cache is expected to be in memory.
+type QueryCache interface {
+	// Get returns the value if the key exists and is not expired
+	Get(key string) (any, bool)
+
+	// Set stores the value, which expires after ttl. ttl <= 0 means never expires.
+	Set(key string, value any, ttl time.Duration)
+}
+
+type lruEntry struct {
+	key      string
+	value    any
+	expireAt time.Time
+}
+
+func (e *lruEntry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && now.After(e.expireAt)
+}
+
+// lruQueryCache evicts the least recently used entry if the capacity is exceeded
+type lruQueryCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+// NewLRUQueryCache helps to init the in-memory LRU QueryCache, which holds at most
+// capacity entries. The capacity is 1024 if it is less than 1.
+func NewLRUQueryCache(capacity int) QueryCache {
+	if capacity < 1 {
+		capacity = defaultQueryCacheCapacity
+	}
+
+	return &lruQueryCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+func (c *lruQueryCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*lruEntry)
+	if entry.expired(time.Now()) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *lruQueryCache) Set(key string, value any, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value, entry.expireAt = value, expireAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, value: value, expireAt: expireAt})
+	for c.ll.Len() > c.capacity {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *lruQueryCache) remove(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry).key)
+}
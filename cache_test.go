@@ -0,0 +1,61 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUQueryCache(t *testing.T) {
+	cache := NewLRUQueryCache(2)
+
+	cache.Set("a", 1, 0)
+	cache.Set("b", 2, 0)
+	v, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	// b is the least recently used
+	cache.Set("c", 3, 0)
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	v, ok = cache.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3, v)
+
+	cache.Set("a", 4, 0)
+	v, ok = cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 4, v)
+
+	cache.Set("d", 5, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok = cache.Get("d")
+	assert.False(t, ok)
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+}
+
+func TestNewLRUQueryCacheDefaultCapacity(t *testing.T) {
+	cache := NewLRUQueryCache(0).(*lruQueryCache)
+	assert.Equal(t, defaultQueryCacheCapacity, cache.capacity)
+
+	cfg := NewCfg("localhost").WithQueryCache(nil, time.Minute)
+	assert.NotNil(t, cfg.QueryCache)
+	assert.Equal(t, time.Minute, cfg.QueryCacheTTL)
+}
@@ -16,10 +16,15 @@ package greptime
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 
 	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/GreptimeTeam/greptimedb-client-go/prom"
 	"github.com/apache/arrow/go/v13/arrow/flight"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -36,6 +41,18 @@ type Client struct {
 
 	// For `Promql` query
 	promqlClient greptimepb.PrometheusGatewayClient
+
+	// conn is shared by greptimeClient and promqlClient, and closed by [Client.Close]
+	conn *grpc.ClientConn
+
+	metrics *clientMetrics
+	tracer  *clientTracer
+
+	// extentMu guards the cached extents of range promql, see [Config.WithQueryCache]
+	extentMu sync.Mutex
+
+	// schemas are the schemas of tables, see [Config.WithSchemaValidation]
+	schemas schemaCache
 }
 
 // NewClient helps to create the greptimedb client, which will be responsible Write/Read data To/From GreptimeDB
@@ -47,58 +64,261 @@ func NewClient(cfg *Config) (*Client, error) {
 
 	conn, err := grpc.Dial(cfg.getGRPCAddr(), cfg.DialOptions...)
 	if err != nil {
+		cfg.getLogger().Error("failed to connect", "addr", cfg.getGRPCAddr(), "error", err)
+		flightClient.Close()
+		return nil, err
+	}
+
+	metrics, err := cfg.newMetrics()
+	if err != nil {
+		flightClient.Close()
+		conn.Close()
 		return nil, err
 	}
 
 	greptimeClient := greptimepb.NewGreptimeDatabaseClient(conn)
 	promqlClient := greptimepb.NewPrometheusGatewayClient(conn)
 
+	cfg.getLogger().Info("client is created", "addr", cfg.getGRPCAddr(), "database", cfg.Database)
+
 	return &Client{
 		cfg:            cfg,
 		flightClient:   flightClient,
 		greptimeClient: greptimeClient,
 		promqlClient:   promqlClient,
+		conn:           conn,
+		metrics:        metrics,
+		tracer:         cfg.newTracer(),
 	}, nil
 }
 
-// Insert helps to insert multiple rows of multiple tables into greptimedb
+// Close helps to close the gRPC and Flight connections of the client, the client
+// can not be used after closed.
+func (c *Client) Close() error {
+	var errs []error
+	if c.flightClient != nil {
+		if err := c.flightClient.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.conn != nil {
+		if err := c.conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Insert helps to insert multiple rows of multiple tables into greptimedb. If
+// greptimedb responds failure, the response is returned with an [*Error], whose
+// Table is set if only one table is inserted.
+//
+// If [Config.WithSchemaValidation] is specified, the inserts are validated before
+// sending, and nothing is sent if any column mismatches.
+//
+// If [Config.WithMaxRequestSize] is specified, the request larger than the size is
+// split into multiple requests, and [PartialInsertError] is returned if one fails.
 func (c *Client) Insert(ctx context.Context, req InsertsRequest) (*greptimepb.GreptimeResponse, error) {
+	var stale []string
+	if c.cfg.SchemaValidation {
+		validated, keys, err := c.validateSchema(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		req, stale = validated, keys
+	}
+
 	request, err := req.build(c.cfg)
 	if err != nil {
 		return nil, err
 	}
-	return c.greptimeClient.Handle(ctx, request, c.cfg.CallOptions...)
+
+	var resp *greptimepb.GreptimeResponse
+	if c.cfg.MaxRequestSize > 0 && proto.Size(request) > c.cfg.MaxRequestSize {
+		requests, ranges, err := splitRequest(request, c.cfg.MaxRequestSize)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = c.insertSplit(ctx, requests, ranges)
+		if c.cfg.SchemaValidation {
+			c.refreshSchemas(request, stale, err)
+		}
+		return resp, err
+	}
+
+	resp, err = c.handle(ctx, OperationInsert, request)
+	if err == nil {
+		err = ParseRespHeader(resp).Err()
+	}
+	if c.cfg.SchemaValidation {
+		c.refreshSchemas(request, stale, err)
+	}
+	if e, ok := err.(*Error); ok {
+		if inserts := request.GetInserts().GetInserts(); len(inserts) == 1 {
+			e.Table = inserts[0].GetTableName()
+		}
+	}
+	return resp, err
 }
 
-// Query helps to retrieve data from greptimedb
+// handle sends the request via unary call through the interceptors
+func (c *Client) handle(ctx context.Context, op string, request *greptimepb.GreptimeRequest) (*greptimepb.GreptimeResponse, error) {
+	resp, err := c.observer().invoke(ctx, op, request, func(ctx context.Context, call *Call) (proto.Message, error) {
+		request, err := greptimeRequestOf(call)
+		if err != nil {
+			return nil, err
+		}
+		var trailer metadata.MD
+		resp, err := c.greptimeClient.Handle(ctx, request, c.callOptions(grpc.Trailer(&trailer))...)
+		return resp, statusErrorOf(err, trailer)
+	})
+	r, _ := resp.(*greptimepb.GreptimeResponse)
+	return r, err
+}
+
+// Query helps to retrieve data from greptimedb. If greptimedb fails the query, an
+// [*Error] is returned, like [Client.Insert].
 func (c *Client) Query(ctx context.Context, req QueryRequest) (*Metric, error) {
-	request, err := req.buildGreptimeRequest(c.cfg)
+	if c.cfg.QueryCache != nil {
+		return c.cachedQuery(ctx, req)
+	}
+	return c.query(ctx, req)
+}
+
+func (c *Client) query(ctx context.Context, req QueryRequest) (*Metric, error) {
+	reader, err := c.doGet(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+	defer reader.Release()
+
+	return req.buildMetric(reader)
+}
 
-	b, err := proto.Marshal(request)
+// doGet fires the query via flight DoGet, and the caller is responsible
+// to release the returned reader
+func (c *Client) doGet(ctx context.Context, req QueryRequest) (*flight.Reader, error) {
+	request, err := req.buildGreptimeRequest(c.cfg)
 	if err != nil {
 		return nil, err
 	}
-	sr, err := c.flightClient.DoGet(ctx, &flight.Ticket{Ticket: b}, c.cfg.CallOptions...)
+
+	var reader *flight.Reader
+	_, err = c.observer().invoke(ctx, OperationQuery, request, func(ctx context.Context, call *Call) (proto.Message, error) {
+		request, err := greptimeRequestOf(call)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := proto.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+
+		reader, err = c.doGetTicket(ctx, &flight.Ticket{Ticket: b})
+		return nil, err
+	})
 	if err != nil {
+		if reader != nil {
+			reader.Release()
+		}
 		return nil, err
 	}
+	return reader, nil
+}
 
-	reader, err := flight.NewRecordReader(sr)
+func (c *Client) doGetTicket(ctx context.Context, ticket *flight.Ticket) (*flight.Reader, error) {
+	sr, err := c.flightClient.DoGet(ctx, ticket, c.cfg.CallOptions...)
 	if err != nil {
 		return nil, err
 	}
+	// the failure is received with the schema
+	reader, err := flight.NewRecordReader(sr)
+	if err != nil {
+		return nil, statusErrorOf(err, sr.Trailer())
+	}
+	return reader, nil
+}
+
+// callOptions returns the CallOptions of the config with the options appended
+func (c *Client) callOptions(opts ...grpc.CallOption) []grpc.CallOption {
+	return append(c.cfg.CallOptions[:len(c.cfg.CallOptions):len(c.cfg.CallOptions)], opts...)
+}
+
+// execute fires the query via unary call, which only works for statements
+// without result set, like INSERT, CREATE, ALTER, etc.
+func (c *Client) execute(ctx context.Context, req QueryRequest) (uint32, error) {
+	request, err := req.buildGreptimeRequest(c.cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.handle(ctx, OperationExecute, request)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := ParseRespHeader(resp).Err(); err != nil {
+		return 0, fmt.Errorf("failed to execute, %w", err)
+	}
 
-	return buildMetricFromReader(reader)
+	return resp.GetAffectedRows().GetValue(), nil
 }
 
 // PromqlQuery helps to retrieve data from greptimedb via InstantQuery or RangeQuery
 func (c *Client) PromqlQuery(ctx context.Context, req QueryRequest) (*greptimepb.PromqlResponse, error) {
+	if c.cfg.QueryCache != nil {
+		return c.cachedPromqlQuery(ctx, req)
+	}
+	return c.promqlQuery(ctx, req)
+}
+
+func (c *Client) promqlQuery(ctx context.Context, req QueryRequest) (*greptimepb.PromqlResponse, error) {
 	request, err := req.buildPromqlRequest(c.cfg)
 	if err != nil {
 		return nil, err
 	}
-	return c.promqlClient.Handle(ctx, request, c.cfg.CallOptions...)
+
+	resp, err := c.observer().invoke(ctx, OperationPromql, request, func(ctx context.Context, call *Call) (proto.Message, error) {
+		request, err := promqlRequestOf(call)
+		if err != nil {
+			return nil, err
+		}
+		var trailer metadata.MD
+		resp, err := c.promqlClient.Handle(ctx, request, c.callOptions(grpc.Trailer(&trailer))...)
+		return resp, statusErrorOf(err, trailer)
+	})
+	r, _ := resp.(*greptimepb.PromqlResponse)
+	return r, err
+}
+
+// PromqlQueryResult is like [Client.PromqlQuery], but the response is decoded into
+// [prom.QueryResult], you can retrieve [model.Vector], [model.Matrix] or [model.Scalar]
+// from it. The error responded by Prometheus API can be retrieved as [prom.Error],
+// and you can check the rate limited error via [prom.IsRateLimitedError].
+//
+// If [RangePromql.WithSplit] is specified, the range is split and the sub-ranges
+// are queried concurrently, and the matrices are merged into one result.
+func (c *Client) PromqlQueryResult(ctx context.Context, req QueryRequest) (*prom.QueryResult, error) {
+	if rp, ok := req.query.(*RangePromql); ok {
+		if subs := rp.split(); len(subs) > 0 {
+			return queryAndMergeMatrix(ctx, req.header, subs, rp.SplitConcurrency, c.promqlQueryResult)
+		}
+	}
+	return c.promqlQueryResult(ctx, req)
+}
+
+func (c *Client) promqlQueryResult(ctx context.Context, req QueryRequest) (*prom.QueryResult, error) {
+	resp, err := c.PromqlQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if header := ParseRespHeader(resp); header.IsRateLimited() {
+		return nil, &prom.Error{Type: prom.ErrRateLimited, Msg: header.Msg}
+	} else if err := header.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query promql, %w", err)
+	}
+
+	return prom.UnmarshalApiResponse(resp.GetBody())
 }
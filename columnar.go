@@ -0,0 +1,384 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+)
+
+// ColumnarMetric helps to build the columns of a table directly, which is much
+// cheaper than [Metric] for the high throughput, since the values are appended
+// into the typed columns without the per-row maps of [Series]:
+//
+//	metric := greptime.NewColumnarMetric()
+//	host := metric.StringTag("host")
+//	cpu := metric.FloatField("cpu")
+//	for _, sample := range samples {
+//		metric.AddRow(sample.Time)
+//		host.Set(sample.Host)
+//		cpu.Set(sample.Cpu)
+//	}
+//	insert := greptime.InsertRequest{}
+//	insert.WithTable("monitor").WithColumnarMetric(metric)
+//
+// [ColumnBuilder.Set] sets the value of the last row added via
+// [ColumnarMetric.AddRow], and the column is NULL in the rows it is not set.
+// The errors, like setting the same column twice in a row, are reported when
+// the request is built.
+//
+// ColumnarMetric is not safe for concurrent use.
+type ColumnarMetric struct {
+	naming             NamingPolicy
+	timestampAlias     string
+	timestampPrecision time.Duration
+
+	orders  []string
+	columns map[string]column
+	states  []*columnState
+	// builders are the *ColumnBuilder[T] of the columns
+	builders map[string]any
+
+	timestamps []time.Time
+	err        error
+}
+
+// NewColumnarMetric helps to init the ColumnarMetric
+func NewColumnarMetric() *ColumnarMetric {
+	return &ColumnarMetric{
+		columns:  map[string]column{},
+		builders: map[string]any{},
+	}
+}
+
+func (m *ColumnarMetric) addErr(err error) {
+	if err != nil {
+		m.err = errors.Join(m.err, err)
+	}
+}
+
+// SetNamingPolicy helps to specify how the names of the table and columns are
+// normalized, the same as [Metric.SetNamingPolicy]. It MUST be set before
+// adding columns.
+func (m *ColumnarMetric) SetNamingPolicy(naming NamingPolicy) error {
+	if len(m.timestampAlias) > 0 || len(m.orders) > 0 {
+		return ErrNamingPolicyAfterColumns
+	}
+	m.naming = naming
+	return nil
+}
+
+// SetTimePrecision set precision for ColumnarMetric, the same as [Metric.SetTimePrecision]
+func (m *ColumnarMetric) SetTimePrecision(precision time.Duration) error {
+	if !isValidPrecision(precision) {
+		return ErrInvalidTimePrecision
+	}
+	m.timestampPrecision = precision
+	return nil
+}
+
+// SetTimestampAlias helps to specify the timestamp column name, default is ts.
+func (m *ColumnarMetric) SetTimestampAlias(alias string) error {
+	alias, err := m.naming.normalize(alias)
+	if err != nil {
+		return err
+	}
+	m.timestampAlias = alias
+	return nil
+}
+
+// GetTimestampAlias get the timestamp column name, default is ts.
+func (m *ColumnarMetric) GetTimestampAlias() string {
+	if len(m.timestampAlias) == 0 {
+		return "ts"
+	}
+	return m.timestampAlias
+}
+
+// GetTagsAndFields get all column names, except timestamp column
+func (m *ColumnarMetric) GetTagsAndFields() []string {
+	dst := make([]string, len(m.orders))
+	copy(dst, m.orders)
+	return dst
+}
+
+// AddRow adds a row of the timestamp, the columns set afterwards are of the row
+func (m *ColumnarMetric) AddRow(t time.Time) {
+	m.timestamps = append(m.timestamps, t)
+}
+
+// RowCount returns the number of rows added
+func (m *ColumnarMetric) RowCount() int {
+	return len(m.timestamps)
+}
+
+func (m *ColumnarMetric) rowCount() int {
+	return len(m.timestamps)
+}
+
+func (m *ColumnarMetric) namingPolicy() NamingPolicy {
+	return m.naming
+}
+
+// Grow helps to reserve the capacity of n more rows for the timestamps, see
+// [ColumnBuilder.Grow] for the columns
+func (m *ColumnarMetric) Grow(n int) {
+	if n <= 0 {
+		return
+	}
+	timestamps := make([]time.Time, len(m.timestamps), len(m.timestamps)+n)
+	copy(timestamps, m.timestamps)
+	m.timestamps = timestamps
+}
+
+// columnValue is the types of the values of [ColumnBuilder]
+type columnValue interface {
+	int64 | uint64 | float64 | bool | string | []byte
+}
+
+// columnState is the column being built, and the rows which are NULL
+type columnState struct {
+	name  string
+	col   *greptimepb.Column
+	nulls mask
+	rows  int // the number of rows set, including NULLs
+}
+
+// next moves the column to the last row, the skipped rows are NULL
+func (c *columnState) next(m *ColumnarMetric) bool {
+	row := len(m.timestamps) - 1
+	if row < 0 {
+		m.addErr(fmt.Errorf("column %q is set before adding a row", c.name))
+		return false
+	}
+	if c.rows > row {
+		m.addErr(fmt.Errorf("column %q is set twice in row %d", c.name, row))
+		return false
+	}
+	for ; c.rows < row; c.rows++ {
+		c.nulls.set(uint(c.rows))
+	}
+	c.rows++
+	return true
+}
+
+// build builds the column of n rows, the rows not set at the end are NULL
+func (c *columnState) build(n int) (*greptimepb.Column, error) {
+	nulls := c.nulls
+	if c.rows < n {
+		nulls = mask{bs: *c.nulls.bs.Clone()}
+		for row := c.rows; row < n; row++ {
+			nulls.set(uint(row))
+		}
+	}
+	b, err := nulls.shrink(nullMaskByteSize(n))
+	if err != nil {
+		return nil, err
+	}
+	return &greptimepb.Column{
+		ColumnName:   c.col.ColumnName,
+		SemanticType: c.col.SemanticType,
+		Datatype:     c.col.Datatype,
+		Values:       c.col.Values,
+		NullMask:     b,
+	}, nil
+}
+
+// ColumnBuilder is a typed column of [ColumnarMetric], which is got via like
+// [ColumnarMetric.FloatField]
+type ColumnBuilder[T columnValue] struct {
+	metric *ColumnarMetric
+	state  *columnState
+	values *[]T
+}
+
+// Set sets the value of the column in the last row added via [ColumnarMetric.AddRow]
+func (c *ColumnBuilder[T]) Set(v T) {
+	if c.state.next(c.metric) {
+		*c.values = append(*c.values, v)
+	}
+}
+
+// Grow helps to reserve the capacity of n more values
+func (c *ColumnBuilder[T]) Grow(n int) {
+	if n <= 0 {
+		return
+	}
+	values := make([]T, len(*c.values), len(*c.values)+n)
+	copy(values, *c.values)
+	*c.values = values
+}
+
+func addColumn[T columnValue](m *ColumnarMetric, name string, semantic greptimepb.SemanticType,
+	datatype greptimepb.ColumnDataType, values func(*greptimepb.Column_Values) *[]T) *ColumnBuilder[T] {
+	col := &greptimepb.Column{
+		SemanticType: semantic,
+		Datatype:     datatype,
+		Values:       &greptimepb.Column_Values{},
+	}
+	// the builder not added into the metric, if the column is not valid
+	detached := &ColumnBuilder[T]{metric: m, state: &columnState{name: name, col: col}, values: values(col.Values)}
+
+	key, err := m.naming.normalize(name)
+	if err != nil {
+		m.addErr(err)
+		return detached
+	}
+	col.ColumnName = key
+	detached.state.name = key
+
+	newCol := column{typ: datatype, semantic: semantic}
+	if existing, seen := m.columns[key]; seen {
+		if err := checkColumnEquality(key, existing, newCol); err != nil {
+			m.addErr(err)
+			return detached
+		}
+		return m.builders[key].(*ColumnBuilder[T])
+	}
+
+	m.orders = append(m.orders, key)
+	m.columns[key] = newCol
+	m.states = append(m.states, detached.state)
+	m.builders[key] = detached
+	return detached
+}
+
+// IntTag helps to add the int64 tag column, the same column is returned if it
+// has been added
+func (m *ColumnarMetric) IntTag(name string) *ColumnBuilder[int64] {
+	return addColumn(m, name, greptimepb.SemanticType_TAG, greptimepb.ColumnDataType_INT64,
+		func(v *greptimepb.Column_Values) *[]int64 { return &v.I64Values })
+}
+
+// IntField helps to add the int64 field column
+func (m *ColumnarMetric) IntField(name string) *ColumnBuilder[int64] {
+	return addColumn(m, name, greptimepb.SemanticType_FIELD, greptimepb.ColumnDataType_INT64,
+		func(v *greptimepb.Column_Values) *[]int64 { return &v.I64Values })
+}
+
+// UintTag helps to add the uint64 tag column
+func (m *ColumnarMetric) UintTag(name string) *ColumnBuilder[uint64] {
+	return addColumn(m, name, greptimepb.SemanticType_TAG, greptimepb.ColumnDataType_UINT64,
+		func(v *greptimepb.Column_Values) *[]uint64 { return &v.U64Values })
+}
+
+// UintField helps to add the uint64 field column
+func (m *ColumnarMetric) UintField(name string) *ColumnBuilder[uint64] {
+	return addColumn(m, name, greptimepb.SemanticType_FIELD, greptimepb.ColumnDataType_UINT64,
+		func(v *greptimepb.Column_Values) *[]uint64 { return &v.U64Values })
+}
+
+// FloatTag helps to add the float64 tag column
+func (m *ColumnarMetric) FloatTag(name string) *ColumnBuilder[float64] {
+	return addColumn(m, name, greptimepb.SemanticType_TAG, greptimepb.ColumnDataType_FLOAT64,
+		func(v *greptimepb.Column_Values) *[]float64 { return &v.F64Values })
+}
+
+// FloatField helps to add the float64 field column
+func (m *ColumnarMetric) FloatField(name string) *ColumnBuilder[float64] {
+	return addColumn(m, name, greptimepb.SemanticType_FIELD, greptimepb.ColumnDataType_FLOAT64,
+		func(v *greptimepb.Column_Values) *[]float64 { return &v.F64Values })
+}
+
+// BoolTag helps to add the bool tag column
+func (m *ColumnarMetric) BoolTag(name string) *ColumnBuilder[bool] {
+	return addColumn(m, name, greptimepb.SemanticType_TAG, greptimepb.ColumnDataType_BOOLEAN,
+		func(v *greptimepb.Column_Values) *[]bool { return &v.BoolValues })
+}
+
+// BoolField helps to add the bool field column
+func (m *ColumnarMetric) BoolField(name string) *ColumnBuilder[bool] {
+	return addColumn(m, name, greptimepb.SemanticType_FIELD, greptimepb.ColumnDataType_BOOLEAN,
+		func(v *greptimepb.Column_Values) *[]bool { return &v.BoolValues })
+}
+
+// StringTag helps to add the string tag column
+func (m *ColumnarMetric) StringTag(name string) *ColumnBuilder[string] {
+	return addColumn(m, name, greptimepb.SemanticType_TAG, greptimepb.ColumnDataType_STRING,
+		func(v *greptimepb.Column_Values) *[]string { return &v.StringValues })
+}
+
+// StringField helps to add the string field column
+func (m *ColumnarMetric) StringField(name string) *ColumnBuilder[string] {
+	return addColumn(m, name, greptimepb.SemanticType_FIELD, greptimepb.ColumnDataType_STRING,
+		func(v *greptimepb.Column_Values) *[]string { return &v.StringValues })
+}
+
+// BytesTag helps to add the []byte tag column
+func (m *ColumnarMetric) BytesTag(name string) *ColumnBuilder[[]byte] {
+	return addColumn(m, name, greptimepb.SemanticType_TAG, greptimepb.ColumnDataType_BINARY,
+		func(v *greptimepb.Column_Values) *[][]byte { return &v.BinaryValues })
+}
+
+// BytesField helps to add the []byte field column
+func (m *ColumnarMetric) BytesField(name string) *ColumnBuilder[[]byte] {
+	return addColumn(m, name, greptimepb.SemanticType_FIELD, greptimepb.ColumnDataType_BINARY,
+		func(v *greptimepb.Column_Values) *[][]byte { return &v.BinaryValues })
+}
+
+// precision returns the precision of the timestamps, default is millisecond
+func (m *ColumnarMetric) precision() time.Duration {
+	if m.timestampPrecision == 0 {
+		return time.Millisecond
+	}
+	return m.timestampPrecision
+}
+
+// checkTimestamps is the same as [Metric.checkTimestamps]
+func (m *ColumnarMetric) checkTimestamps(zero ZeroTimestampPolicy, truncation TruncationPolicy) (int, error) {
+	return checkTimestamps(len(m.timestamps), func(i int) time.Time { return m.timestamps[i] },
+		m.precision(), zero, truncation)
+}
+
+// schema returns the Metric of the same columns without series, which helps to
+// validate the columns against the schema of the table
+func (m *ColumnarMetric) schema() Metric {
+	return Metric{
+		naming:             m.naming,
+		timestampAlias:     m.timestampAlias,
+		timestampPrecision: m.timestampPrecision,
+		orders:             m.orders,
+		columns:            m.columns,
+	}
+}
+
+func (m *ColumnarMetric) intoGreptimeColumn(zero ZeroTimestampPolicy) ([]*greptimepb.Column, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if len(m.timestamps) == 0 {
+		return nil, ErrNoSeriesInMetric
+	}
+
+	n := len(m.timestamps)
+	result := make([]*greptimepb.Column, 0, len(m.states)+1)
+	for _, state := range m.states {
+		col, err := state.build(n)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, col)
+	}
+
+	tsColumn, err := intoTimestampColumn(m.GetTimestampAlias(), m.timestampPrecision, n,
+		func(i int) time.Time { return m.timestamps[i] }, zero)
+	if err != nil {
+		return nil, err
+	}
+	return append(result, tsColumn), nil
+}
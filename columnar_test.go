@@ -0,0 +1,222 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestColumnarMetric(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+
+	columnar := NewColumnarMetric()
+	assert.Nil(t, columnar.SetTimePrecision(time.Second))
+	assert.Nil(t, columnar.SetTimestampAlias("created_at"))
+	host := columnar.StringTag("host")
+	healthy := columnar.BoolField("healthy")
+	cpu := columnar.FloatField("cpu")
+
+	metric := Metric{}
+	assert.Nil(t, metric.SetTimePrecision(time.Second))
+	assert.Nil(t, metric.SetTimestampAlias("created_at"))
+
+	for i := 0; i < 20; i++ {
+		ts := start.Add(time.Duration(i) * time.Second)
+		columnar.AddRow(ts)
+		series := Series{}
+		assert.Nil(t, series.SetTimestamp(ts))
+
+		host.Set(fmt.Sprintf("127.0.0.%d", i))
+		assert.Nil(t, series.AddStringTag("host", fmt.Sprintf("127.0.0.%d", i)))
+		if i%3 != 0 {
+			cpu.Set(float64(i))
+			assert.Nil(t, series.AddFloatField("cpu", float64(i)))
+		}
+		if i < 10 {
+			healthy.Set(i%2 == 0)
+			assert.Nil(t, series.AddBoolField("healthy", i%2 == 0))
+		}
+		if i == 15 {
+			// added after rows, so the previous rows are NULL
+			columnar.UintField("memory").Set(1024)
+			assert.Nil(t, series.AddUintField("memory", 1024))
+		}
+		assert.Nil(t, metric.AddSeries(series))
+	}
+
+	assert.Equal(t, 20, columnar.RowCount())
+	assert.Equal(t, []string{"host", "healthy", "cpu", "memory"}, columnar.GetTagsAndFields())
+
+	expected, err := (&InsertRequest{}).WithTable("monitor").WithMetric(metric).build(&Config{})
+	assert.Nil(t, err)
+	req, err := (&InsertRequest{}).WithTable("monitor").WithColumnarMetric(columnar).build(&Config{})
+	assert.Nil(t, err)
+	assert.True(t, proto.Equal(expected, req), "%v != %v", expected, req)
+
+	// built again after more rows
+	columnar.AddRow(start.Add(time.Minute))
+	cpu.Set(0.5)
+	req, err = (&InsertRequest{}).WithTable("monitor").WithColumnarMetric(columnar).build(&Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(21), req.RowCount)
+	assert.Equal(t, []byte{0, 0, 0b10000}, req.Columns[0].NullMask)
+	assert.Len(t, req.Columns[2].Values.F64Values, 14)
+	assert.Zero(t, req.Columns[2].NullMask[2]&0b10000)
+}
+
+func TestColumnarMetricLargeBatchNulls(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+
+	columnar := NewColumnarMetric()
+	cpu := columnar.FloatField("cpu")
+	for i := 0; i < 2000; i++ {
+		columnar.AddRow(start.Add(time.Duration(i) * time.Millisecond))
+		// NULL only in the first rows
+		if i >= 2 {
+			cpu.Set(float64(i))
+		}
+	}
+	// added late, so NULL only in the early rows
+	memory := columnar.UintField("memory")
+	memory.Set(1024)
+
+	req, err := (&InsertRequest{}).WithTable("monitor").WithColumnarMetric(columnar).build(&Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2000), req.RowCount)
+	assert.Len(t, req.Columns[0].NullMask, 250)
+	assert.Equal(t, byte(0b11), req.Columns[0].NullMask[0])
+	assert.Len(t, req.Columns[0].Values.F64Values, 1998)
+	assert.Len(t, req.Columns[1].NullMask, 250)
+	assert.Equal(t, byte(0b01111111), req.Columns[1].NullMask[249])
+	assert.Equal(t, []uint64{1024}, req.Columns[1].Values.U64Values)
+
+	insert, err := DecodeInsertRequest(req)
+	assert.Nil(t, err)
+	metric := insert.GetMetric()
+	series := metric.GetSeries()
+	assert.Len(t, series, 2000)
+	_, ok := series[1].Get("cpu")
+	assert.False(t, ok)
+	cpuValue, ok := series[2].Get("cpu")
+	assert.True(t, ok)
+	assert.Equal(t, float64(2), cpuValue)
+}
+
+func TestColumnarMetricErrors(t *testing.T) {
+	build := func(columnar *ColumnarMetric) error {
+		_, err := (&InsertRequest{}).WithTable("monitor").WithColumnarMetric(columnar).build(&Config{})
+		return err
+	}
+
+	columnar := NewColumnarMetric()
+	assert.ErrorIs(t, build(columnar), ErrNoSeriesInMetric)
+
+	columnar.IntField("count").Set(1)
+	columnar.AddRow(time.UnixMilli(1))
+	assert.ErrorContains(t, build(columnar), `column "count" is set before adding a row`)
+
+	columnar = NewColumnarMetric()
+	count := columnar.IntField("count")
+	columnar.AddRow(time.UnixMilli(1))
+	count.Set(1)
+	count.Set(2)
+	assert.ErrorContains(t, build(columnar), `column "count" is set twice in row 0`)
+
+	columnar = NewColumnarMetric()
+	columnar.AddRow(time.UnixMilli(1))
+	columnar.IntField("Count").Set(1)
+	// the same column is returned
+	assert.Same(t, columnar.IntField("count"), columnar.IntField(" count "))
+	columnar.StringField("count")
+	columnar.IntTag("count")
+	columnar.IntField("  ")
+	err := build(columnar)
+	assert.ErrorContains(t, err, "the type of 'count' does not match")
+	assert.ErrorContains(t, err, `tag and field MUST NOT contain same key: "count"`)
+	assert.ErrorIs(t, err, ErrEmptyKey)
+
+	columnar = NewColumnarMetric()
+	columnar.AddRow(time.Time{})
+	_, err = (&InsertRequest{}).WithTable("monitor").WithColumnarMetric(columnar).
+		build(&Config{ZeroTimestamp: ZeroTimestampReject})
+	assert.ErrorIs(t, err, ErrEmptyTimestamp)
+}
+
+func TestColumnarMetricNamingPolicy(t *testing.T) {
+	columnar := NewColumnarMetric()
+	assert.Nil(t, columnar.SetNamingPolicy(NamingPreserve))
+	assert.Nil(t, columnar.SetTimestampAlias("CreatedAt"))
+	columnar.AddRow(time.Time{})
+	columnar.IntField("HTTPStatus").Set(200)
+	assert.ErrorIs(t, columnar.SetNamingPolicy(NamingLowerCase), ErrNamingPolicyAfterColumns)
+
+	req, err := (&InsertRequest{}).WithTable("AppMonitor").WithColumnarMetric(columnar).
+		build(&Config{ZeroTimestamp: ZeroTimestampServerNow})
+	assert.Nil(t, err)
+	assert.Equal(t, "AppMonitor", req.TableName)
+	assert.Equal(t, "HTTPStatus", req.Columns[0].ColumnName)
+	assert.Equal(t, "CreatedAt", req.Columns[1].ColumnName)
+	assert.Equal(t, greptimepb.SemanticType_TIMESTAMP, req.Columns[1].SemanticType)
+	assert.Equal(t, []byte{0b1}, req.Columns[1].NullMask)
+}
+
+const benchmarkRows = 1000
+
+func BenchmarkMetricBuild(b *testing.B) {
+	start := time.UnixMilli(1700000000000)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		metric := Metric{}
+		for i := 0; i < benchmarkRows; i++ {
+			series := Series{}
+			_ = series.AddStringTag("host", "127.0.0.1")
+			_ = series.AddIntField("requests", int64(i))
+			_ = series.AddFloatField("cpu", float64(i)/benchmarkRows)
+			_ = series.SetTimestamp(start.Add(time.Duration(i) * time.Millisecond))
+			_ = metric.AddSeries(series)
+		}
+		if _, err := (&InsertRequest{}).WithTable("monitor").WithMetric(metric).build(&Config{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkColumnarMetricBuild(b *testing.B) {
+	start := time.UnixMilli(1700000000000)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		metric := NewColumnarMetric()
+		metric.Grow(benchmarkRows)
+		host, requests, cpu := metric.StringTag("host"), metric.IntField("requests"), metric.FloatField("cpu")
+		host.Grow(benchmarkRows)
+		requests.Grow(benchmarkRows)
+		cpu.Grow(benchmarkRows)
+		for i := 0; i < benchmarkRows; i++ {
+			metric.AddRow(start.Add(time.Duration(i) * time.Millisecond))
+			host.Set("127.0.0.1")
+			requests.Set(int64(i))
+			cpu.Set(float64(i) / benchmarkRows)
+		}
+		if _, err := (&InsertRequest{}).WithTable("monitor").WithColumnarMetric(metric).build(&Config{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -16,8 +16,13 @@ package greptime
 
 import (
 	"fmt"
+	"net/http"
+	"time"
 
 	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 )
 
@@ -31,6 +36,21 @@ import (
 //     But you can change the database in InsertRequest or QueryRequest.
 //   - DialOptions and CallOptions are for gRPC service.
 //     You can specify them or leave them empty.
+//   - HTTPPort, HTTPScheme and HTTPClient are for the HTTP service, which is only
+//     used by the APIs not provided in gRPC, like [Client.PromSeries].
+//   - QueryCache and QueryCacheTTL enable the query result cache, see [Config.WithQueryCache].
+//   - MetricsRegisterer enables the Prometheus metrics of the client, see [Config.WithMetrics].
+//   - TracerProvider and StatementRedaction enable the OpenTelemetry spans of the
+//     client, see [Config.WithTracing].
+//   - Logger is the structured logger of the client, logs are discarded by default.
+//   - Interceptors intercept the requests sent to greptimedb, see [Config.WithInterceptors].
+//   - SchemaValidation, SchemaCacheTTL and SchemaCoercion enable validating the inserts
+//     against the schemas of the tables, see [Config.WithSchemaValidation].
+//   - SchemaEvolutionTables are the tables allowed to add new fields, see [Config.WithSchemaEvolution].
+//   - ZeroTimestamp and TimestampTruncation are the policies of the timestamps of
+//     Series, see [Config.WithTimestampValidation].
+//   - MaxRequestSize is the max encoded size of an insert request, the larger ones
+//     are split, see [Config.WithMaxRequestSize].
 type Config struct {
 	Host     string // example: 127.0.0.1
 	Port     int    // default: 4001
@@ -38,6 +58,33 @@ type Config struct {
 	Password string
 	Database string // the default database for client
 
+	HTTPPort   int    // default: 4000
+	HTTPScheme string // default: http
+	HTTPClient *http.Client
+
+	QueryCache    QueryCache
+	QueryCacheTTL time.Duration
+
+	MetricsRegisterer prometheus.Registerer
+
+	TracerProvider     trace.TracerProvider
+	StatementRedaction StatementRedaction
+
+	Logger Logger
+
+	Interceptors []Interceptor
+
+	SchemaValidation bool
+	SchemaCacheTTL   time.Duration
+	SchemaCoercion   bool
+
+	SchemaEvolutionTables []string
+
+	ZeroTimestamp       ZeroTimestampPolicy
+	TimestampTruncation TruncationPolicy
+
+	MaxRequestSize int // default: 0, never split
+
 	// DialOptions are passed to grpc.DialContext
 	// when a new gRPC connection is to be created.
 	DialOptions []grpc.DialOption
@@ -49,8 +96,9 @@ type Config struct {
 // NewCfg helps to init Config with host only
 func NewCfg(host string) *Config {
 	return &Config{
-		Host: host,
-		Port: 4001,
+		Host:     host,
+		Port:     4001,
+		HTTPPort: 4000,
 
 		DialOptions: []grpc.DialOption{
 			grpc.WithUserAgent("greptimedb-client-go"),
@@ -79,6 +127,181 @@ func (c *Config) WithAuth(username, password string) *Config {
 	return c
 }
 
+// WithHTTPPort set the HTTPPort field. Do not change it if you have no idea what it is.
+func (c *Config) WithHTTPPort(port int) *Config {
+	c.HTTPPort = port
+	return c
+}
+
+// WithHTTPScheme helps to specify the scheme of HTTP service, http or https.
+func (c *Config) WithHTTPScheme(scheme string) *Config {
+	c.HTTPScheme = scheme
+	return c
+}
+
+// WithHTTPClient helps to specify the http.Client, default is http.DefaultClient.
+func (c *Config) WithHTTPClient(client *http.Client) *Config {
+	c.HTTPClient = client
+	return c
+}
+
+// WithQueryCache helps to cache the results of [Client.Query] and [Client.PromqlQuery]
+// for ttl, the in-memory LRU cache created by [NewLRUQueryCache] is used if cache is nil.
+//
+// The results are keyed by database, query text and time range. The range of
+// [RangePromql] is sent as it is, and in [Client.PromqlQuery] the ranges of the
+// same Step and evaluation times share the cached samples, so that only the new
+// tail of the shifting windows of dashboards is queried. The windows whose Start
+// is aligned to the multiples of Step, like the ones of Grafana, share the
+// evaluation times. The cached samples expire ttl after they are queried, even
+// if the range is extended later. [InstantPromql] without the evaluation time
+// is never cached.
+//
+// The cached [Metric] is shared by the callers, do not modify it.
+func (c *Config) WithQueryCache(cache QueryCache, ttl time.Duration) *Config {
+	if cache == nil {
+		cache = NewLRUQueryCache(defaultQueryCacheCapacity)
+	}
+	c.QueryCache = cache
+	c.QueryCacheTTL = ttl
+	return c
+}
+
+// WithMetrics helps to register the Prometheus metrics of [Client] and [StreamClient]
+// into reg, prometheus.DefaultRegisterer is used if reg is nil. The metrics are
+// prefixed with `greptimedb_client_`:
+//
+//   - requests_total and request_duration_seconds, by operation, database and status
+//   - rate_limited_total, by operation and database
+//   - table_requests_total, by operation, database, table and status
+//   - rows_written_total and sent_bytes_total, by operation, database and table
+//
+// The status is the status code responded by greptimedb, like 0 for success and
+// 6001 for rate limited, or the gRPC code like Unavailable if the request failed.
+// Each request is counted once in requests_total, and a request inserting into
+// multiple tables is counted for each table in table_requests_total. The table
+// is empty for queries.
+func (c *Config) WithMetrics(reg prometheus.Registerer) *Config {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	c.MetricsRegisterer = reg
+	return c
+}
+
+// WithTracing helps to start the OpenTelemetry spans of [Client.Insert], [Client.Query],
+// [Client.PromqlQuery] and [StreamClient.Send] via tp, the global TracerProvider
+// is used if tp is nil. The spans are named like `insert public`, with attributes:
+//
+//   - db.system, db.name, db.operation and db.statement, see [Config.WithStatementRedaction]
+//   - greptime.tables and greptime.rows for inserts
+//   - greptime.status_code responded by greptimedb
+//
+// The trace context is propagated to greptimedb in gRPC metadata in W3C format.
+// The span of [Client.Query] ends when the schema is received, since the records
+// are read lazily.
+func (c *Config) WithTracing(tp trace.TracerProvider) *Config {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	c.TracerProvider = tp
+	return c
+}
+
+// WithStatementRedaction helps to specify how the SQL or PromQL is recorded in the
+// spans, the statement is recorded as is by default.
+func (c *Config) WithStatementRedaction(redaction StatementRedaction) *Config {
+	c.StatementRedaction = redaction
+	return c
+}
+
+// WithLogger helps to log the lifecycle of the client and the failed requests,
+// see [NewLogrusLogger] and [NewSlogLogger]. Nothing is logged by default.
+func (c *Config) WithLogger(logger Logger) *Config {
+	c.Logger = logger
+	return c
+}
+
+// WithInterceptors helps to intercept the requests of [Client] and [StreamClient],
+// like auditing, quotas, or tagging every insert with a tenant column. The
+// interceptors are appended, and the first one is the outermost. The metrics, spans
+// and logs of the client observe the requests modified by the interceptors.
+func (c *Config) WithInterceptors(interceptors ...Interceptor) *Config {
+	c.Interceptors = append(c.Interceptors, interceptors...)
+	return c
+}
+
+// WithSchemaValidation helps to validate the inserts of [Client.Insert] against the
+// schemas of the existing tables before sending, so that the mismatched columns are
+// reported as [*Error] for each column, instead of failing the whole request in
+// greptimedb. The following are validated:
+//
+//   - semantic type and data type of the existing tags and fields
+//   - name and precision of the timestamp column
+//
+// The schemas are retrieved from information_schema.columns and cached for ttl,
+// ttl <= 0 means never expires. The schemas of the tables are refreshed if the
+// insert fails, or the table is created or altered by the insert.
+func (c *Config) WithSchemaValidation(ttl time.Duration) *Config {
+	c.SchemaValidation = true
+	c.SchemaCacheTTL = ttl
+	return c
+}
+
+// WithSchemaCoercion helps to convert the numbers into the data types of the
+// existing columns in schema validation, like int64 into FLOAT64, if the values
+// can be represented exactly. It implies [Config.WithSchemaValidation] if not
+// specified.
+func (c *Config) WithSchemaCoercion() *Config {
+	c.SchemaValidation = true
+	c.SchemaCoercion = true
+	return c
+}
+
+// WithSchemaEvolution helps to add the new fields of the existing tables via
+// `ALTER TABLE ... ADD COLUMN` before inserting, instead of failing the insert.
+// Only the tables in the allow-list are altered, and the changes are additive:
+//
+//   - the fields absent in the table are added as nullable columns
+//   - the new tags are never added, and the types of the columns are never changed
+//
+// It implies [Config.WithSchemaValidation] if not specified, since the new fields
+// are found by comparing with the schemas of the tables.
+func (c *Config) WithSchemaEvolution(tables ...string) *Config {
+	c.SchemaValidation = true
+	c.SchemaEvolutionTables = append(c.SchemaEvolutionTables, tables...)
+	return c
+}
+
+// WithTimestampValidation helps to validate the timestamps of Series in inserting,
+// by default the zero timestamp is inserted as is and the timestamp finer than the
+// precision of [Metric] is truncated silently. For example, to reject both of them:
+//
+//	cfg.WithTimestampValidation(greptime.ZeroTimestampReject, greptime.TruncationReject)
+func (c *Config) WithTimestampValidation(zero ZeroTimestampPolicy, truncation TruncationPolicy) *Config {
+	c.ZeroTimestamp = zero
+	c.TimestampTruncation = truncation
+	return c
+}
+
+// WithMaxRequestSize helps to split the insert request larger than size bytes
+// into multiple requests, instead of failing with the transport error if the
+// request exceeds the max message size of gRPC in greptimedb. The size is the
+// encoded size of the request, and:
+//
+//   - the inserts of tables are packed in order, the table is the unit of splitting
+//   - the insert of a table too large for one request is split by row ranges
+//   - the requests are sent one by one, and the AffectedRows are summed up
+//   - a failure stops the remaining requests, see [PartialInsertError]
+//
+// Since the requests are not atomic as a whole, the rows inserted before the
+// failure are kept. It only applies to [Client.Insert], which fails with
+// [ErrRequestTooLarge] if the size is too small for the header of the request.
+func (c *Config) WithMaxRequestSize(size int) *Config {
+	c.MaxRequestSize = size
+	return c
+}
+
 func (c *Config) WithDialOptions(options ...grpc.DialOption) *Config {
 	if c.DialOptions == nil {
 		c.DialOptions = []grpc.DialOption{}
@@ -117,3 +340,31 @@ func (c *Config) buildAuthHeader() *greptimepb.AuthHeader {
 func (c *Config) getGRPCAddr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
+
+func (c *Config) getHTTPAddr() string {
+	scheme := c.HTTPScheme
+	if isEmptyString(scheme) {
+		scheme = "http"
+	}
+
+	port := c.HTTPPort
+	if port == 0 {
+		port = 4000
+	}
+
+	return fmt.Sprintf("%s://%s:%d", scheme, c.Host, port)
+}
+
+func (c *Config) getLogger() Logger {
+	if c.Logger == nil {
+		return nopLogger{}
+	}
+	return c.Logger
+}
+
+func (c *Config) getHTTPClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
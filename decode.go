@@ -0,0 +1,258 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"fmt"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+)
+
+// DecodeInsertRequests helps to decode the inserts built by the client back into
+// [InsertsRequest], which is the inverse of building the request, so that the
+// requests can be inspected, replayed or forwarded via [Client.Insert].
+//
+// The database is in the header of greptimepb.GreptimeRequest, specify it via
+// [InsertsRequest.WithDatabase] if needed.
+func DecodeInsertRequests(reqs *greptimepb.InsertRequests) (*InsertsRequest, error) {
+	inserts := &InsertsRequest{}
+	for _, req := range reqs.GetInserts() {
+		insert, err := DecodeInsertRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		inserts.Append(*insert)
+	}
+	if len(inserts.inserts) == 0 {
+		return nil, ErrEmptyInserts
+	}
+	return inserts, nil
+}
+
+// DecodeInsertRequest helps to decode the insert of one table back into
+// [InsertRequest]. The Metric is restored exactly, building it again results in
+// the same columns:
+//
+//   - the column names, semantic types, data types and orders are kept as they are,
+//     except that the timestamp column is built as the last one, and the Metric
+//     keeps the names via [NamingPreserve]
+//   - the timestamp alias and precision come from the timestamp column
+//   - NULLs are absent in the Series, see [Series.Get], and the NULL timestamps
+//     are zero, see [ZeroTimestampServerNow]
+//
+// Tags and fields of timestamp data types are kept as int64 in their precision.
+func DecodeInsertRequest(req *greptimepb.InsertRequest) (*InsertRequest, error) {
+	if isEmptyString(req.GetTableName()) {
+		return nil, ErrEmptyTable
+	}
+
+	metric, err := decodeMetric(req.GetColumns(), int(req.GetRowCount()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the insert of %q: %w", req.GetTableName(), err)
+	}
+	return (&InsertRequest{}).WithTable(req.GetTableName()).WithMetric(*metric), nil
+}
+
+func decodeMetric(columns []*greptimepb.Column, rowCount int) (*Metric, error) {
+	if rowCount == 0 {
+		return nil, ErrNoSeriesInMetric
+	}
+
+	metric := &Metric{
+		naming:  NamingPreserve,
+		orders:  []string{},
+		columns: map[string]column{},
+		series:  make([]Series, rowCount),
+	}
+	for i := range metric.series {
+		metric.series[i].naming = NamingPreserve
+	}
+
+	tsCount := 0
+	for _, col := range columns {
+		name := col.GetColumnName()
+		if isEmptyString(name) {
+			return nil, ErrEmptyKey
+		}
+		if _, seen := metric.columns[name]; seen || name == metric.timestampAlias {
+			return nil, fmt.Errorf("column %q is duplicated", name)
+		}
+
+		values, err := decodeColumnValues(col)
+		if err != nil {
+			return nil, err
+		}
+
+		nonNulls := 0
+		for i := 0; i < rowCount; i++ {
+			if !isNullAt(col.GetNullMask(), i) {
+				nonNulls++
+			}
+		}
+		if nonNulls != len(values) {
+			return nil, fmt.Errorf("column %q has %d values, but %d rows are not null", name, len(values), nonNulls)
+		}
+
+		if col.GetSemanticType() == greptimepb.SemanticType_TIMESTAMP {
+			tsCount++
+			if err := decodeTimestampColumn(metric, col, values); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		c := column{typ: col.GetDatatype(), semantic: col.GetSemanticType()}
+		metric.orders = append(metric.orders, name)
+		metric.columns[name] = c
+
+		idx := 0
+		for i := range metric.series {
+			if isNullAt(col.GetNullMask(), i) {
+				continue
+			}
+			metric.series[i].set(name, c, values[idx])
+			idx++
+		}
+	}
+
+	if tsCount != 1 {
+		return nil, fmt.Errorf("exactly one timestamp column is required, but got %d", tsCount)
+	}
+	return metric, nil
+}
+
+// decodeTimestampColumn sets the timestamps of the series, the NULL ones are zero
+func decodeTimestampColumn(metric *Metric, col *greptimepb.Column, values []any) error {
+	precision, err := dataTypeToPrecision(col.GetDatatype())
+	if err != nil {
+		return fmt.Errorf("timestamp column %q: %w", col.GetColumnName(), err)
+	}
+	metric.timestampAlias = col.GetColumnName()
+	metric.timestampPrecision = precision
+
+	idx := 0
+	for i := range metric.series {
+		if isNullAt(col.GetNullMask(), i) {
+			continue
+		}
+		metric.series[i].timestamp = toTime(values[idx].(int64), precision)
+		idx++
+	}
+	return nil
+}
+
+// set helps to set the column as it is, without converting the name or value
+func (s *Series) set(key string, col column, val any) {
+	if s.columns == nil {
+		s.columns = map[string]column{}
+	}
+	if s.vals == nil {
+		s.vals = map[string]any{}
+	}
+	if _, seen := s.columns[key]; !seen {
+		s.orders = append(s.orders, key)
+	}
+	s.columns[key] = col
+	s.vals[key] = val
+}
+
+// isNullAt checks the bit of the row in the null mask, which is in LSB order
+func isNullAt(nullMask []byte, i int) bool {
+	if i/8 >= len(nullMask) {
+		return false
+	}
+	return nullMask[i/8]&(1<<(i%8)) != 0
+}
+
+// decodeColumnValues is the inverse of setColumn, the values are of the types
+// setColumn accepts
+func decodeColumnValues(col *greptimepb.Column) ([]any, error) {
+	v := col.GetValues()
+	switch col.GetDatatype() {
+	case greptimepb.ColumnDataType_INT8:
+		return toAnys(v.GetI8Values(), func(x int32) any { return int8(x) }), nil
+	case greptimepb.ColumnDataType_INT16:
+		return toAnys(v.GetI16Values(), func(x int32) any { return int16(x) }), nil
+	case greptimepb.ColumnDataType_INT32:
+		return toAnys(v.GetI32Values(), func(x int32) any { return x }), nil
+	case greptimepb.ColumnDataType_INT64:
+		return toAnys(v.GetI64Values(), func(x int64) any { return x }), nil
+	case greptimepb.ColumnDataType_UINT8:
+		return toAnys(v.GetU8Values(), func(x uint32) any { return uint8(x) }), nil
+	case greptimepb.ColumnDataType_UINT16:
+		return toAnys(v.GetU16Values(), func(x uint32) any { return uint16(x) }), nil
+	case greptimepb.ColumnDataType_UINT32:
+		return toAnys(v.GetU32Values(), func(x uint32) any { return x }), nil
+	case greptimepb.ColumnDataType_UINT64:
+		return toAnys(v.GetU64Values(), func(x uint64) any { return x }), nil
+	case greptimepb.ColumnDataType_FLOAT32:
+		return toAnys(v.GetF32Values(), func(x float32) any { return x }), nil
+	case greptimepb.ColumnDataType_FLOAT64:
+		return toAnys(v.GetF64Values(), func(x float64) any { return x }), nil
+	case greptimepb.ColumnDataType_BOOLEAN:
+		return toAnys(v.GetBoolValues(), func(x bool) any { return x }), nil
+	case greptimepb.ColumnDataType_STRING:
+		return toAnys(v.GetStringValues(), func(x string) any { return x }), nil
+	case greptimepb.ColumnDataType_BINARY:
+		return toAnys(v.GetBinaryValues(), func(x []byte) any { return x }), nil
+	case greptimepb.ColumnDataType_TIMESTAMP_SECOND:
+		return toAnys(v.GetTimestampSecondValues(), func(x int64) any { return x }), nil
+	case greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND:
+		return toAnys(v.GetTimestampMillisecondValues(), func(x int64) any { return x }), nil
+	case greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND:
+		return toAnys(v.GetTimestampMicrosecondValues(), func(x int64) any { return x }), nil
+	case greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:
+		return toAnys(v.GetTimestampNanosecondValues(), func(x int64) any { return x }), nil
+	default:
+		return nil, fmt.Errorf("unsupported data type %s of column %q", col.GetDatatype(), col.GetColumnName())
+	}
+}
+
+func toAnys[T any](values []T, f func(T) any) []any {
+	res := make([]any, 0, len(values))
+	for _, v := range values {
+		res = append(res, f(v))
+	}
+	return res
+}
+
+func dataTypeToPrecision(datatype greptimepb.ColumnDataType) (time.Duration, error) {
+	switch datatype {
+	case greptimepb.ColumnDataType_TIMESTAMP_SECOND:
+		return time.Second, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND:
+		return time.Millisecond, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND:
+		return time.Microsecond, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:
+		return time.Nanosecond, nil
+	default:
+		return 0, ErrInvalidTimePrecision
+	}
+}
+
+func toTime(v int64, precision time.Duration) time.Time {
+	switch precision {
+	case time.Second:
+		return time.Unix(v, 0)
+	case time.Microsecond:
+		return time.UnixMicro(v)
+	case time.Nanosecond:
+		return time.Unix(0, v)
+	default:
+		return time.UnixMilli(v)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDecodeInsertRequest(t *testing.T) {
+	metric := Metric{}
+	assert.Nil(t, metric.SetTimestampAlias("created_at"))
+	assert.Nil(t, metric.SetTimePrecision(time.Second))
+
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 10; i++ {
+		series := Series{}
+		assert.Nil(t, series.AddStringTag("host", "127.0.0.1"))
+		assert.Nil(t, series.AddTag("region", uint8(i)))
+		if i%3 != 0 {
+			assert.Nil(t, series.AddField("cpu", float32(i)))
+			assert.Nil(t, series.AddField("healthy", i%2 == 0))
+		}
+		if i == 9 {
+			assert.Nil(t, series.AddField("payload", []byte("x")))
+			assert.Nil(t, series.AddField("count", int16(-i)))
+		}
+		assert.Nil(t, series.SetTimestamp(start.Add(time.Duration(i)*time.Second)))
+		assert.Nil(t, metric.AddSeries(series))
+	}
+
+	req, err := (&InsertRequest{}).WithTable("monitor").WithMetric(metric).build(&Config{})
+	assert.Nil(t, err)
+
+	insert, err := DecodeInsertRequest(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "monitor", insert.GetTable())
+
+	decoded := insert.GetMetric()
+	assert.Equal(t, "created_at", decoded.GetTimestampAlias())
+	assert.Equal(t, time.Second, decoded.timestampPrecision)
+	assert.Equal(t, metric.GetTagsAndFields(), decoded.GetTagsAndFields())
+	assert.Len(t, decoded.GetSeries(), 10)
+
+	// NULLs are absent
+	series := decoded.GetSeries()[3]
+	_, ok := series.Get("cpu")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"host", "region"}, series.GetTagsAndFields())
+	assert.True(t, start.Add(3*time.Second).Equal(series.Timestamp()))
+
+	series = decoded.GetSeries()[9]
+	region, _ := series.Get("region")
+	assert.Equal(t, uint8(9), region)
+	count, _ := series.Get("count")
+	assert.Equal(t, int16(-9), count)
+
+	// built into the same request again
+	rebuilt, err := insert.build(&Config{})
+	assert.Nil(t, err)
+	assert.True(t, proto.Equal(req, rebuilt), "%v != %v", req, rebuilt)
+}
+
+func TestDecodeInsertRequestKeepsNames(t *testing.T) {
+	req := &greptimepb.InsertRequest{
+		TableName: "Monitor",
+		RowCount:  2,
+		Columns: []*greptimepb.Column{
+			{
+				ColumnName:   "ts",
+				SemanticType: greptimepb.SemanticType_TIMESTAMP,
+				Datatype:     greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND,
+				Values:       &greptimepb.Column_Values{TimestampNanosecondValues: []int64{1, 2}},
+			},
+			{
+				ColumnName:   "HostName",
+				SemanticType: greptimepb.SemanticType_TAG,
+				Datatype:     greptimepb.ColumnDataType_STRING,
+				Values:       &greptimepb.Column_Values{StringValues: []string{"b"}},
+				NullMask:     []byte{0b01},
+			},
+			{
+				ColumnName:   "last_seen",
+				SemanticType: greptimepb.SemanticType_FIELD,
+				Datatype:     greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND,
+				Values:       &greptimepb.Column_Values{TimestampMillisecondValues: []int64{10, 20}},
+			},
+		},
+	}
+
+	insert, err := DecodeInsertRequest(req)
+	assert.Nil(t, err)
+	metric := insert.GetMetric()
+	assert.Equal(t, []string{"HostName", "last_seen"}, metric.GetTagsAndFields())
+	assert.Equal(t, time.Nanosecond, metric.timestampPrecision)
+
+	_, ok := metric.GetSeries()[0].Get("HostName")
+	assert.False(t, ok)
+	host, _ := metric.GetSeries()[1].GetString("HostName")
+	assert.Equal(t, "b", host)
+	lastSeen, _ := metric.GetSeries()[1].GetInt("last_seen")
+	assert.Equal(t, int64(20), lastSeen)
+	assert.Equal(t, int64(2), metric.GetSeries()[1].Timestamp().UnixNano())
+
+	rebuilt, err := insert.build(&Config{})
+	assert.Nil(t, err)
+	assert.True(t, proto.Equal(req.Columns[1], rebuilt.Columns[0]))
+	assert.True(t, proto.Equal(req.Columns[2], rebuilt.Columns[1]))
+	assert.True(t, proto.Equal(req.Columns[0], rebuilt.Columns[2]))
+}
+
+func TestDecodeInsertRequestErrors(t *testing.T) {
+	ts := &greptimepb.Column{
+		ColumnName:   "ts",
+		SemanticType: greptimepb.SemanticType_TIMESTAMP,
+		Datatype:     greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND,
+		Values:       &greptimepb.Column_Values{TimestampMillisecondValues: []int64{1}},
+	}
+	field := &greptimepb.Column{
+		ColumnName:   "cpu",
+		SemanticType: greptimepb.SemanticType_FIELD,
+		Datatype:     greptimepb.ColumnDataType_FLOAT64,
+		Values:       &greptimepb.Column_Values{F64Values: []float64{1, 2}},
+	}
+	date := &greptimepb.Column{
+		ColumnName:   "day",
+		SemanticType: greptimepb.SemanticType_FIELD,
+		Datatype:     greptimepb.ColumnDataType_DATE,
+		Values:       &greptimepb.Column_Values{DateValues: []int32{1}},
+	}
+
+	cases := map[string]*greptimepb.InsertRequest{
+		"name of table should not be be empty":                {RowCount: 1, Columns: []*greptimepb.Column{ts}},
+		"empty series in Metric":                              {TableName: "monitor", Columns: []*greptimepb.Column{ts}},
+		"exactly one timestamp column is required, but got 0": {TableName: "monitor", RowCount: 1},
+		`column "ts" is duplicated`:                           {TableName: "monitor", RowCount: 1, Columns: []*greptimepb.Column{ts, ts}},
+		`column "cpu" has 2 values, but 1 rows are not null`:  {TableName: "monitor", RowCount: 1, Columns: []*greptimepb.Column{ts, field}},
+		`unsupported data type DATE of column "day"`:          {TableName: "monitor", RowCount: 1, Columns: []*greptimepb.Column{ts, date}},
+	}
+	for msg, req := range cases {
+		_, err := DecodeInsertRequest(req)
+		assert.ErrorContains(t, err, msg)
+	}
+}
+
+func TestDecodeInsertRequests(t *testing.T) {
+	inserts := InsertsRequest{}
+	for _, table := range []string{"monitor", "cpu"} {
+		metric := Metric{}
+		series := Series{}
+		assert.Nil(t, series.AddTag("host", "a"))
+		assert.Nil(t, series.SetTimestamp(time.UnixMilli(1)))
+		assert.Nil(t, metric.AddSeries(series))
+		inserts.Append(*(&InsertRequest{}).WithTable(table).WithMetric(metric))
+	}
+
+	req, err := inserts.WithDatabase("public").build(&Config{})
+	assert.Nil(t, err)
+
+	decoded, err := DecodeInsertRequests(req.GetInserts())
+	assert.Nil(t, err)
+	assert.Len(t, decoded.GetInserts(), 2)
+	assert.Equal(t, "cpu", decoded.GetInserts()[1].GetTable())
+
+	rebuilt, err := decoded.WithDatabase("public").build(&Config{})
+	assert.Nil(t, err)
+	assert.True(t, proto.Equal(req, rebuilt))
+
+	_, err = DecodeInsertRequests(&greptimepb.InsertRequests{})
+	assert.ErrorIs(t, err, ErrEmptyInserts)
+}
@@ -21,11 +21,57 @@
 // [InsertsRequest] into greptimedb, and call [Client.Query] to retrieve data from
 // greptimedb via [QueryRequest].
 //
+// If greptimedb responds failure, [Client.Insert] and [Client.Query] return an
+// [*Error] carrying the [StatusCode], which can be checked via
+// errors.Is(err, StatusTableNotFound).
+//
+// The large insert can be split into multiple requests via [Config.WithMaxRequestSize].
+//
 // # Promql
 //
 // You can also call [Client.PromqlQuery] to retrieve data in []byte format, which
 // is absolutely the same as Prometheus. You can choose [InstantPromql] or [RangePromql]
-// to get vector or matrix result.
+// to get vector or matrix result. [Client.PromqlQueryResult] helps to decode the
+// response into typed result.
+//
+// [InstantPromql] can also be used in [Client.Query], labels are converted into
+// tags, value into field and evaluation time into timestamp of [Metric]. The
+// result of [RangePromql] in [Client.Query] is in fields as the one of sql.
+//
+// [Client.PromQuerier] works with prom.Handler to serve the query APIs of Prometheus,
+// so that Grafana can query greptimedb via gRPC through it.
+//
+// The results of dashboards querying the same PromQL or SQL repeatedly can be
+// cached via [Config.WithQueryCache].
+//
+// # Interceptors
+//
+// [Config.WithInterceptors] helps to see the requests before they are sent and the
+// responses afterwards, so that auditing, quotas, or modifying the requests can be
+// done via [Interceptor] without forking the client.
+//
+// # Schema Validation
+//
+// [Config.WithSchemaValidation] helps to validate the inserts against the cached
+// schemas of the tables, so that the mismatched columns are reported before the
+// request is sent, and [Config.WithSchemaCoercion] converts the numbers into the
+// types of the columns. [Config.WithSchemaEvolution] adds the new fields into the
+// allowed tables before inserting.
+//
+// [Config.WithTimestampValidation] helps to reject the zero timestamps of Series,
+// or to leave them to the server, and to report the timestamps truncated by the
+// precision of Metric.
+//
+// # Testing
+//
+// The greptimetest package provides an in-memory greptimedb server, so that the
+// code using [Client] can be tested without Docker, and a recorder to assert
+// which rows are written.
+//
+// # database/sql
+//
+// The driver is registered as "greptime", statements are executed via the same
+// path as [Client.Query], see [Driver] and [ParseDSN] for detail.
 //
 // # Series
 //
@@ -51,4 +97,9 @@
 //
 //   - [Metric.SetTimePrecision]
 //   - [Metric.SetTimestampAlias]
+//   - [Metric.SetNamingPolicy], the names are converted into snake_case by default,
+//     [NamingPreserve] keeps them as they are, see [NamingPolicy]
+//
+// For the high throughput, [ColumnarMetric] builds the typed columns directly
+// without [Series], see [InsertRequest.WithColumnarMetric].
 package greptime
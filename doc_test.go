@@ -65,7 +65,7 @@ func constructInsertRequest(table string) greptime.InsertRequest {
 	return insertRequest
 }
 
-func ExampleInsert() {
+func Example_insert() {
 	insertsRequest := greptime.InsertsRequest{}
 	insertsRequest.
 		Append(constructInsertRequest(monitorTable)).
@@ -79,7 +79,7 @@ func ExampleInsert() {
 	fmt.Printf("AffectedRows: %d\n", resp.GetAffectedRows().GetValue())
 }
 
-func ExampleQueryViaSql() {
+func Example_queryViaSql() {
 	type Monitor struct {
 		region string
 		host   string
@@ -113,7 +113,7 @@ func ExampleQueryViaSql() {
 	fmt.Println(monitors)
 }
 
-func ExampleQueryViaInstantPromql() {
+func Example_queryViaInstantPromql() {
 	promql := greptime.NewInstantPromql(monitorTable)
 	req := greptime.QueryRequest{}
 	req.WithInstantPromql(promql)
@@ -132,7 +132,7 @@ func ExampleQueryViaInstantPromql() {
 
 }
 
-func ExamplQueryViaRangePromql() {
+func Example_queryViaRangePromql() {
 	end := time.Now()
 	start := end.Add(time.Duration(-15) * time.Second)
 	promql := greptime.NewRangePromql(monitorTable).WithStart(start).WithEnd(end).WithStep(time.Second)
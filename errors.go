@@ -16,21 +16,84 @@ package greptime
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/status"
 )
 
 var (
-	ErrEmptyDatabase        = errors.New("name of database should not be empty")
-	ErrEmptyTable           = errors.New("name of table should not be be empty")
-	ErrEmptyInserts         = errors.New("at least one insert is required in InsertsRequest")
-	ErrEmptyTimestamp       = errors.New("timestamp should not be empty")
-	ErrEmptyQuery           = errors.New("query should not be empty, assign Sql, InstantPromql or RangePromql")
-	ErrEmptyKey             = errors.New("key should not be empty")
-	ErrEmptySql             = errors.New("sql is required in querying")
-	ErrEmptyPromql          = errors.New("promql is required in promql querying")
-	ErrEmptyStep            = errors.New("step is required in range promql")
-	ErrEmptyRange           = errors.New("start and end is required in range promql")
-	ErrInvalidTimePrecision = errors.New("precision of timestamp is not valid")
-	ErrNoSeriesInMetric     = errors.New("empty series in Metric")
-	ErrNotImplemented       = errors.New("not implemented!")
-	ErrSqlInPromql          = errors.New("Sql can not be used as Promql")
+	ErrEmptyDatabase            = errors.New("name of database should not be empty")
+	ErrEmptyTable               = errors.New("name of table should not be be empty")
+	ErrEmptyInserts             = errors.New("at least one insert is required in InsertsRequest")
+	ErrEmptyTimestamp           = errors.New("timestamp should not be empty")
+	ErrEmptyQuery               = errors.New("query should not be empty, assign Sql, InstantPromql or RangePromql")
+	ErrEmptyKey                 = errors.New("key should not be empty")
+	ErrEmptySql                 = errors.New("sql is required in querying")
+	ErrEmptyPromql              = errors.New("promql is required in promql querying")
+	ErrEmptyStep                = errors.New("step is required in range promql")
+	ErrEmptyRange               = errors.New("start and end is required in range promql")
+	ErrInvalidTimePrecision     = errors.New("precision of timestamp is not valid")
+	ErrTimestampTruncated       = errors.New("timestamp is finer than the precision")
+	ErrNoSeriesInMetric         = errors.New("empty series in Metric")
+	ErrNotImplemented           = errors.New("not implemented!")
+	ErrSqlInPromql              = errors.New("Sql can not be used as Promql")
+	ErrArgsMismatch             = errors.New("number of placeholders and arguments does not match")
+	ErrInvalidIdentifier        = errors.New("identifier is not valid")
+	ErrNamingPolicyAfterColumns = errors.New("naming policy MUST be set before adding columns")
+	ErrRequestTooLarge          = errors.New("request is larger than the max request size")
 )
+
+// Error is the error responded by greptimedb, it can be retrieved via errors.As,
+// and the code can be checked via errors.Is(err, StatusTableNotFound).
+//
+// Table and Column are the offending table and column if known, like the table
+// of an [InsertRequest] failed to insert.
+type Error struct {
+	Code      StatusCode
+	Msg       string
+	Retryable bool
+	Table     string
+	Column    string
+
+	// status is the gRPC status the error is converted from, if any
+	status *status.Status
+}
+
+// newError returns nil if the code is success
+func newError(code uint32, msg string) *Error {
+	if StatusCode(code) == StatusSuccess {
+		return nil
+	}
+	return &Error{Code: StatusCode(code), Msg: msg, Retryable: StatusCode(code).IsRetryable()}
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "code: %d (%s), msg: %s", uint32(e.Code), e.Code, e.Msg)
+	if len(e.Table) > 0 {
+		fmt.Fprintf(&b, ", table: %s", e.Table)
+	}
+	if len(e.Column) > 0 {
+		fmt.Fprintf(&b, ", column: %s", e.Column)
+	}
+	return b.String()
+}
+
+// GRPCStatus returns the gRPC status the error is converted from, so that
+// status.Code(err) still works for the failed queries
+func (e *Error) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// Is reports whether target is the same [StatusCode], or an [*Error] of the same code
+func (e *Error) Is(target error) bool {
+	switch t := target.(type) {
+	case StatusCode:
+		return e.Code == t
+	case *Error:
+		return t != nil && e.Code == t.Code
+	default:
+		return false
+	}
+}
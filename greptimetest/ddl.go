@@ -0,0 +1,142 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptimetest
+
+import (
+	"fmt"
+	"strings"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/GreptimeTeam/greptimedb-client-go"
+)
+
+// alterStmt is the subset of ALTER TABLE supported by the server:
+//
+//	ALTER TABLE [database.]table ADD [COLUMN] column type [NULL]
+//
+// The column is added as a nullable field.
+type alterStmt struct {
+	database string
+	table    string
+	column   columnSchema
+}
+
+// sqlTypes are the SQL names of the data types
+var sqlTypes = map[string]greptimepb.ColumnDataType{
+	"tinyint":           greptimepb.ColumnDataType_INT8,
+	"smallint":          greptimepb.ColumnDataType_INT16,
+	"int":               greptimepb.ColumnDataType_INT32,
+	"bigint":            greptimepb.ColumnDataType_INT64,
+	"tinyint unsigned":  greptimepb.ColumnDataType_UINT8,
+	"smallint unsigned": greptimepb.ColumnDataType_UINT16,
+	"int unsigned":      greptimepb.ColumnDataType_UINT32,
+	"bigint unsigned":   greptimepb.ColumnDataType_UINT64,
+	"float":             greptimepb.ColumnDataType_FLOAT32,
+	"double":            greptimepb.ColumnDataType_FLOAT64,
+	"boolean":           greptimepb.ColumnDataType_BOOLEAN,
+	"string":            greptimepb.ColumnDataType_STRING,
+	"varbinary":         greptimepb.ColumnDataType_BINARY,
+	"timestamp(0)":      greptimepb.ColumnDataType_TIMESTAMP_SECOND,
+	"timestamp(3)":      greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND,
+	"timestamp":         greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND,
+	"timestamp(6)":      greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND,
+	"timestamp(9)":      greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND,
+}
+
+func parseAlter(sql string) (*alterStmt, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	stmt := &alterStmt{}
+
+	for _, keyword := range []string{"ALTER", "TABLE"} {
+		if err := p.expect(keyword); err != nil {
+			return nil, err
+		}
+	}
+	if stmt.table, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if p.accept(".") {
+		stmt.database = stmt.table
+		if stmt.table, err = p.ident(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect("ADD"); err != nil {
+		return nil, err
+	}
+	p.accept("COLUMN")
+	if stmt.column.name, err = p.ident(); err != nil {
+		return nil, err
+	}
+
+	// the type is the words till NULL, like `BIGINT UNSIGNED` or `TIMESTAMP(3)`
+	var b strings.Builder
+	for {
+		t, ok := p.peek()
+		if !ok || t.is("NULL") {
+			break
+		}
+		p.pos++
+		if b.Len() > 0 && t.text != "(" && t.text != ")" && !strings.HasSuffix(b.String(), "(") {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strings.ToLower(t.text))
+	}
+	datatype, ok := sqlTypes[b.String()]
+	if !ok {
+		return nil, syntaxError("unsupported data type %q", b.String())
+	}
+	stmt.column.datatype = datatype
+	stmt.column.semantic = greptimepb.SemanticType_FIELD
+
+	p.accept("NULL")
+	if t, ok := p.peek(); ok {
+		return nil, syntaxError("unsupported clause at %q", t.text)
+	}
+	return stmt, nil
+}
+
+// execute executes the statement without result set, only ALTER TABLE is supported
+func (s *Server) execute(database, sql string) (uint32, error) {
+	stmt, err := parseAlter(sql)
+	if err != nil {
+		return 0, err
+	}
+	if len(stmt.database) > 0 {
+		database = stmt.database
+	}
+	return 0, s.store.addColumn(database, stmt.table, stmt.column)
+}
+
+func (s *store) addColumn(database, name string, column columnSchema) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(database, name)
+	if t == nil {
+		return &greptime.Error{Code: greptime.StatusTableNotFound, Msg: fmt.Sprintf("table %s.%s not found", database, name), Table: name}
+	}
+	if _, ok := t.column(column.name); ok {
+		return &greptime.Error{Code: greptime.StatusTableColumnExists,
+			Msg: fmt.Sprintf("column %s already exists in table %s", column.name, name), Table: name, Column: column.name}
+	}
+	t.columns = append(t.columns, column)
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptimetest
+
+import (
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+)
+
+// informationSchema is the database of the virtual tables describing the schemas
+const informationSchema = "information_schema"
+
+// dataTypeNames are the names of data types in information_schema.columns
+var dataTypeNames = map[greptimepb.ColumnDataType]string{
+	greptimepb.ColumnDataType_INT8:                  "Int8",
+	greptimepb.ColumnDataType_INT16:                 "Int16",
+	greptimepb.ColumnDataType_INT32:                 "Int32",
+	greptimepb.ColumnDataType_INT64:                 "Int64",
+	greptimepb.ColumnDataType_UINT8:                 "UInt8",
+	greptimepb.ColumnDataType_UINT16:                "UInt16",
+	greptimepb.ColumnDataType_UINT32:                "UInt32",
+	greptimepb.ColumnDataType_UINT64:                "UInt64",
+	greptimepb.ColumnDataType_FLOAT32:               "Float32",
+	greptimepb.ColumnDataType_FLOAT64:               "Float64",
+	greptimepb.ColumnDataType_BOOLEAN:               "Boolean",
+	greptimepb.ColumnDataType_STRING:                "String",
+	greptimepb.ColumnDataType_BINARY:                "Binary",
+	greptimepb.ColumnDataType_TIMESTAMP_SECOND:      "TimestampSecond",
+	greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND: "TimestampMillisecond",
+	greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND: "TimestampMicrosecond",
+	greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:  "TimestampNanosecond",
+}
+
+// columnsTable builds the virtual table information_schema.columns from the
+// schemas of all the tables, the caller MUST hold the lock
+func (s *store) columnsTable() *table {
+	stringColumn := func(name string, semantic greptimepb.SemanticType) columnSchema {
+		return columnSchema{name: name, semantic: semantic, datatype: greptimepb.ColumnDataType_STRING}
+	}
+	t := &table{
+		name: "columns",
+		columns: []columnSchema{
+			stringColumn("table_catalog", greptimepb.SemanticType_FIELD),
+			stringColumn("table_schema", greptimepb.SemanticType_TAG),
+			stringColumn("table_name", greptimepb.SemanticType_TAG),
+			stringColumn("column_name", greptimepb.SemanticType_TAG),
+			stringColumn("data_type", greptimepb.SemanticType_FIELD),
+			stringColumn("semantic_type", greptimepb.SemanticType_FIELD),
+		},
+		rows: map[string]map[string]any{},
+	}
+
+	for database, tables := range s.databases {
+		for _, tbl := range tables {
+			for _, c := range tbl.columns {
+				row := map[string]any{
+					"table_catalog": "greptime",
+					"table_schema":  database,
+					"table_name":    tbl.name,
+					"column_name":   c.name,
+					"data_type":     dataTypeNames[c.datatype],
+					"semantic_type": c.semantic.String(),
+				}
+				t.rows[t.primaryKey(row)] = row
+			}
+		}
+	}
+	return t
+}
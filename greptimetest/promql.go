@@ -0,0 +1,390 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptimetest
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/GreptimeTeam/greptimedb-client-go"
+	"github.com/GreptimeTeam/greptimedb-client-go/prom"
+	"github.com/prometheus/common/model"
+)
+
+// lookbackDelta is how far the latest sample is looked back, the same as Prometheus
+const lookbackDelta = 5 * time.Minute
+
+// valueColumn is the field preferred as the value of the series
+const valueColumn = "greptime_value"
+
+// matcher matches the label of a series
+type matcher struct {
+	name  string
+	op    string // =, !=, =~ or !~
+	value string
+	re    *regexp.Regexp
+}
+
+func (m matcher) matches(v string) bool {
+	switch m.op {
+	case "=":
+		return v == m.value
+	case "!=":
+		return v != m.value
+	case "=~":
+		return m.re.MatchString(v)
+	default: // !~
+		return !m.re.MatchString(v)
+	}
+}
+
+var selectorRegexp = regexp.MustCompile(`^\s*([a-zA-Z_:][a-zA-Z0-9_:]*)?\s*(?:\{(.*)\})?\s*$`)
+
+var matcherRegexp = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*"((?:[^"\\]|\\.)*)"\s*$`)
+
+// parseSelector parses the vector selector like `monitor{host="a", idc=~"us-.*"}`,
+// the metric name is the table
+func parseSelector(query string) (string, []matcher, error) {
+	invalid := &greptime.Error{Code: greptime.StatusInvalidSyntax, Msg: fmt.Sprintf("only vector selector is supported, but got %q", query)}
+
+	groups := selectorRegexp.FindStringSubmatch(query)
+	if groups == nil {
+		return "", nil, invalid
+	}
+
+	name := groups[1]
+	var matchers []matcher
+	if body := strings.TrimSpace(groups[2]); len(body) > 0 {
+		for _, part := range splitMatchers(body) {
+			if len(strings.TrimSpace(part)) == 0 {
+				continue
+			}
+			m := matcherRegexp.FindStringSubmatch(part)
+			if m == nil {
+				return "", nil, invalid
+			}
+			value, err := strconv.Unquote(`"` + m[3] + `"`)
+			if err != nil {
+				return "", nil, invalid
+			}
+
+			if m[1] == model.MetricNameLabel && m[2] == "=" {
+				name = value
+				continue
+			}
+			mt := matcher{name: m[1], op: m[2], value: value}
+			if mt.op == "=~" || mt.op == "!~" {
+				if mt.re, err = regexp.Compile("^(?:" + value + ")$"); err != nil {
+					return "", nil, invalid
+				}
+			}
+			matchers = append(matchers, mt)
+		}
+	}
+
+	if len(name) == 0 {
+		return "", nil, invalid
+	}
+	return name, matchers, nil
+}
+
+// splitMatchers splits the matchers by the commas out of quotes
+func splitMatchers(body string) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				parts = append(parts, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, body[start:])
+}
+
+// parsePromqlTime parses the unix timestamp in seconds or RFC3339
+func parsePromqlTime(s string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		whole, frac := math.Modf(secs)
+		return time.Unix(int64(whole), int64(frac*float64(time.Second))), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, &greptime.Error{Code: greptime.StatusInvalidArguments, Msg: fmt.Sprintf("invalid time %q", s)}
+	}
+	return t, nil
+}
+
+// parsePromqlDuration parses the duration in seconds or like 1m
+func parsePromqlDuration(s string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := model.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, &greptime.Error{Code: greptime.StatusInvalidArguments, Msg: fmt.Sprintf("invalid step %q", s)}
+	}
+	return time.Duration(d), nil
+}
+
+// series is the samples of a series ordered by timestamp
+type series struct {
+	metric model.Metric
+	times  []time.Time
+	values []float64
+}
+
+// at returns the latest sample in the lookback window of t
+func (s *series) at(t time.Time) (float64, bool) {
+	i := sort.Search(len(s.times), func(i int) bool { return s.times[i].After(t) }) - 1
+	if i < 0 || !s.times[i].After(t.Add(-lookbackDelta)) {
+		return 0, false
+	}
+	return s.values[i], true
+}
+
+// selectSeries selects the series of the table matching the matchers. The labels
+// are the tags, and the value is the greptime_value or the first numeric field.
+func (s *store) selectSeries(database, query string) ([]*series, error) {
+	name, matchers, err := parseSelector(query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.table(database, name)
+	if t == nil {
+		// Prometheus responds empty result for unknown metrics
+		return nil, nil
+	}
+
+	ts, _ := t.timestampColumn()
+	var value *columnSchema
+	for i, c := range t.columns {
+		if c.semantic != greptimepb.SemanticType_FIELD {
+			continue
+		}
+		if !isNumeric(c.datatype) {
+			continue
+		}
+		if value == nil || c.name == valueColumn {
+			value = &t.columns[i]
+		}
+	}
+	if value == nil {
+		return nil, &greptime.Error{Code: greptime.StatusInvalidArguments, Msg: fmt.Sprintf("no numeric field in table %s", name), Table: name}
+	}
+
+	grouped := map[model.Fingerprint]*series{}
+	var res []*series
+	for _, row := range t.sortedRows() {
+		v, ok := row[value.name]
+		if !ok {
+			continue
+		}
+
+		metric := model.Metric{model.MetricNameLabel: model.LabelValue(name)}
+		for _, tag := range t.tagColumns() {
+			if tv, ok := row[tag.name]; ok {
+				metric[model.LabelName(tag.name)] = model.LabelValue(fmt.Sprint(tv))
+			}
+		}
+
+		matched := true
+		for _, m := range matchers {
+			if !m.matches(string(metric[model.LabelName(m.name)])) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		fp := metric.Fingerprint()
+		ss, ok := grouped[fp]
+		if !ok {
+			ss = &series{metric: metric}
+			grouped[fp] = ss
+			res = append(res, ss)
+		}
+		ss.times = append(ss.times, row[ts.name].(time.Time))
+		ss.values = append(ss.values, toFloat(v))
+	}
+
+	for _, ss := range res {
+		sort.Sort(bySampleTime{ss})
+	}
+	return res, nil
+}
+
+type bySampleTime struct{ *series }
+
+func (s bySampleTime) Len() int           { return len(s.times) }
+func (s bySampleTime) Less(i, j int) bool { return s.times[i].Before(s.times[j]) }
+func (s bySampleTime) Swap(i, j int) {
+	s.times[i], s.times[j] = s.times[j], s.times[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
+
+func isNumeric(datatype greptimepb.ColumnDataType) bool {
+	switch datatype {
+	case greptimepb.ColumnDataType_INT8, greptimepb.ColumnDataType_INT16, greptimepb.ColumnDataType_INT32,
+		greptimepb.ColumnDataType_INT64, greptimepb.ColumnDataType_UINT8, greptimepb.ColumnDataType_UINT16,
+		greptimepb.ColumnDataType_UINT32, greptimepb.ColumnDataType_UINT64, greptimepb.ColumnDataType_FLOAT32,
+		greptimepb.ColumnDataType_FLOAT64:
+		return true
+	default:
+		return false
+	}
+}
+
+// evaluate evaluates the series at each step of the range into matrix
+func evaluate(selected []*series, start, end time.Time, step time.Duration) model.Matrix {
+	matrix := model.Matrix{}
+	for _, s := range selected {
+		stream := &model.SampleStream{Metric: s.metric}
+		for t := start; !t.After(end); t = t.Add(step) {
+			if v, ok := s.at(t); ok {
+				stream.Values = append(stream.Values, model.SamplePair{
+					Timestamp: model.TimeFromUnixNano(t.UnixNano()),
+					Value:     model.SampleValue(v),
+				})
+			}
+		}
+		if len(stream.Values) > 0 {
+			matrix = append(matrix, stream)
+		}
+	}
+	return matrix
+}
+
+// promql handles the request of PrometheusGateway, the body is the same as the
+// HTTP API of Prometheus
+func (s *Server) promql(request *greptimepb.PromqlRequest) ([]byte, error) {
+	database := request.GetHeader().GetDbname()
+
+	if rq := request.GetRangeQuery(); rq != nil {
+		start, end, step, err := parseRange(rq.GetStart(), rq.GetEnd(), rq.GetStep())
+		if err != nil {
+			return nil, err
+		}
+		selected, err := s.store.selectSeries(database, rq.GetQuery())
+		if err != nil {
+			return nil, err
+		}
+		return prom.MarshalApiResponse(&prom.QueryResult{Type: model.ValMatrix, Val: evaluate(selected, start, end, step)})
+	}
+
+	iq := request.GetInstantQuery()
+	t := time.Now()
+	if len(iq.GetTime()) > 0 {
+		var err error
+		if t, err = parsePromqlTime(iq.GetTime()); err != nil {
+			return nil, err
+		}
+	}
+	selected, err := s.store.selectSeries(database, iq.GetQuery())
+	if err != nil {
+		return nil, err
+	}
+
+	vector := model.Vector{}
+	for _, ss := range selected {
+		if v, ok := ss.at(t); ok {
+			vector = append(vector, &model.Sample{Metric: ss.metric, Value: model.SampleValue(v), Timestamp: model.TimeFromUnixNano(t.UnixNano())})
+		}
+	}
+	return prom.MarshalApiResponse(&prom.QueryResult{Type: model.ValVector, Val: vector})
+}
+
+func parseRange(startStr, endStr, stepStr string) (time.Time, time.Time, time.Duration, error) {
+	start, err := parsePromqlTime(startStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, 0, err
+	}
+	end, err := parsePromqlTime(endStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, 0, err
+	}
+	step, err := parsePromqlDuration(stepStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, 0, err
+	}
+	return start, end, step, nil
+}
+
+// rangePromqlResult evaluates the range query via flight DoGet, the columns are
+// the timestamp, the value and the labels
+func (s *Server) rangePromqlResult(database string, rq *greptimepb.PromRangeQuery) (*result, error) {
+	start, end, step, err := parseRange(rq.GetStart(), rq.GetEnd(), rq.GetStep())
+	if err != nil {
+		return nil, err
+	}
+	selected, err := s.store.selectSeries(database, rq.GetQuery())
+	if err != nil {
+		return nil, err
+	}
+	matrix := evaluate(selected, start, end, step)
+
+	var labels []string
+	seen := map[model.LabelName]bool{model.MetricNameLabel: true}
+	for _, stream := range matrix {
+		for name := range stream.Metric {
+			if !seen[name] {
+				seen[name] = true
+				labels = append(labels, string(name))
+			}
+		}
+	}
+	sort.Strings(labels)
+
+	res := &result{columns: []columnSchema{
+		{name: "ts", semantic: greptimepb.SemanticType_TIMESTAMP, datatype: greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND},
+		{name: "value", semantic: greptimepb.SemanticType_FIELD, datatype: greptimepb.ColumnDataType_FLOAT64},
+	}}
+	for _, label := range labels {
+		res.columns = append(res.columns, columnSchema{name: label, semantic: greptimepb.SemanticType_TAG, datatype: greptimepb.ColumnDataType_STRING})
+	}
+
+	for _, stream := range matrix {
+		for _, v := range stream.Values {
+			row := []any{v.Timestamp.Time(), float64(v.Value)}
+			for _, label := range labels {
+				if lv, ok := stream.Metric[model.LabelName(label)]; ok {
+					row = append(row, string(lv))
+				} else {
+					row = append(row, nil)
+				}
+			}
+			res.rows = append(res.rows, row)
+		}
+	}
+	return res, nil
+}
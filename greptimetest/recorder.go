@@ -0,0 +1,345 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptimetest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/GreptimeTeam/greptimedb-client-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// Column is the schema of a column in the recorded insert
+type Column struct {
+	Name     string
+	Semantic greptimepb.SemanticType
+	Datatype greptimepb.ColumnDataType
+}
+
+// Insert is an insert of a table recorded by [Recorder]
+type Insert struct {
+	Operation string // greptime.OperationInsert or greptime.OperationStreamInsert
+	Database  string
+	Table     string
+	Columns   []Column
+	Metric    greptime.Metric
+}
+
+// column returns the schema of the column
+func (i *Insert) column(name string) (Column, bool) {
+	for _, c := range i.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// Recorder records the requests built by the client via [Recorder.Interceptor],
+// so that the tests can assert what is written without a server:
+//
+//	recorder := greptimetest.NewRecorder()
+//	cfg := greptime.NewCfg("localhost").WithDatabase("public").
+//		WithDialOptions(grpc.WithTransportCredentials(insecure.NewCredentials())).
+//		WithInterceptors(recorder.Interceptor())
+//	... // write via the client
+//	recorder.AssertRows(t, "monitor", 2, greptimetest.HasTag("host", "127.0.0.1"))
+//
+// The inserts are responded successfully without being sent by default, see
+// [Recorder.WithForward]. The other requests are always sent.
+type Recorder struct {
+	forward bool
+
+	mu       sync.Mutex
+	requests []*greptimepb.GreptimeRequest
+	inserts  []*Insert
+}
+
+// NewRecorder helps to init the Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// WithForward helps to send the recorded inserts to the server, like [Server]
+func (r *Recorder) WithForward() *Recorder {
+	r.forward = true
+	return r
+}
+
+// Interceptor returns the interceptor recording the requests, it should be the
+// last one of [greptime.Config.WithInterceptors] to record the requests modified
+// by other interceptors.
+func (r *Recorder) Interceptor() greptime.Interceptor {
+	return func(ctx context.Context, call *greptime.Call, invoker greptime.Invoker) (proto.Message, error) {
+		request, ok := call.Request.(*greptimepb.GreptimeRequest)
+		if !ok {
+			return invoker(ctx, call)
+		}
+
+		request = proto.Clone(request).(*greptimepb.GreptimeRequest)
+		inserts, err := decodeInserts(call.Operation, request)
+		if err != nil {
+			return nil, err
+		}
+
+		r.mu.Lock()
+		r.requests = append(r.requests, request)
+		r.inserts = append(r.inserts, inserts...)
+		r.mu.Unlock()
+
+		if r.forward || len(inserts) == 0 {
+			return invoker(ctx, call)
+		}
+		return respond(call.Operation, request), nil
+	}
+}
+
+// respond responds the inserts successfully
+func respond(op string, request *greptimepb.GreptimeRequest) proto.Message {
+	if op == greptime.OperationStreamInsert {
+		return nil
+	}
+
+	var rows uint32
+	for _, insert := range request.GetInserts().GetInserts() {
+		rows += insert.GetRowCount()
+	}
+	return &greptimepb.GreptimeResponse{
+		Header:   successHeader(),
+		Response: &greptimepb.GreptimeResponse_AffectedRows{AffectedRows: &greptimepb.AffectedRows{Value: rows}},
+	}
+}
+
+func decodeInserts(op string, request *greptimepb.GreptimeRequest) ([]*Insert, error) {
+	var inserts []*Insert
+	for _, req := range request.GetInserts().GetInserts() {
+		insert, err := greptime.DecodeInsertRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		columns := make([]Column, 0, len(req.GetColumns()))
+		for _, c := range req.GetColumns() {
+			columns = append(columns, Column{Name: c.GetColumnName(), Semantic: c.GetSemanticType(), Datatype: c.GetDatatype()})
+		}
+		inserts = append(inserts, &Insert{
+			Operation: op,
+			Database:  request.GetHeader().GetDbname(),
+			Table:     insert.GetTable(),
+			Columns:   columns,
+			Metric:    insert.GetMetric(),
+		})
+	}
+	return inserts, nil
+}
+
+// Requests returns the recorded requests in order
+func (r *Recorder) Requests() []*greptimepb.GreptimeRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*greptimepb.GreptimeRequest{}, r.requests...)
+}
+
+// Inserts returns the recorded inserts in order, an insert request of multiple
+// tables is recorded as multiple inserts
+func (r *Recorder) Inserts() []*Insert {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Insert{}, r.inserts...)
+}
+
+// Tables returns the names of tables inserted in order of the first insert
+func (r *Recorder) Tables() []string {
+	var tables []string
+	seen := map[string]bool{}
+	for _, insert := range r.Inserts() {
+		if !seen[insert.Table] {
+			seen[insert.Table] = true
+			tables = append(tables, insert.Table)
+		}
+	}
+	return tables
+}
+
+// Reset drops the recorded requests
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests, r.inserts = nil, nil
+}
+
+// Row is a recorded row of the insert
+type Row struct {
+	Insert *Insert
+	Series greptime.Series
+}
+
+// RowMatcher reports whether the row matches, see [HasTag], [HasField] and [HasTimestamp]
+type RowMatcher interface {
+	Match(row Row) bool
+	String() string
+}
+
+type rowMatcher struct {
+	match func(row Row) bool
+	desc  string
+}
+
+func (m rowMatcher) Match(row Row) bool { return m.match(row) }
+func (m rowMatcher) String() string     { return m.desc }
+
+// HasTag matches the rows whose tag is the value, the numbers of different types
+// are compared by value
+func HasTag(name string, value any) RowMatcher {
+	return hasColumn(greptimepb.SemanticType_TAG, name, value)
+}
+
+// HasField matches the rows whose field is the value, the numbers of different
+// types are compared by value
+func HasField(name string, value any) RowMatcher {
+	return hasColumn(greptimepb.SemanticType_FIELD, name, value)
+}
+
+func hasColumn(semantic greptimepb.SemanticType, name string, value any) RowMatcher {
+	return rowMatcher{
+		desc: fmt.Sprintf("%s %s=%v", strings.ToLower(semantic.String()), name, value),
+		match: func(row Row) bool {
+			column, ok := row.Insert.column(name)
+			if !ok || column.Semantic != semantic {
+				return false
+			}
+			v, ok := row.Series.Get(name)
+			return ok && equalValues(v, value)
+		},
+	}
+}
+
+// HasNull matches the rows whose column is NULL
+func HasNull(name string) RowMatcher {
+	return rowMatcher{
+		desc: fmt.Sprintf("%s is null", name),
+		match: func(row Row) bool {
+			_, ok := row.Series.Get(name)
+			return !ok
+		},
+	}
+}
+
+// HasTimestamp matches the rows of the timestamp
+func HasTimestamp(t time.Time) RowMatcher {
+	return rowMatcher{
+		desc: fmt.Sprintf("timestamp=%s", t.Format(time.RFC3339Nano)),
+		match: func(row Row) bool {
+			return row.Series.Timestamp().Equal(t)
+		},
+	}
+}
+
+func equalValues(actual, expected any) bool {
+	if reflect.DeepEqual(actual, expected) {
+		return true
+	}
+	a, ok := number(actual)
+	if !ok {
+		return false
+	}
+	e, ok := number(expected)
+	return ok && a == e
+}
+
+// number converts the value into float64, ok is false if it is not a number
+func number(v any) (float64, bool) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), true
+	case uint:
+		return float64(x), true
+	case int8, int16, int32, int64, uint8, uint16, uint32, uint64, float32, float64:
+		return toFloat(x), true
+	default:
+		return 0, false
+	}
+}
+
+// Rows returns the rows of the table matching all the matchers in order
+func (r *Recorder) Rows(table string, matchers ...RowMatcher) []Row {
+	var rows []Row
+	for _, insert := range r.Inserts() {
+		if insert.Table != table {
+			continue
+		}
+		for _, series := range insert.Metric.GetSeries() {
+			row := Row{Insert: insert, Series: series}
+			matched := true
+			for _, m := range matchers {
+				if !m.Match(row) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows
+}
+
+// TestingT is the subset of testing.T used by the assertions
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertRows asserts the table received n rows matching all the matchers
+func (r *Recorder) AssertRows(t TestingT, table string, n int, matchers ...RowMatcher) bool {
+	t.Helper()
+	if rows := r.Rows(table, matchers...); len(rows) != n {
+		descs := make([]string, 0, len(matchers))
+		for _, m := range matchers {
+			descs = append(descs, m.String())
+		}
+		t.Errorf("table %s received %d rows with [%s], but %d are expected", table, len(rows), strings.Join(descs, ", "), n)
+		return false
+	}
+	return true
+}
+
+// AssertColumn asserts the column of the table is received with the semantic type
+// and data type
+func (r *Recorder) AssertColumn(t TestingT, table string, column Column) bool {
+	t.Helper()
+	for _, insert := range r.Inserts() {
+		if insert.Table != table {
+			continue
+		}
+		if c, ok := insert.column(column.Name); ok {
+			if c != column {
+				t.Errorf("column %s of table %s is %s %s, but %s %s is expected", column.Name, table,
+					c.Semantic, c.Datatype, column.Semantic, column.Datatype)
+				return false
+			}
+			return true
+		}
+	}
+	t.Errorf("column %s of table %s is not received", column.Name, table)
+	return false
+}
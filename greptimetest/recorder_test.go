@@ -0,0 +1,113 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptimetest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/GreptimeTeam/greptimedb-client-go"
+	"github.com/GreptimeTeam/greptimedb-client-go/greptimetest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// fakeT records the failures of assertions
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := greptimetest.NewRecorder()
+	cfg := greptime.NewCfg("localhost").WithDatabase("public").
+		WithDialOptions(grpc.WithTransportCredentials(insecure.NewCredentials())).
+		WithInterceptors(recorder.Interceptor())
+	client, err := greptime.NewClient(cfg)
+	assert.Nil(t, err)
+
+	start := time.UnixMilli(1700000000000)
+	inserts := greptime.InsertsRequest{}
+	inserts.Append(*(&greptime.InsertRequest{}).WithTable("monitor").WithMetric(monitorMetric(t, start, "a", "b", "a")))
+	inserts.Append(*(&greptime.InsertRequest{}).WithTable("cpu").WithMetric(monitorMetric(t, start, "c")))
+
+	// responded without a server
+	resp, err := client.Insert(context.Background(), inserts)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), resp.GetAffectedRows().GetValue())
+
+	assert.Len(t, recorder.Requests(), 1)
+	assert.Len(t, recorder.Inserts(), 2)
+	assert.Equal(t, []string{"monitor", "cpu"}, recorder.Tables())
+	assert.Equal(t, "public", recorder.Inserts()[0].Database)
+	assert.Equal(t, greptime.OperationInsert, recorder.Inserts()[0].Operation)
+
+	recorder.AssertRows(t, "monitor", 3)
+	recorder.AssertRows(t, "monitor", 2, greptimetest.HasTag("host", "a"))
+	recorder.AssertRows(t, "monitor", 1, greptimetest.HasTag("host", "a"), greptimetest.HasField("memory", 2048))
+	recorder.AssertRows(t, "monitor", 1, greptimetest.HasNull("memory"))
+	recorder.AssertRows(t, "monitor", 1, greptimetest.HasTimestamp(start.Add(time.Minute)), greptimetest.HasField("cpu", 1.5))
+	recorder.AssertColumn(t, "monitor", greptimetest.Column{Name: "host", Semantic: greptimepb.SemanticType_TAG, Datatype: greptimepb.ColumnDataType_STRING})
+	recorder.AssertColumn(t, "monitor", greptimetest.Column{Name: "ts", Semantic: greptimepb.SemanticType_TIMESTAMP, Datatype: greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND})
+
+	rows := recorder.Rows("cpu")
+	assert.Len(t, rows, 1)
+	host, _ := rows[0].Series.GetString("host")
+	assert.Equal(t, "c", host)
+	assert.True(t, start.Equal(rows[0].Series.Timestamp()))
+
+	// the failed assertions
+	ft := &fakeT{}
+	assert.False(t, recorder.AssertRows(ft, "monitor", 1, greptimetest.HasField("host", "a")))
+	assert.False(t, recorder.AssertColumn(ft, "monitor", greptimetest.Column{Name: "cpu", Semantic: greptimepb.SemanticType_TAG, Datatype: greptimepb.ColumnDataType_FLOAT64}))
+	assert.False(t, recorder.AssertColumn(ft, "monitor", greptimetest.Column{Name: "unknown"}))
+	assert.Equal(t, []string{
+		"table monitor received 0 rows with [field host=a], but 1 are expected",
+		"column cpu of table monitor is FIELD FLOAT64, but TAG FLOAT64 is expected",
+		"column unknown of table monitor is not received",
+	}, ft.errors)
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Inserts())
+}
+
+func TestRecorderForward(t *testing.T) {
+	srv := greptimetest.NewServer()
+	defer srv.Close()
+
+	recorder := greptimetest.NewRecorder().WithForward()
+	client, err := greptime.NewStreamClient(srv.Config().WithInterceptors(recorder.Interceptor()))
+	assert.Nil(t, err)
+
+	inserts := greptime.InsertsRequest{}
+	inserts.Append(*(&greptime.InsertRequest{}).WithTable("monitor").WithMetric(monitorMetric(t, time.Now(), "a", "b")))
+	assert.Nil(t, client.Send(context.Background(), inserts))
+	affected, err := client.CloseAndRecv(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), affected.GetValue())
+
+	assert.Len(t, srv.Rows(greptimetest.DefaultDatabase, "monitor"), 2)
+	recorder.AssertRows(t, "monitor", 1, greptimetest.HasTag("host", "b"))
+	assert.Equal(t, greptime.OperationStreamInsert, recorder.Inserts()[0].Operation)
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptimetest
+
+import (
+	"fmt"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/apache/arrow/go/v13/arrow"
+	"github.com/apache/arrow/go/v13/arrow/array"
+	"github.com/apache/arrow/go/v13/arrow/flight"
+	"github.com/apache/arrow/go/v13/arrow/ipc"
+	"github.com/apache/arrow/go/v13/arrow/memory"
+)
+
+// arrowType maps the data type of greptimedb into arrow
+func arrowType(datatype greptimepb.ColumnDataType) (arrow.DataType, error) {
+	switch datatype {
+	case greptimepb.ColumnDataType_INT8:
+		return arrow.PrimitiveTypes.Int8, nil
+	case greptimepb.ColumnDataType_INT16:
+		return arrow.PrimitiveTypes.Int16, nil
+	case greptimepb.ColumnDataType_INT32:
+		return arrow.PrimitiveTypes.Int32, nil
+	case greptimepb.ColumnDataType_INT64:
+		return arrow.PrimitiveTypes.Int64, nil
+	case greptimepb.ColumnDataType_UINT8:
+		return arrow.PrimitiveTypes.Uint8, nil
+	case greptimepb.ColumnDataType_UINT16:
+		return arrow.PrimitiveTypes.Uint16, nil
+	case greptimepb.ColumnDataType_UINT32:
+		return arrow.PrimitiveTypes.Uint32, nil
+	case greptimepb.ColumnDataType_UINT64:
+		return arrow.PrimitiveTypes.Uint64, nil
+	case greptimepb.ColumnDataType_FLOAT32:
+		return arrow.PrimitiveTypes.Float32, nil
+	case greptimepb.ColumnDataType_FLOAT64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case greptimepb.ColumnDataType_BOOLEAN:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case greptimepb.ColumnDataType_STRING:
+		return arrow.BinaryTypes.String, nil
+	case greptimepb.ColumnDataType_BINARY:
+		return arrow.BinaryTypes.Binary, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_SECOND:
+		return arrow.FixedWidthTypes.Timestamp_s, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND:
+		return arrow.FixedWidthTypes.Timestamp_ms, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND:
+		return arrow.FixedWidthTypes.Timestamp_us, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:
+		return arrow.FixedWidthTypes.Timestamp_ns, nil
+	default:
+		return nil, fmt.Errorf("unsupported data type %s", datatype)
+	}
+}
+
+// record converts the result into arrow record, the caller is responsible to
+// release it
+func (r *result) record() (arrow.Record, error) {
+	fields := make([]arrow.Field, 0, len(r.columns))
+	for _, column := range r.columns {
+		typ, err := arrowType(column.datatype)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, arrow.Field{Name: column.name, Type: typ, Nullable: true})
+	}
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+	defer builder.Release()
+
+	for _, row := range r.rows {
+		for i, v := range row {
+			if err := appendValue(builder.Field(i), v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return builder.NewRecord(), nil
+}
+
+func appendValue(builder array.Builder, v any) error {
+	if v == nil {
+		builder.AppendNull()
+		return nil
+	}
+
+	switch b := builder.(type) {
+	case *array.Int8Builder:
+		b.Append(v.(int8))
+	case *array.Int16Builder:
+		b.Append(v.(int16))
+	case *array.Int32Builder:
+		b.Append(v.(int32))
+	case *array.Int64Builder:
+		b.Append(v.(int64))
+	case *array.Uint8Builder:
+		b.Append(v.(uint8))
+	case *array.Uint16Builder:
+		b.Append(v.(uint16))
+	case *array.Uint32Builder:
+		b.Append(v.(uint32))
+	case *array.Uint64Builder:
+		b.Append(v.(uint64))
+	case *array.Float32Builder:
+		b.Append(v.(float32))
+	case *array.Float64Builder:
+		b.Append(v.(float64))
+	case *array.BooleanBuilder:
+		b.Append(v.(bool))
+	case *array.StringBuilder:
+		b.Append(v.(string))
+	case *array.BinaryBuilder:
+		b.Append(v.([]byte))
+	case *array.TimestampBuilder:
+		t := v.(time.Time)
+		switch b.Type().(*arrow.TimestampType).Unit {
+		case arrow.Second:
+			b.Append(arrow.Timestamp(t.Unix()))
+		case arrow.Microsecond:
+			b.Append(arrow.Timestamp(t.UnixMicro()))
+		case arrow.Nanosecond:
+			b.Append(arrow.Timestamp(t.UnixNano()))
+		default:
+			b.Append(arrow.Timestamp(t.UnixMilli()))
+		}
+	default:
+		return fmt.Errorf("unsupported arrow builder %T", builder)
+	}
+	return nil
+}
+
+// write writes the result into the stream of DoGet
+func (r *result) write(stream flight.FlightService_DoGetServer) error {
+	record, err := r.record()
+	if err != nil {
+		return err
+	}
+	defer record.Release()
+
+	w := flight.NewRecordWriter(stream, ipc.WithSchema(record.Schema()))
+	defer w.Close()
+	return w.Write(record)
+}
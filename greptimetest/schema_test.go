@@ -0,0 +1,208 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptimetest_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GreptimeTeam/greptimedb-client-go"
+	"github.com/GreptimeTeam/greptimedb-client-go/greptimetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func cpuMetric(t *testing.T, host string, cpu any) greptime.Metric {
+	series := greptime.Series{}
+	assert.Nil(t, series.AddTag("host", host))
+	assert.Nil(t, series.AddField("cpu", cpu))
+	assert.Nil(t, series.SetTimestamp(time.Now()))
+	metric := greptime.Metric{}
+	assert.Nil(t, metric.AddSeries(series))
+	return metric
+}
+
+func insertMetric(client *greptime.Client, table string, metric greptime.Metric) error {
+	inserts := greptime.InsertsRequest{}
+	inserts.Append(*(&greptime.InsertRequest{}).WithTable(table).WithMetric(metric))
+	_, err := client.Insert(context.Background(), inserts)
+	return err
+}
+
+func TestInformationSchemaColumns(t *testing.T) {
+	_, client := newClient(t)
+	insert(t, client, "monitor", monitorMetric(t, time.Now(), "a", "b"))
+
+	sql := "SELECT column_name, data_type, semantic_type FROM information_schema.columns WHERE table_schema = 'public' AND table_name = 'monitor'"
+	metric, err := client.Query(context.Background(), *greptime.NewQueryRequest().WithSql(sql))
+	assert.Nil(t, err)
+
+	columns := map[string]string{}
+	for _, s := range metric.GetSeries() {
+		name, _ := s.GetString("column_name")
+		dataType, _ := s.GetString("data_type")
+		semanticType, _ := s.GetString("semantic_type")
+		columns[name] = semanticType + " " + dataType
+	}
+	assert.Equal(t, map[string]string{
+		"host":   "TAG String",
+		"cpu":    "FIELD Float64",
+		"memory": "FIELD UInt64",
+		"ts":     "TIMESTAMP TimestampMillisecond",
+	}, columns)
+}
+
+func TestSchemaValidation(t *testing.T) {
+	srv := greptimetest.NewServer()
+	defer srv.Close()
+
+	client, err := greptime.NewClient(srv.Config().WithSchemaValidation(time.Minute))
+	assert.Nil(t, err)
+
+	// the table is created
+	assert.Nil(t, insertMetric(client, "monitor", cpuMetric(t, "a", 0.5)))
+
+	// nothing is sent if the column mismatches
+	err = insertMetric(client, "monitor", cpuMetric(t, "b", int64(1)))
+	var e *greptime.Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, greptime.StatusInvalidArguments, e.Code)
+	assert.Equal(t, "monitor", e.Table)
+	assert.Equal(t, "cpu", e.Column)
+	assert.Len(t, srv.Rows(greptimetest.DefaultDatabase, "monitor"), 1)
+
+	// coerced into the type of the column
+	coercing, err := greptime.NewClient(srv.Config().WithSchemaCoercion())
+	assert.Nil(t, err)
+	assert.Nil(t, insertMetric(coercing, "monitor", cpuMetric(t, "b", int64(1))))
+	rows := srv.Rows(greptimetest.DefaultDatabase, "monitor")
+	assert.Len(t, rows, 2)
+	assert.Equal(t, float64(1), rows[1]["cpu"])
+
+	// the cached schema is refreshed if the table is recreated
+	srv.Reset()
+	assert.Nil(t, insertMetric(coercing, "monitor", cpuMetric(t, "a", "high")))
+	assert.Nil(t, insertMetric(client, "monitor", cpuMetric(t, "b", "low")))
+	assert.Len(t, srv.Rows(greptimetest.DefaultDatabase, "monitor"), 2)
+}
+
+func TestSchemaEvolution(t *testing.T) {
+	srv := greptimetest.NewServer()
+	defer srv.Close()
+
+	recorder := greptimetest.NewRecorder().WithForward()
+	client, err := greptime.NewClient(srv.Config().WithSchemaEvolution("monitor").WithInterceptors(recorder.Interceptor()))
+	assert.Nil(t, err)
+
+	for _, table := range []string{"monitor", "cpu"} {
+		assert.Nil(t, insertMetric(client, table, cpuMetric(t, "a", 0.5)))
+	}
+
+	withDisk := func(host string) greptime.Metric {
+		metric := cpuMetric(t, host, 0.5)
+		series := greptime.Series{}
+		assert.Nil(t, series.AddTag("host", host))
+		assert.Nil(t, series.AddField("disk", uint64(1024)))
+		assert.Nil(t, series.SetTimestamp(time.Now().Add(time.Second)))
+		assert.Nil(t, metric.AddSeries(series))
+		return metric
+	}
+
+	// the new field is added before inserting
+	recorder.Reset()
+	assert.Nil(t, insertMetric(client, "monitor", withDisk("b")))
+	var statements []string
+	for _, request := range recorder.Requests() {
+		if sql := request.GetQuery().GetSql(); strings.HasPrefix(sql, "ALTER") {
+			statements = append(statements, sql)
+		}
+	}
+	assert.Equal(t, []string{`ALTER TABLE "monitor" ADD COLUMN "disk" BIGINT UNSIGNED NULL`}, statements)
+	recorder.AssertRows(t, "monitor", 1, greptimetest.HasField("disk", 1024))
+
+	// the table not allowed is not altered
+	recorder.Reset()
+	assert.Nil(t, insertMetric(client, "cpu", withDisk("b")))
+	for _, request := range recorder.Requests() {
+		assert.NotContains(t, request.GetQuery().GetSql(), "ALTER")
+	}
+
+	// the column added by others since cached
+	other, err := greptime.NewClient(srv.Config().WithSchemaEvolution("monitor"))
+	assert.Nil(t, err)
+	assert.Nil(t, insertMetric(other, "monitor", cpuMetric(t, "c", 0.5)))
+	metric := cpuMetric(t, "c", 0.5)
+	series := greptime.Series{}
+	assert.Nil(t, series.AddTag("host", "c"))
+	assert.Nil(t, series.AddField("load", int64(1)))
+	assert.Nil(t, series.SetTimestamp(time.Now().Add(time.Second)))
+	assert.Nil(t, metric.AddSeries(series))
+	assert.Nil(t, insertMetric(client, "monitor", metric))
+	assert.Nil(t, insertMetric(other, "monitor", metric))
+
+	// the type of the column is never changed
+	series = greptime.Series{}
+	assert.Nil(t, series.AddTag("host", "c"))
+	assert.Nil(t, series.AddField("load", "high"))
+	assert.Nil(t, series.SetTimestamp(time.Now()))
+	metric = greptime.Metric{}
+	assert.Nil(t, metric.AddSeries(series))
+	assert.ErrorIs(t, insertMetric(other, "monitor", metric), greptime.StatusInvalidArguments)
+}
+
+func TestColumnarMetricSchemaValidation(t *testing.T) {
+	srv := greptimetest.NewServer()
+	defer srv.Close()
+
+	client, err := greptime.NewClient(srv.Config().WithSchemaEvolution("monitor"))
+	assert.Nil(t, err)
+	assert.Nil(t, insertMetric(client, "monitor", cpuMetric(t, "a", 0.5)))
+
+	insertColumnar := func(columnar *greptime.ColumnarMetric) error {
+		inserts := greptime.InsertsRequest{}
+		inserts.Append(*(&greptime.InsertRequest{}).WithTable("monitor").WithColumnarMetric(columnar))
+		_, err := client.Insert(context.Background(), inserts)
+		return err
+	}
+
+	// the new field is added
+	columnar := greptime.NewColumnarMetric()
+	host, cpu, disk := columnar.StringTag("host"), columnar.FloatField("cpu"), columnar.UintField("disk")
+	for i, h := range []string{"b", "c"} {
+		columnar.AddRow(time.Now())
+		host.Set(h)
+		cpu.Set(0.25)
+		if i == 1 {
+			disk.Set(1024)
+		}
+	}
+	assert.Nil(t, insertColumnar(columnar))
+	rows := srv.Rows(greptimetest.DefaultDatabase, "monitor")
+	assert.Len(t, rows, 3)
+	assert.Equal(t, uint64(1024), rows[2]["disk"])
+	assert.NotContains(t, rows[1], "disk")
+
+	// never coerced
+	columnar = greptime.NewColumnarMetric()
+	columnar.AddRow(time.Now())
+	columnar.StringTag("host").Set("d")
+	columnar.IntField("cpu").Set(1)
+	var e *greptime.Error
+	assert.True(t, errors.As(insertColumnar(columnar), &e))
+	assert.Equal(t, "cpu", e.Column)
+	assert.Len(t, srv.Rows(greptimetest.DefaultDatabase, "monitor"), 3)
+}
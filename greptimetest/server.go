@@ -0,0 +1,313 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package greptimetest provides an in-memory greptimedb server for unit tests, so
+// that the behaviour of [greptime.Client] can be tested without Docker.
+//
+// [NewServer] starts the server over an in-memory connection, and [Server.Config]
+// helps to create the client connecting to it:
+//
+//	srv := greptimetest.NewServer()
+//	defer srv.Close()
+//	client, err := greptime.NewClient(srv.Config())
+//
+// The server stores the inserted rows, and supports:
+//
+//   - inserting via [greptime.Client.Insert] and [greptime.StreamClient]
+//   - simple SQL like `SELECT host, cpu FROM monitor WHERE host = 'a' ORDER BY ts DESC LIMIT 10`
+//     via [greptime.Client.Query], including information_schema.columns
+//   - `ALTER TABLE monitor ADD COLUMN disk DOUBLE NULL` executed via unary calls
+//   - PromQL vector selectors like `monitor{host="a"}` via [greptime.Client.PromqlQuery],
+//     the value is the first numeric field of the table
+//
+// [Server.InjectError] helps to test how the failures are handled.
+//
+// If only the written requests matter, [Recorder] captures them via the interceptor
+// without a server, and asserts the rows via matchers like [HasTag].
+package greptimetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/GreptimeTeam/greptimedb-client-go"
+	"github.com/apache/arrow/go/v13/arrow/flight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+const bufferSize = 1024 * 1024
+
+// DefaultDatabase is the database created by default
+const DefaultDatabase = "public"
+
+// Server is the in-memory greptimedb server, it is safe for concurrent use
+type Server struct {
+	listener *bufconn.Listener
+	server   *grpc.Server
+	store    *store
+
+	mu       sync.Mutex
+	injected []*greptime.Error
+}
+
+// NewServer starts the server, and the caller is responsible to call [Server.Close]
+func NewServer() *Server {
+	s := &Server{
+		listener: bufconn.Listen(bufferSize),
+		server:   grpc.NewServer(),
+		store:    newStore(),
+	}
+
+	greptimepb.RegisterGreptimeDatabaseServer(s.server, &databaseServer{s: s})
+	greptimepb.RegisterPrometheusGatewayServer(s.server, &prometheusServer{s: s})
+	flight.RegisterFlightServiceServer(s.server, &flightServer{s: s})
+
+	go func() {
+		// Serve returns after the server is stopped
+		_ = s.server.Serve(s.listener)
+	}()
+	return s
+}
+
+// Close stops the server and closes the connections
+func (s *Server) Close() {
+	s.server.Stop()
+}
+
+// DialOptions helps to connect to the server via the in-memory connection
+func (s *Server) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+}
+
+// Config helps to init the Config of the client connecting to the server, the
+// database is [DefaultDatabase]
+func (s *Server) Config() *greptime.Config {
+	return greptime.NewCfg("bufconn").WithDatabase(DefaultDatabase).WithDialOptions(s.DialOptions()...)
+}
+
+// InjectError makes the next request fail with the code and msg. The errors are
+// injected in order if it is called multiple times.
+func (s *Server) InjectError(code greptime.StatusCode, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.injected = append(s.injected, &greptime.Error{Code: code, Msg: msg})
+}
+
+func (s *Server) popInjected() *greptime.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.injected) == 0 {
+		return nil
+	}
+	err := s.injected[0]
+	s.injected = s.injected[1:]
+	return err
+}
+
+// Tables returns the names of tables in the database in order of creation
+func (s *Server) Tables(database string) []string {
+	return s.store.tables(database)
+}
+
+// Rows returns the rows of the table ordered by the tags and timestamp, the values
+// are keyed by the column names, and NULLs are absent.
+func (s *Server) Rows(database, table string) []map[string]any {
+	return s.store.rows(database, table)
+}
+
+// Reset drops all the tables
+func (s *Server) Reset() {
+	s.store.reset()
+}
+
+// databaseServer implements GreptimeDatabase
+type databaseServer struct {
+	greptimepb.UnimplementedGreptimeDatabaseServer
+	s *Server
+}
+
+func (d *databaseServer) Handle(ctx context.Context, request *greptimepb.GreptimeRequest) (*greptimepb.GreptimeResponse, error) {
+	if err := d.s.popInjected(); err != nil {
+		return &greptimepb.GreptimeResponse{Header: errorHeader(err)}, nil
+	}
+
+	affected, err := d.s.handle(request)
+	if err != nil {
+		return &greptimepb.GreptimeResponse{Header: errorHeader(err)}, nil
+	}
+	return &greptimepb.GreptimeResponse{
+		Header:   successHeader(),
+		Response: &greptimepb.GreptimeResponse_AffectedRows{AffectedRows: &greptimepb.AffectedRows{Value: affected}},
+	}, nil
+}
+
+func (d *databaseServer) HandleRequests(stream greptimepb.GreptimeDatabase_HandleRequestsServer) error {
+	var affected uint32
+	var failed error
+	for {
+		request, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// the stream responds the first error once it is closed
+		if failed != nil {
+			continue
+		}
+		if err := d.s.popInjected(); err != nil {
+			failed = err
+			continue
+		}
+		n, err := d.s.handle(request)
+		if err != nil {
+			failed = err
+			continue
+		}
+		affected += n
+	}
+
+	resp := &greptimepb.GreptimeResponse{
+		Header:   successHeader(),
+		Response: &greptimepb.GreptimeResponse_AffectedRows{AffectedRows: &greptimepb.AffectedRows{Value: affected}},
+	}
+	if failed != nil {
+		resp.Header = errorHeader(failed)
+	}
+	return stream.SendAndClose(resp)
+}
+
+// handle handles the unary request, only inserts are supported
+func (s *Server) handle(request *greptimepb.GreptimeRequest) (uint32, error) {
+	database := request.GetHeader().GetDbname()
+	switch r := request.GetRequest().(type) {
+	case *greptimepb.GreptimeRequest_Inserts:
+		var affected uint32
+		for _, insert := range r.Inserts.GetInserts() {
+			n, err := s.store.insert(database, insert)
+			if err != nil {
+				return affected, err
+			}
+			affected += n
+		}
+		return affected, nil
+	case *greptimepb.GreptimeRequest_Query:
+		if sql, ok := r.Query.GetQuery().(*greptimepb.QueryRequest_Sql); ok {
+			return s.execute(database, sql.Sql)
+		}
+		return 0, &greptime.Error{Code: greptime.StatusUnsupported, Msg: fmt.Sprintf("unsupported query %T", r.Query.GetQuery())}
+	default:
+		return 0, &greptime.Error{Code: greptime.StatusUnsupported, Msg: fmt.Sprintf("unsupported request %T", r)}
+	}
+}
+
+// prometheusServer implements PrometheusGateway
+type prometheusServer struct {
+	greptimepb.UnimplementedPrometheusGatewayServer
+	s *Server
+}
+
+func (p *prometheusServer) Handle(ctx context.Context, request *greptimepb.PromqlRequest) (*greptimepb.PromqlResponse, error) {
+	if err := p.s.popInjected(); err != nil {
+		return &greptimepb.PromqlResponse{Header: errorHeader(err)}, nil
+	}
+
+	body, err := p.s.promql(request)
+	if err != nil {
+		return &greptimepb.PromqlResponse{Header: errorHeader(err)}, nil
+	}
+	return &greptimepb.PromqlResponse{Header: successHeader(), Body: body}, nil
+}
+
+// flightServer implements the DoGet of Arrow Flight
+type flightServer struct {
+	flight.BaseFlightServer
+	s *Server
+}
+
+func (f *flightServer) DoGet(ticket *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	if err := f.s.popInjected(); err != nil {
+		return statusError(stream, err)
+	}
+
+	request := &greptimepb.GreptimeRequest{}
+	if err := proto.Unmarshal(ticket.GetTicket(), request); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid ticket: %s", err)
+	}
+
+	res, err := f.s.query(request)
+	if err != nil {
+		return statusError(stream, err)
+	}
+	return res.write(stream)
+}
+
+func successHeader() *greptimepb.ResponseHeader {
+	return &greptimepb.ResponseHeader{Status: &greptimepb.Status{}}
+}
+
+func errorHeader(err error) *greptimepb.ResponseHeader {
+	code, msg := greptime.StatusUnknown, err.Error()
+	var e *greptime.Error
+	if errors.As(err, &e) {
+		code, msg = e.Code, e.Msg
+	}
+	return &greptimepb.ResponseHeader{Status: &greptimepb.Status{StatusCode: uint32(code), ErrMsg: msg}}
+}
+
+// statusError converts the error into gRPC status like greptimedb, whose message
+// is the message of the error, and the status code and message are also set in
+// the trailers
+func statusError(stream grpc.ServerStream, err error) error {
+	var e *greptime.Error
+	if !errors.As(err, &e) {
+		return status.Error(codes.Unknown, err.Error())
+	}
+	stream.SetTrailer(metadata.Pairs(
+		"x-greptime-err-code", strconv.FormatUint(uint64(e.Code), 10),
+		"x-greptime-err-msg", e.Msg,
+	))
+
+	code := codes.Internal
+	switch e.Code {
+	case greptime.StatusInvalidArguments, greptime.StatusInvalidSyntax, greptime.StatusUnsupported:
+		code = codes.InvalidArgument
+	case greptime.StatusTableNotFound, greptime.StatusTableColumnNotFound, greptime.StatusDatabaseNotFound:
+		code = codes.NotFound
+	case greptime.StatusRateLimited, greptime.StatusRuntimeResourcesExhausted:
+		code = codes.ResourceExhausted
+	case greptime.StatusStorageUnavailable:
+		code = codes.Unavailable
+	}
+	return status.Error(code, e.Msg)
+}
@@ -0,0 +1,387 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptimetest_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GreptimeTeam/greptimedb-client-go"
+	"github.com/GreptimeTeam/greptimedb-client-go/greptimetest"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func newClient(t *testing.T) (*greptimetest.Server, *greptime.Client) {
+	srv := greptimetest.NewServer()
+	t.Cleanup(srv.Close)
+
+	client, err := greptime.NewClient(srv.Config())
+	assert.Nil(t, err)
+	return srv, client
+}
+
+func monitorMetric(t *testing.T, start time.Time, hosts ...string) greptime.Metric {
+	metric := greptime.Metric{}
+	for i, host := range hosts {
+		series := greptime.Series{}
+		assert.Nil(t, series.AddTag("host", host))
+		assert.Nil(t, series.AddField("cpu", float64(i)+0.5))
+		if i%2 == 0 {
+			assert.Nil(t, series.AddField("memory", uint64(i*1024)))
+		}
+		assert.Nil(t, series.SetTimestamp(start.Add(time.Duration(i)*time.Minute)))
+		assert.Nil(t, metric.AddSeries(series))
+	}
+	return metric
+}
+
+func insert(t *testing.T, client *greptime.Client, table string, metric greptime.Metric) {
+	inserts := greptime.InsertsRequest{}
+	inserts.Append(*(&greptime.InsertRequest{}).WithTable(table).WithMetric(metric))
+	resp, err := client.Insert(context.Background(), inserts)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(len(metric.GetSeries())), resp.GetAffectedRows().GetValue())
+}
+
+func TestInsertAndQuery(t *testing.T) {
+	srv, client := newClient(t)
+	start := time.UnixMilli(1700000000000)
+	insert(t, client, "monitor", monitorMetric(t, start, "a", "b", "c"))
+
+	assert.Equal(t, []string{"monitor"}, srv.Tables(greptimetest.DefaultDatabase))
+	rows := srv.Rows(greptimetest.DefaultDatabase, "monitor")
+	assert.Len(t, rows, 3)
+	assert.Equal(t, map[string]any{"host": "b", "cpu": 1.5, "ts": start.Add(time.Minute)}, rows[1])
+
+	metric, err := client.Query(context.Background(), *greptime.NewQueryRequest().WithSql("SELECT * FROM monitor"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"host", "cpu", "memory", "ts"}, metric.GetTagsAndFields())
+	assert.Len(t, metric.GetSeries(), 3)
+
+	series := metric.GetSeries()[2]
+	host, _ := series.GetString("host")
+	assert.Equal(t, "c", host)
+	memory, _ := series.GetUint("memory")
+	assert.Equal(t, uint64(2048), memory)
+	ts, _ := series.GetTimestamp("ts")
+	assert.True(t, start.Add(2*time.Minute).Equal(ts))
+
+	// NULL is absent
+	_, ok := metric.GetSeries()[1].Get("memory")
+	assert.False(t, ok)
+
+	sql := "SELECT host, cpu FROM public.monitor WHERE cpu > 0.5 AND host != 'x' ORDER BY ts DESC LIMIT 1"
+	metric, err = client.Query(context.Background(), *greptime.NewQueryRequest().WithSql(sql))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"host", "cpu"}, metric.GetTagsAndFields())
+	assert.Len(t, metric.GetSeries(), 1)
+	host, _ = metric.GetSeries()[0].GetString("host")
+	assert.Equal(t, "c", host)
+
+	sql = "SELECT * FROM monitor WHERE ts >= '2023-11-14T22:14:20Z'"
+	metric, err = client.Query(context.Background(), *greptime.NewQueryRequest().WithSql(sql))
+	assert.Nil(t, err)
+	assert.Len(t, metric.GetSeries(), 2)
+
+	// the row of the same tags and timestamp is overwritten
+	insert(t, client, "monitor", monitorMetric(t, start, "a"))
+	assert.Len(t, srv.Rows(greptimetest.DefaultDatabase, "monitor"), 3)
+
+	srv.Reset()
+	assert.Empty(t, srv.Tables(greptimetest.DefaultDatabase))
+}
+
+func TestQuerySelectBuilder(t *testing.T) {
+	_, client := newClient(t)
+	start := time.UnixMilli(1700000000000)
+	insert(t, client, "monitor", monitorMetric(t, start, "a", "b", "c", "d"))
+
+	query := func(b *greptime.SelectBuilder) []string {
+		sql, err := b.Sql()
+		assert.Nil(t, err)
+		metric, err := client.Query(context.Background(), *greptime.NewQueryRequest().WithSql(sql))
+		assert.Nil(t, err, sql)
+		if err != nil {
+			return nil
+		}
+		var hosts []string
+		for _, series := range metric.GetSeries() {
+			host, _ := series.GetString("host")
+			hosts = append(hosts, host)
+		}
+		return hosts
+	}
+
+	// BETWEEN is inclusive, and the timestamps are like '2023-11-14 22:14:20.000Z'
+	assert.Equal(t, []string{"b", "c"}, query(greptime.NewSelect("monitor").
+		Columns("host", "cpu").
+		TimeRange(start.Add(time.Minute), start.Add(2*time.Minute))))
+
+	assert.Equal(t, []string{"c", "a"}, query(greptime.NewSelect("monitor").
+		Columns("host").
+		WhereTagIn("host", "a", "c", "x").
+		TimeRange(start, start.Add(3*time.Minute)).
+		OrderByDesc("ts").
+		Limit(2)))
+
+	assert.Equal(t, []string{"d"}, query(greptime.NewSelect("monitor").
+		WhereTag("host", "d").
+		WithTimePrecision(time.Second).
+		TimeRange(start.Add(3*time.Minute), start.Add(time.Hour))))
+}
+
+func TestDatabaseSQL(t *testing.T) {
+	srv, client := newClient(t)
+	start := time.UnixMilli(1700000000000)
+	insert(t, client, "monitor", monitorMetric(t, start, "a", "b", "c"))
+
+	connector, err := greptime.NewConnector(srv.Config())
+	assert.Nil(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	// the named types are converted by database/sql, and Ident is kept as it is
+	type hostName string
+	var host string
+	err = db.QueryRow("SELECT host FROM ? WHERE host = ?", greptime.Ident("monitor"), hostName("b")).Scan(&host)
+	assert.Nil(t, err)
+	assert.Equal(t, "b", host)
+
+	// the nil Valuer is NULL, which matches nothing
+	err = db.QueryRow("SELECT host FROM monitor WHERE host = ?", (*sql.NullString)(nil)).Scan(&host)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestQueryErrors(t *testing.T) {
+	_, client := newClient(t)
+	insert(t, client, "monitor", monitorMetric(t, time.Now(), "a"))
+
+	cases := map[string]codes.Code{
+		"SELECT * FROM not_exist":                                  codes.NotFound,
+		"SELECT unknown FROM monitor":                              codes.NotFound,
+		"SELECT * FROM monitor WHERE host = 1":                     codes.InvalidArgument,
+		"DELETE FROM monitor":                                      codes.InvalidArgument,
+		"SELECT * FROM monitor GROUP BY host":                      codes.InvalidArgument,
+		"SELECT * FROM monitor WHERE ts BETWEEN '2023-11-14' OR 1": codes.InvalidArgument,
+		"SELECT * FROM monitor WHERE host IN ('a'":                 codes.InvalidArgument,
+	}
+	for sql, code := range cases {
+		_, err := client.Query(context.Background(), *greptime.NewQueryRequest().WithSql(sql))
+		assert.Equal(t, code, status.Code(err), sql)
+	}
+
+	// the status codes are converted from the trailers
+	_, err := client.Query(context.Background(), *greptime.NewQueryRequest().WithSql("SELECT * FROM not_exist"))
+	assert.ErrorIs(t, err, greptime.StatusTableNotFound)
+	_, err = client.Query(context.Background(), *greptime.NewQueryRequest().WithSql("SELECT unknown FROM monitor"))
+	assert.ErrorIs(t, err, greptime.StatusTableColumnNotFound)
+	var e *greptime.Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "column unknown not found in table monitor", e.Msg)
+}
+
+func TestInsertSchemaMismatch(t *testing.T) {
+	_, client := newClient(t)
+	insert(t, client, "monitor", monitorMetric(t, time.Now(), "a"))
+
+	series := greptime.Series{}
+	assert.Nil(t, series.AddTag("host", "a"))
+	assert.Nil(t, series.AddField("cpu", "high"))
+	assert.Nil(t, series.SetTimestamp(time.Now()))
+	metric := greptime.Metric{}
+	assert.Nil(t, metric.AddSeries(series))
+
+	inserts := greptime.InsertsRequest{}
+	inserts.Append(*(&greptime.InsertRequest{}).WithTable("monitor").WithMetric(metric))
+	_, err := client.Insert(context.Background(), inserts)
+
+	var e *greptime.Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, greptime.StatusInvalidArguments, e.Code)
+	assert.Equal(t, "monitor", e.Table)
+	assert.Contains(t, e.Msg, "column cpu is FIELD FLOAT64")
+}
+
+func TestInjectError(t *testing.T) {
+	srv, client := newClient(t)
+	srv.InjectError(greptime.StatusRateLimited, "too many requests")
+	srv.InjectError(greptime.StatusStorageUnavailable, "storage is down")
+
+	inserts := greptime.InsertsRequest{}
+	inserts.Append(*(&greptime.InsertRequest{}).WithTable("monitor").WithMetric(monitorMetric(t, time.Now(), "a")))
+	_, err := client.Insert(context.Background(), inserts)
+	assert.ErrorIs(t, err, greptime.StatusRateLimited)
+
+	_, err = client.Query(context.Background(), *greptime.NewQueryRequest().WithSql("SELECT * FROM monitor"))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// the injected errors are consumed
+	_, err = client.Insert(context.Background(), inserts)
+	assert.Nil(t, err)
+}
+
+func TestStreamInsert(t *testing.T) {
+	srv := greptimetest.NewServer()
+	defer srv.Close()
+
+	client, err := greptime.NewStreamClient(srv.Config())
+	assert.Nil(t, err)
+
+	for _, table := range []string{"monitor", "cpu"} {
+		inserts := greptime.InsertsRequest{}
+		inserts.Append(*(&greptime.InsertRequest{}).WithTable(table).WithMetric(monitorMetric(t, time.Now(), "a", "b")))
+		assert.Nil(t, client.Send(context.Background(), inserts))
+	}
+
+	affected, err := client.CloseAndRecv(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), affected.GetValue())
+	assert.Equal(t, []string{"monitor", "cpu"}, srv.Tables(greptimetest.DefaultDatabase))
+}
+
+func TestPromql(t *testing.T) {
+	_, client := newClient(t)
+	start := time.Unix(1700000000, 0)
+	insert(t, client, "monitor", monitorMetric(t, start, "a", "b", "c"))
+
+	req := greptime.NewQueryRequest().WithInstantPromql(
+		greptime.NewInstantPromql(`monitor{host=~"a|b"}`).WithTime(start.Add(time.Minute)))
+	res, err := client.PromqlQueryResult(context.Background(), *req)
+	assert.Nil(t, err)
+	vector, ok := res.Vector()
+	assert.True(t, ok)
+	assert.Len(t, vector, 2)
+	assert.Equal(t, model.LabelValue("a"), vector[0].Metric["host"])
+	assert.Equal(t, model.SampleValue(0.5), vector[0].Value)
+
+	req = greptime.NewQueryRequest().WithRangePromql(greptime.NewRangePromql(`monitor{host="c"}`).
+		WithStart(start).WithEnd(start.Add(4 * time.Minute)).WithStep(time.Minute))
+	res, err = client.PromqlQueryResult(context.Background(), *req)
+	assert.Nil(t, err)
+	matrix, ok := res.Matrix()
+	assert.True(t, ok)
+	assert.Len(t, matrix, 1)
+	assert.Len(t, matrix[0].Values, 3)
+	assert.Equal(t, model.SampleValue(2.5), matrix[0].Values[0].Value)
+
+	// range query via flight
+	metric, err := client.Query(context.Background(), *req)
+	assert.Nil(t, err)
+	assert.Len(t, metric.GetSeries(), 3)
+	host, _ := metric.GetSeries()[0].GetString("host")
+	assert.Equal(t, "c", host)
+
+	req = greptime.NewQueryRequest().WithInstantPromql(greptime.NewInstantPromql(`sum(monitor)`))
+	_, err = client.PromqlQueryResult(context.Background(), *req)
+	assert.ErrorIs(t, err, greptime.StatusInvalidSyntax)
+}
+
+func TestInsertServerNowTimestamp(t *testing.T) {
+	srv := greptimetest.NewServer()
+	t.Cleanup(srv.Close)
+	cfg := srv.Config().WithTimestampValidation(greptime.ZeroTimestampServerNow, greptime.TruncationAllowed)
+	client, err := greptime.NewClient(cfg)
+	assert.Nil(t, err)
+
+	before := time.Now().Truncate(time.Millisecond)
+	insert(t, client, "monitor", monitorMetric(t, time.Time{}, "a"))
+
+	rows := srv.Rows(greptimetest.DefaultDatabase, "monitor")
+	assert.Len(t, rows, 1)
+	ts := rows[0]["ts"].(time.Time)
+	assert.False(t, ts.Before(before))
+	assert.WithinDuration(t, time.Now(), ts, time.Minute)
+}
+
+func TestNamingPolicyRoundTrip(t *testing.T) {
+	_, client := newClient(t)
+
+	metric := greptime.Metric{}
+	assert.Nil(t, metric.SetNamingPolicy(greptime.NamingPreserve))
+	series := greptime.Series{}
+	assert.Nil(t, series.AddTag("HostName", "a"))
+	assert.Nil(t, series.AddField("HTTPStatus", int64(200)))
+	assert.Nil(t, series.SetTimestamp(time.UnixMilli(1700000000000)))
+	assert.Nil(t, metric.AddSeries(series))
+	insert(t, client, "monitor", metric)
+
+	sql := `SELECT "HostName", "HTTPStatus" FROM monitor`
+	queried, err := client.Query(context.Background(), *greptime.NewQueryRequest().WithSql(sql))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"HostName", "HTTPStatus"}, queried.GetTagsAndFields())
+	status, ok := queried.GetSeries()[0].GetInt("HTTPStatus")
+	assert.True(t, ok)
+	assert.Equal(t, int64(200), status)
+}
+
+func TestInsertSplit(t *testing.T) {
+	srv := greptimetest.NewServer()
+	t.Cleanup(srv.Close)
+
+	// the second insert fails once
+	calls := 0
+	failing := func(ctx context.Context, call *greptime.Call, invoker greptime.Invoker) (proto.Message, error) {
+		if call.Operation == greptime.OperationInsert {
+			if calls++; calls == 2 {
+				return nil, &greptime.Error{Code: greptime.StatusRateLimited, Msg: "too many requests"}
+			}
+		}
+		return invoker(ctx, call)
+	}
+	recorder := greptimetest.NewRecorder().WithForward()
+	cfg := srv.Config().WithMaxRequestSize(2048).WithInterceptors(failing, recorder.Interceptor())
+	client, err := greptime.NewClient(cfg)
+	assert.Nil(t, err)
+
+	hosts := make([]string, 100)
+	for i := range hosts {
+		hosts[i] = fmt.Sprintf("host-%d", i)
+	}
+	start := time.UnixMilli(1700000000000)
+	inserts := greptime.InsertsRequest{}
+	inserts.Append(*(&greptime.InsertRequest{}).WithTable("monitor").WithMetric(monitorMetric(t, start, hosts...)))
+	inserts.Append(*(&greptime.InsertRequest{}).WithTable("cpu").WithMetric(monitorMetric(t, start, hosts[:10]...)))
+
+	resp, err := client.Insert(context.Background(), inserts)
+	var partial *greptime.PartialInsertError
+	assert.True(t, errors.As(err, &partial))
+	assert.ErrorIs(t, err, greptime.StatusRateLimited)
+	assert.Len(t, partial.Inserted, 1)
+	assert.Equal(t, partial.Inserted[0].To, partial.Failed[0].From)
+	assert.Equal(t, "cpu", partial.Failed[len(partial.Failed)-1].Table)
+	assert.Equal(t, uint32(partial.Inserted[0].To), partial.AffectedRows)
+	assert.Equal(t, partial.AffectedRows, resp.GetAffectedRows().GetValue())
+	assert.Len(t, srv.Rows(greptimetest.DefaultDatabase, "monitor"), partial.Inserted[0].To)
+
+	// split into multiple requests, and the affected rows are summed up
+	recorder.Reset()
+	resp, err = client.Insert(context.Background(), inserts)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(110), resp.GetAffectedRows().GetValue())
+	assert.Greater(t, len(recorder.Requests()), 1)
+	for _, request := range recorder.Requests() {
+		assert.LessOrEqual(t, proto.Size(request), 2048)
+	}
+	assert.Len(t, srv.Rows(greptimetest.DefaultDatabase, "monitor"), 100)
+	assert.Len(t, srv.Rows(greptimetest.DefaultDatabase, "cpu"), 10)
+}
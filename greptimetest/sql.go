@@ -0,0 +1,600 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptimetest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/GreptimeTeam/greptimedb-client-go"
+)
+
+// selectStmt is the supported subset of SELECT:
+//
+//	SELECT * | column [, column ...] FROM [database.]table
+//	[WHERE predicate [AND predicate ...]]
+//	[ORDER BY column [ASC | DESC] [, ...]]
+//	[LIMIT n]
+//
+// predicate is one of:
+//
+//	column op literal
+//	column BETWEEN literal AND literal
+//	column IN (literal [, literal ...])
+//
+// op is one of =, !=, <>, <, <=, > and >=, literal is a quoted string, a number,
+// true, false or NULL, which matches nothing. The string literal is parsed as time
+// if the column is timestamp, like the ones formatted by [greptime.SelectBuilder].
+type selectStmt struct {
+	columns  []string // empty if *
+	database string
+	table    string
+	where    []condition
+	orderBy  []ordering
+	limit    int // -1 if no limit
+}
+
+type condition struct {
+	column string
+	op     string    // one of the comparison operators, BETWEEN or IN
+	values []literal // two for BETWEEN, one or more for IN, otherwise one
+}
+
+type literal struct {
+	text   string
+	quoted bool
+}
+
+type ordering struct {
+	column string
+	desc   bool
+}
+
+func syntaxError(format string, args ...any) error {
+	return &greptime.Error{Code: greptime.StatusInvalidSyntax, Msg: fmt.Sprintf(format, args...)}
+}
+
+// token is a word, a quoted identifier or literal, or a symbol
+type token struct {
+	text  string
+	quote byte // ', " or `, 0 if not quoted
+}
+
+func (t token) is(keyword string) bool {
+	return t.quote == 0 && strings.EqualFold(t.text, keyword)
+}
+
+func tokenize(sql string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case unicode.IsSpace(rune(c)) || c == ';':
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(sql); j++ {
+				if sql[j] != c {
+					b.WriteByte(sql[j])
+					continue
+				}
+				// the quote is escaped by doubling it
+				if j+1 < len(sql) && sql[j+1] == c {
+					b.WriteByte(c)
+					j++
+					continue
+				}
+				break
+			}
+			if j >= len(sql) {
+				return nil, syntaxError("unclosed quote at %d", i)
+			}
+			tokens = append(tokens, token{text: b.String(), quote: c})
+			i = j + 1
+		case strings.ContainsRune("=<>!", rune(c)):
+			j := i + 1
+			for j < len(sql) && strings.ContainsRune("=<>", rune(sql[j])) {
+				j++
+			}
+			tokens = append(tokens, token{text: sql[i:j]})
+			i = j
+		case c == ',' || c == '*' || c == '.' || c == '(' || c == ')':
+			tokens = append(tokens, token{text: string(c)})
+			i++
+		default:
+			j := i
+			for j < len(sql) && (isWordChar(sql[j]) || (sql[j] == '.' && j > i && isNumber(sql[i:j]))) {
+				j++
+			}
+			if j == i {
+				return nil, syntaxError("unexpected character %q at %d", c, i)
+			}
+			tokens = append(tokens, token{text: sql[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '-' || c == '+' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// parser parses the tokens of SQL
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) next() (token, error) {
+	t, ok := p.peek()
+	if !ok {
+		return token{}, syntaxError("unexpected end of statement")
+	}
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) accept(keyword string) bool {
+	if t, ok := p.peek(); ok && t.is(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(keyword string) error {
+	if !p.accept(keyword) {
+		t, _ := p.peek()
+		return syntaxError("expected %s, but got %q", keyword, t.text)
+	}
+	return nil
+}
+
+// ident parses an identifier, the unquoted one is case insensitive
+func (p *parser) ident() (string, error) {
+	t, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case t.quote == '"' || t.quote == '`':
+		return t.text, nil
+	case t.quote == 0 && len(t.text) > 0 && (unicode.IsLetter(rune(t.text[0])) || t.text[0] == '_'):
+		return strings.ToLower(t.text), nil
+	default:
+		return "", syntaxError("expected identifier, but got %q", t.text)
+	}
+}
+
+func parseSelect(sql string) (*selectStmt, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	stmt := &selectStmt{limit: -1}
+
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	if !p.accept("*") {
+		for {
+			column, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			stmt.columns = append(stmt.columns, column)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if err := p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	if stmt.table, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if p.accept(".") {
+		stmt.database = stmt.table
+		if stmt.table, err = p.ident(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.accept("WHERE") {
+		for {
+			cond, err := p.condition()
+			if err != nil {
+				return nil, err
+			}
+			stmt.where = append(stmt.where, cond)
+			if !p.accept("AND") {
+				break
+			}
+		}
+	}
+
+	if p.accept("ORDER") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			column, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			o := ordering{column: column}
+			if p.accept("DESC") {
+				o.desc = true
+			} else {
+				p.accept("ASC")
+			}
+			stmt.orderBy = append(stmt.orderBy, o)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if p.accept("LIMIT") {
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if stmt.limit, err = strconv.Atoi(t.text); err != nil || stmt.limit < 0 || t.quote != 0 {
+			return nil, syntaxError("invalid limit %q", t.text)
+		}
+	}
+
+	if t, ok := p.peek(); ok {
+		return nil, syntaxError("unsupported clause at %q", t.text)
+	}
+	return stmt, nil
+}
+
+func (p *parser) condition() (condition, error) {
+	column, err := p.ident()
+	if err != nil {
+		return condition{}, err
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return condition{}, err
+	}
+	cond := condition{column: column, op: op.text}
+	switch {
+	case op.quote != 0:
+		return condition{}, syntaxError("unsupported operator %q", op.text)
+	case op.is("BETWEEN"):
+		cond.op = "BETWEEN"
+		low, err := p.literal()
+		if err != nil {
+			return condition{}, err
+		}
+		if err := p.expect("AND"); err != nil {
+			return condition{}, err
+		}
+		high, err := p.literal()
+		if err != nil {
+			return condition{}, err
+		}
+		cond.values = []literal{low, high}
+	case op.is("IN"):
+		cond.op = "IN"
+		if err := p.expect("("); err != nil {
+			return condition{}, err
+		}
+		for {
+			value, err := p.literal()
+			if err != nil {
+				return condition{}, err
+			}
+			cond.values = append(cond.values, value)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return condition{}, err
+		}
+	default:
+		switch op.text {
+		case "=", "!=", "<>", "<", "<=", ">", ">=":
+		default:
+			return condition{}, syntaxError("unsupported operator %q", op.text)
+		}
+		value, err := p.literal()
+		if err != nil {
+			return condition{}, err
+		}
+		cond.values = []literal{value}
+	}
+	return cond, nil
+}
+
+func (p *parser) literal() (literal, error) {
+	value, err := p.next()
+	if err != nil {
+		return literal{}, err
+	}
+	if value.quote == '"' || value.quote == '`' {
+		return literal{}, syntaxError("comparing columns is not supported")
+	}
+	return literal{text: value.text, quoted: value.quote != 0}, nil
+}
+
+// result is the columns and rows responded to the query
+type result struct {
+	columns []columnSchema
+	rows    [][]any
+}
+
+// query queries via flight DoGet, SQL and range PromQL are supported
+func (s *Server) query(request *greptimepb.GreptimeRequest) (*result, error) {
+	database := request.GetHeader().GetDbname()
+	switch q := request.GetQuery().GetQuery().(type) {
+	case *greptimepb.QueryRequest_Sql:
+		stmt, err := parseSelect(q.Sql)
+		if err != nil {
+			return nil, err
+		}
+		if len(stmt.database) > 0 {
+			database = stmt.database
+		}
+		return s.store.query(database, stmt)
+	case *greptimepb.QueryRequest_PromRangeQuery:
+		return s.rangePromqlResult(database, q.PromRangeQuery)
+	default:
+		return nil, &greptime.Error{Code: greptime.StatusUnsupported, Msg: fmt.Sprintf("unsupported query %T", q)}
+	}
+}
+
+func (s *store) query(database string, stmt *selectStmt) (*result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.table(database, stmt.table)
+	if t == nil && database == informationSchema && stmt.table == "columns" {
+		t = s.columnsTable()
+	}
+	if t == nil {
+		return nil, &greptime.Error{Code: greptime.StatusTableNotFound, Msg: fmt.Sprintf("table %s.%s not found", database, stmt.table), Table: stmt.table}
+	}
+
+	columns := t.columns
+	if len(stmt.columns) > 0 {
+		columns = make([]columnSchema, 0, len(stmt.columns))
+		for _, name := range stmt.columns {
+			column, err := t.lookup(name)
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, column)
+		}
+	}
+
+	filters := make([]func(map[string]any) bool, 0, len(stmt.where))
+	for _, cond := range stmt.where {
+		column, err := t.lookup(cond.column)
+		if err != nil {
+			return nil, err
+		}
+		filter, err := cond.filter(column)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	for _, o := range stmt.orderBy {
+		if _, err := t.lookup(o.column); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []map[string]any
+	for _, row := range t.sortedRows() {
+		matched := true
+		for _, filter := range filters {
+			if !filter(row) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			rows = append(rows, row)
+		}
+	}
+
+	sortRows(rows, stmt.orderBy)
+	if stmt.limit >= 0 && len(rows) > stmt.limit {
+		rows = rows[:stmt.limit]
+	}
+
+	res := &result{columns: columns, rows: make([][]any, 0, len(rows))}
+	for _, row := range rows {
+		values := make([]any, 0, len(columns))
+		for _, column := range columns {
+			values = append(values, row[column.name])
+		}
+		res.rows = append(res.rows, values)
+	}
+	return res, nil
+}
+
+func (t *table) lookup(name string) (columnSchema, error) {
+	column, ok := t.column(name)
+	if !ok {
+		return column, &greptime.Error{Code: greptime.StatusTableColumnNotFound,
+			Msg: fmt.Sprintf("column %s not found in table %s", name, t.name), Table: t.name, Column: name}
+	}
+	return column, nil
+}
+
+// filter returns the predicate of the condition, NULL does not match any condition
+func (c condition) filter(column columnSchema) (func(map[string]any) bool, error) {
+	values := make([]any, 0, len(c.values))
+	for _, l := range c.values {
+		value, err := l.parse(column)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return func(row map[string]any) bool {
+		v, ok := row[column.name]
+		if !ok {
+			return false
+		}
+		switch c.op {
+		case "BETWEEN":
+			if values[0] == nil || values[1] == nil {
+				return false
+			}
+			return compareValues(v, values[0]) >= 0 && compareValues(v, values[1]) <= 0
+		case "IN":
+			for _, value := range values {
+				if value != nil && compareValues(v, value) == 0 {
+					return true
+				}
+			}
+			return false
+		}
+
+		if values[0] == nil {
+			return false
+		}
+
+		cmp := compareValues(v, values[0])
+		switch c.op {
+		case "=":
+			return cmp == 0
+		case "!=", "<>":
+			return cmp != 0
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		default: // >=
+			return cmp >= 0
+		}
+	}, nil
+}
+
+// parse parses the literal into the value comparable with the column
+func (l literal) parse(column columnSchema) (any, error) {
+	invalid := &greptime.Error{Code: greptime.StatusInvalidArguments,
+		Msg: fmt.Sprintf("%q can not be compared with column %s of %s", l.text, column.name, column.datatype), Column: column.name}
+	if !l.quoted && strings.EqualFold(l.text, "NULL") {
+		return nil, nil
+	}
+
+	switch column.datatype {
+	case greptimepb.ColumnDataType_STRING:
+		if !l.quoted {
+			return nil, invalid
+		}
+		return l.text, nil
+	case greptimepb.ColumnDataType_BINARY:
+		return []byte(l.text), nil
+	case greptimepb.ColumnDataType_BOOLEAN:
+		b, err := strconv.ParseBool(l.text)
+		if err != nil {
+			return nil, invalid
+		}
+		return b, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_SECOND, greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND,
+		greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND, greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:
+		if !l.quoted {
+			// the number is in the precision of the column
+			n, err := strconv.ParseInt(l.text, 10, 64)
+			if err != nil {
+				return nil, invalid
+			}
+			return timeOf(column.datatype, n), nil
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00",
+			"2006-01-02 15:04:05.999999999", "2006-01-02"} {
+			if t, err := time.Parse(layout, l.text); err == nil {
+				return t, nil
+			}
+		}
+		return nil, invalid
+	default:
+		if l.quoted {
+			return nil, invalid
+		}
+		f, err := strconv.ParseFloat(l.text, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return f, nil
+	}
+}
+
+func timeOf(datatype greptimepb.ColumnDataType, n int64) time.Time {
+	switch datatype {
+	case greptimepb.ColumnDataType_TIMESTAMP_SECOND:
+		return time.Unix(n, 0)
+	case greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND:
+		return time.UnixMicro(n)
+	case greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:
+		return time.Unix(0, n)
+	default:
+		return time.UnixMilli(n)
+	}
+}
+
+func sortRows(rows []map[string]any, orderBy []ordering) {
+	if len(orderBy) == 0 {
+		return
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, o := range orderBy {
+			cmp := compareValues(rows[i][o.column], rows[j][o.column])
+			if o.desc {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,407 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptimetest
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/GreptimeTeam/greptimedb-client-go"
+)
+
+// columnSchema is the schema of a column, which is decided by the first insert
+type columnSchema struct {
+	name     string
+	semantic greptimepb.SemanticType
+	datatype greptimepb.ColumnDataType
+}
+
+// table keeps the rows deduplicated by the tags and timestamp like greptimedb,
+// the later row overwrites the earlier one
+type table struct {
+	name    string
+	columns []columnSchema
+	rows    map[string]map[string]any
+}
+
+func (t *table) column(name string) (columnSchema, bool) {
+	for _, c := range t.columns {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return columnSchema{}, false
+}
+
+func (t *table) timestampColumn() (columnSchema, bool) {
+	for _, c := range t.columns {
+		if c.semantic == greptimepb.SemanticType_TIMESTAMP {
+			return c, true
+		}
+	}
+	return columnSchema{}, false
+}
+
+func (t *table) tagColumns() []columnSchema {
+	var tags []columnSchema
+	for _, c := range t.columns {
+		if c.semantic == greptimepb.SemanticType_TAG {
+			tags = append(tags, c)
+		}
+	}
+	return tags
+}
+
+// primaryKey is the tags and timestamp of the row
+func (t *table) primaryKey(row map[string]any) string {
+	var b strings.Builder
+	for _, c := range t.columns {
+		if c.semantic == greptimepb.SemanticType_FIELD {
+			continue
+		}
+		fmt.Fprintf(&b, "%v\x00", row[c.name])
+	}
+	return b.String()
+}
+
+// sortedRows returns the rows ordered by the tags and timestamp
+func (t *table) sortedRows() []map[string]any {
+	rows := make([]map[string]any, 0, len(t.rows))
+	for _, row := range t.rows {
+		rows = append(rows, row)
+	}
+
+	var keys []string
+	for _, c := range t.tagColumns() {
+		keys = append(keys, c.name)
+	}
+	if ts, ok := t.timestampColumn(); ok {
+		keys = append(keys, ts.name)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, key := range keys {
+			if c := compareValues(rows[i][key], rows[j][key]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return rows
+}
+
+type store struct {
+	mu        sync.RWMutex
+	databases map[string][]*table
+}
+
+func newStore() *store {
+	return &store{databases: map[string][]*table{}}
+}
+
+func (s *store) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.databases = map[string][]*table{}
+}
+
+// table returns the table, the caller MUST hold the lock
+func (s *store) table(database, name string) *table {
+	for _, t := range s.databases[database] {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *store) tables(database string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var names []string
+	for _, t := range s.databases[database] {
+		names = append(names, t.name)
+	}
+	return names
+}
+
+func (s *store) rows(database, name string) []map[string]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.table(database, name)
+	if t == nil {
+		return nil
+	}
+	return copyRows(t.sortedRows())
+}
+
+// insert creates the table if not exists and adds the new columns like greptimedb
+func (s *store) insert(database string, insert *greptimepb.InsertRequest) (uint32, error) {
+	if len(database) == 0 {
+		return 0, &greptime.Error{Code: greptime.StatusInvalidArguments, Msg: "database is not specified"}
+	}
+
+	schemas, rows, err := decodeInsert(insert)
+	if err != nil {
+		return 0, &greptime.Error{Code: greptime.StatusInvalidArguments, Msg: err.Error(), Table: insert.GetTableName()}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(database, insert.GetTableName())
+	if t == nil {
+		t = &table{name: insert.GetTableName(), rows: map[string]map[string]any{}}
+		s.databases[database] = append(s.databases[database], t)
+	}
+
+	var added []columnSchema
+	for _, schema := range schemas {
+		existing, ok := t.column(schema.name)
+		if !ok {
+			added = append(added, schema)
+			continue
+		}
+		if existing.semantic != schema.semantic || existing.datatype != schema.datatype {
+			return 0, &greptime.Error{
+				Code:   greptime.StatusInvalidArguments,
+				Msg:    fmt.Sprintf("column %s is %s %s, but %s %s is inserted", schema.name, existing.semantic, existing.datatype, schema.semantic, schema.datatype),
+				Table:  t.name,
+				Column: schema.name,
+			}
+		}
+	}
+	t.columns = append(t.columns, added...)
+
+	for _, row := range rows {
+		t.rows[t.primaryKey(row)] = row
+	}
+	return uint32(len(rows)), nil
+}
+
+// decodeInsert decodes the columns of the insert into rows, the NULLs are absent
+// in the rows
+func decodeInsert(insert *greptimepb.InsertRequest) ([]columnSchema, []map[string]any, error) {
+	if len(insert.GetTableName()) == 0 {
+		return nil, nil, fmt.Errorf("table name is empty")
+	}
+
+	n := int(insert.GetRowCount())
+	rows := make([]map[string]any, n)
+	for i := range rows {
+		rows[i] = map[string]any{}
+	}
+
+	schemas := make([]columnSchema, 0, len(insert.GetColumns()))
+	tsCount := 0
+	for _, col := range insert.GetColumns() {
+		if col.GetSemanticType() == greptimepb.SemanticType_TIMESTAMP {
+			tsCount++
+		}
+		schemas = append(schemas, columnSchema{name: col.GetColumnName(), semantic: col.GetSemanticType(), datatype: col.GetDatatype()})
+
+		values, err := decodeValues(col)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		idx := 0
+		for i := 0; i < n; i++ {
+			if isNull(col.GetNullMask(), i) {
+				// the NULL timestamp is the current time like `DEFAULT CURRENT_TIMESTAMP()`
+				if col.GetSemanticType() == greptimepb.SemanticType_TIMESTAMP {
+					rows[i][col.GetColumnName()] = currentTime(col.GetDatatype())
+				}
+				continue
+			}
+			if idx >= len(values) {
+				return nil, nil, fmt.Errorf("column %s has %d values, less than the rows", col.GetColumnName(), len(values))
+			}
+			rows[i][col.GetColumnName()] = values[idx]
+			idx++
+		}
+		if idx != len(values) {
+			return nil, nil, fmt.Errorf("column %s has %d values, but %d rows are not null", col.GetColumnName(), len(values), idx)
+		}
+	}
+
+	if tsCount != 1 {
+		return nil, nil, fmt.Errorf("exactly one timestamp column is required, but got %d", tsCount)
+	}
+	return schemas, rows, nil
+}
+
+// currentTime returns the current time in the precision of the data type
+func currentTime(datatype greptimepb.ColumnDataType) time.Time {
+	now := time.Now()
+	switch datatype {
+	case greptimepb.ColumnDataType_TIMESTAMP_SECOND:
+		return timeOf(datatype, now.Unix())
+	case greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND:
+		return timeOf(datatype, now.UnixMicro())
+	case greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:
+		return timeOf(datatype, now.UnixNano())
+	default:
+		return timeOf(datatype, now.UnixMilli())
+	}
+}
+
+// isNull checks the bit of the row in the null mask, which is in LSB order
+func isNull(mask []byte, i int) bool {
+	if i/8 >= len(mask) {
+		return false
+	}
+	return mask[i/8]&(1<<(i%8)) != 0
+}
+
+func decodeValues(col *greptimepb.Column) ([]any, error) {
+	v := col.GetValues()
+	switch col.GetDatatype() {
+	case greptimepb.ColumnDataType_INT8:
+		return convertValues(v.GetI8Values(), func(x int32) any { return int8(x) }), nil
+	case greptimepb.ColumnDataType_INT16:
+		return convertValues(v.GetI16Values(), func(x int32) any { return int16(x) }), nil
+	case greptimepb.ColumnDataType_INT32:
+		return convertValues(v.GetI32Values(), func(x int32) any { return x }), nil
+	case greptimepb.ColumnDataType_INT64:
+		return convertValues(v.GetI64Values(), func(x int64) any { return x }), nil
+	case greptimepb.ColumnDataType_UINT8:
+		return convertValues(v.GetU8Values(), func(x uint32) any { return uint8(x) }), nil
+	case greptimepb.ColumnDataType_UINT16:
+		return convertValues(v.GetU16Values(), func(x uint32) any { return uint16(x) }), nil
+	case greptimepb.ColumnDataType_UINT32:
+		return convertValues(v.GetU32Values(), func(x uint32) any { return x }), nil
+	case greptimepb.ColumnDataType_UINT64:
+		return convertValues(v.GetU64Values(), func(x uint64) any { return x }), nil
+	case greptimepb.ColumnDataType_FLOAT32:
+		return convertValues(v.GetF32Values(), func(x float32) any { return x }), nil
+	case greptimepb.ColumnDataType_FLOAT64:
+		return convertValues(v.GetF64Values(), func(x float64) any { return x }), nil
+	case greptimepb.ColumnDataType_BOOLEAN:
+		return convertValues(v.GetBoolValues(), func(x bool) any { return x }), nil
+	case greptimepb.ColumnDataType_STRING:
+		return convertValues(v.GetStringValues(), func(x string) any { return x }), nil
+	case greptimepb.ColumnDataType_BINARY:
+		return convertValues(v.GetBinaryValues(), func(x []byte) any { return x }), nil
+	case greptimepb.ColumnDataType_TIMESTAMP_SECOND:
+		return convertValues(v.GetTimestampSecondValues(), func(x int64) any { return time.Unix(x, 0) }), nil
+	case greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND:
+		return convertValues(v.GetTimestampMillisecondValues(), func(x int64) any { return time.UnixMilli(x) }), nil
+	case greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND:
+		return convertValues(v.GetTimestampMicrosecondValues(), func(x int64) any { return time.UnixMicro(x) }), nil
+	case greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:
+		return convertValues(v.GetTimestampNanosecondValues(), func(x int64) any { return time.Unix(0, x) }), nil
+	default:
+		return nil, fmt.Errorf("unsupported data type %s of column %s", col.GetDatatype(), col.GetColumnName())
+	}
+}
+
+func convertValues[T any](values []T, f func(T) any) []any {
+	res := make([]any, 0, len(values))
+	for _, v := range values {
+		res = append(res, f(v))
+	}
+	return res
+}
+
+// compareValues compares the values of the same column, NULL is the smallest
+func compareValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch x := a.(type) {
+	case string:
+		return strings.Compare(x, b.(string))
+	case []byte:
+		return bytes.Compare(x, b.([]byte))
+	case bool:
+		y := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		default:
+			return 1
+		}
+	case time.Time:
+		return x.Compare(b.(time.Time))
+	default:
+		return compareNumbers(toFloat(a), toFloat(b))
+	}
+}
+
+func compareNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// toFloat converts the numeric value into float64
+func toFloat(v any) float64 {
+	switch x := v.(type) {
+	case int8:
+		return float64(x)
+	case int16:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case uint8:
+		return float64(x)
+	case uint16:
+		return float64(x)
+	case uint32:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	case float32:
+		return float64(x)
+	case float64:
+		return x
+	default:
+		return 0
+	}
+}
+
+func copyRows(rows []map[string]any) []map[string]any {
+	res := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		copied := make(map[string]any, len(row))
+		for k, v := range row {
+			copied[k] = v
+		}
+		res = append(res, copied)
+	}
+	return res
+}
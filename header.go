@@ -49,13 +49,21 @@ func (h RespHeader) IsSuccess() bool {
 }
 
 func (h RespHeader) IsRateLimited() bool {
-	return h.Code == 6001
+	return StatusCode(h.Code) == StatusRateLimited
 }
 
 func (h RespHeader) IsNil() bool {
 	return h.Code == 0 && isEmptyString(h.Msg)
 }
 
+// Err returns the [*Error] of the header, or nil if the response is success
+func (h RespHeader) Err() error {
+	if err := newError(h.Code, h.Msg); err != nil {
+		return err
+	}
+	return nil
+}
+
 type getRespHeader interface {
 	GetHeader() *greptimepb.ResponseHeader
 }
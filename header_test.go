@@ -41,3 +41,11 @@ func TestHeaderBuild(t *testing.T) {
 		Dbname: "db_in_header",
 	}, gh)
 }
+
+func TestRespHeaderErr(t *testing.T) {
+	assert.Nil(t, RespHeader{}.Err())
+
+	err := RespHeader{Code: 6001, Msg: "rate limited"}.Err()
+	assert.EqualError(t, err, "code: 6001 (RateLimited), msg: rate limited")
+	assert.ErrorIs(t, err, StatusRateLimited)
+}
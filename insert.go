@@ -15,6 +15,9 @@
 package greptime
 
 import (
+	"fmt"
+	"time"
+
 	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
 )
 
@@ -42,6 +45,11 @@ func (r *InsertsRequest) Append(insert InsertRequest) *InsertsRequest {
 	return r
 }
 
+// GetInserts gets all the inserts in order
+func (r *InsertsRequest) GetInserts() []InsertRequest {
+	return r.inserts
+}
+
 func (r InsertsRequest) build(cfg *Config) (*greptimepb.GreptimeRequest, error) {
 	header, err := r.header.build(cfg)
 	if err != nil {
@@ -54,7 +62,7 @@ func (r InsertsRequest) build(cfg *Config) (*greptimepb.GreptimeRequest, error)
 
 	reqs := make([]*greptimepb.InsertRequest, 0, len(r.inserts))
 	for _, insert := range r.inserts {
-		req, err := insert.build()
+		req, err := insert.build(cfg)
 		if err != nil {
 			return nil, err
 		}
@@ -74,8 +82,18 @@ func (r InsertsRequest) build(cfg *Config) (*greptimepb.GreptimeRequest, error)
 
 // InsertRequest insert metric to specified table. You can also specify the database in header.
 type InsertRequest struct {
-	table  string
-	metric Metric
+	table    string
+	metric   Metric
+	columnar *ColumnarMetric
+}
+
+// insertRows are the rows to insert, which are [Metric] or [ColumnarMetric]
+type insertRows interface {
+	namingPolicy() NamingPolicy
+	precision() time.Duration
+	rowCount() int
+	checkTimestamps(zero ZeroTimestampPolicy, truncation TruncationPolicy) (int, error)
+	intoGreptimeColumn(zero ZeroTimestampPolicy) ([]*greptimepb.Column, error)
 }
 
 func (r *InsertRequest) WithTable(table string) *InsertRequest {
@@ -85,25 +103,80 @@ func (r *InsertRequest) WithTable(table string) *InsertRequest {
 
 func (r *InsertRequest) WithMetric(metric Metric) *InsertRequest {
 	r.metric = metric
+	r.columnar = nil
 	return r
 }
 
-func (r *InsertRequest) RowCount() uint32 {
-	return uint32(len(r.metric.series))
+// WithColumnarMetric helps to insert the columns built via [ColumnarMetric]
+// instead of [Metric]. The ColumnarMetric SHOULD NOT be modified until the
+// insert is done.
+func (r *InsertRequest) WithColumnarMetric(metric *ColumnarMetric) *InsertRequest {
+	r.metric = Metric{}
+	r.columnar = metric
+	return r
 }
 
-func (r *InsertRequest) build() (*greptimepb.InsertRequest, error) {
+// GetTable gets the table to insert
+func (r *InsertRequest) GetTable() string {
+	return r.table
+}
+
+// GetMetric gets the metric to insert, which is empty if the insert is of [ColumnarMetric]
+func (r *InsertRequest) GetMetric() Metric {
+	return r.metric
+}
+
+func (r *InsertRequest) rows() insertRows {
+	if r.columnar != nil {
+		return r.columnar
+	}
+	return &r.metric
+}
+
+// tableName returns the name of table normalized via the naming policy of Metric
+func (r *InsertRequest) tableName() (string, error) {
 	if isEmptyString(r.table) {
-		return nil, ErrEmptyTable
+		return "", ErrEmptyTable
+	}
+	naming := r.rows().namingPolicy()
+	if naming == nil {
+		return r.table, nil
+	}
+
+	table, err := naming.normalize(r.table)
+	if err != nil {
+		return "", fmt.Errorf("invalid name of table %s, %w", r.table, err)
+	}
+	return table, nil
+}
+
+func (r *InsertRequest) RowCount() uint32 {
+	return uint32(r.rows().rowCount())
+}
+
+func (r *InsertRequest) build(cfg *Config) (*greptimepb.InsertRequest, error) {
+	table, err := r.tableName()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := r.rows()
+	truncated, err := rows.checkTimestamps(cfg.ZeroTimestamp, cfg.TimestampTruncation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp of table %s, %w", table, err)
+	}
+	if truncated > 0 && cfg.TimestampTruncation == TruncationWarn {
+		cfg.getLogger().Warn("timestamps are truncated", "table", table, "series", truncated,
+			"precision", rows.precision().String())
 	}
 
-	columns, err := r.metric.intoGreptimeColumn()
+	columns, err := rows.intoGreptimeColumn(cfg.ZeroTimestamp)
 	if err != nil {
 		return nil, err
 	}
 
 	return &greptimepb.InsertRequest{
-		TableName: r.table,
+		TableName: table,
 		Columns:   columns,
 		RowCount:  r.RowCount(),
 	}, nil
@@ -0,0 +1,150 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// operations of the requests, see [Call.Operation]. They are also the operation
+// label of metrics and the db.operation attribute of spans.
+const (
+	OperationInsert       = "insert"
+	OperationStreamInsert = "stream_insert"
+	OperationQuery        = "query"
+	OperationExecute      = "execute"
+	OperationPromql       = "promql"
+)
+
+// Call is the request about to be sent to greptimedb, it is passed through the
+// interceptors, see [Config.WithInterceptors].
+//
+// Request is *greptimepb.GreptimeRequest for all the operations but [OperationPromql],
+// whose Request is *greptimepb.PromqlRequest. The interceptors can modify the
+// Request, or replace it with another one of the same type.
+type Call struct {
+	Operation string
+	Request   proto.Message
+}
+
+// Invoker sends the request of the call to greptimedb.
+//
+// The response is *greptimepb.GreptimeResponse for [OperationInsert] and [OperationExecute],
+// *greptimepb.PromqlResponse for [OperationPromql], and nil for [OperationQuery] and
+// [OperationStreamInsert], since the records and the affected rows are received later.
+type Invoker func(ctx context.Context, call *Call) (proto.Message, error)
+
+// Interceptor intercepts the call, it can inspect or modify the request before
+// calling invoker, and inspect the response and the error after that. It can also
+// reject the call by returning an error without calling invoker.
+type Interceptor func(ctx context.Context, call *Call, invoker Invoker) (proto.Message, error)
+
+// chainInterceptors wraps invoker with the interceptors, the first one is the outermost
+func chainInterceptors(interceptors []Interceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, call *Call) (proto.Message, error) {
+			return interceptor(ctx, call, next)
+		}
+	}
+	return invoker
+}
+
+// observer records the metrics, spans and logs of the calls. It is the innermost
+// interceptor, so that the requests modified by the interceptors are observed.
+type observer struct {
+	cfg     *Config
+	metrics *clientMetrics
+	tracer  *clientTracer
+}
+
+func (c *Client) observer() observer {
+	return observer{cfg: c.cfg, metrics: c.metrics, tracer: c.tracer}
+}
+
+func (c *StreamClient) observer() observer {
+	return observer{cfg: c.cfg, metrics: c.metrics, tracer: c.tracer}
+}
+
+// invoke passes the call through the interceptors and sends it via send
+func (o observer) invoke(ctx context.Context, op string, request proto.Message, send Invoker) (proto.Message, error) {
+	interceptors := append(o.cfg.Interceptors[:len(o.cfg.Interceptors):len(o.cfg.Interceptors)], o.intercept)
+	return chainInterceptors(interceptors, send)(ctx, &Call{Operation: op, Request: request})
+}
+
+func (o observer) intercept(ctx context.Context, call *Call, invoker Invoker) (proto.Message, error) {
+	database, stats := databaseOf(call.Request), statsOf(call.Request)
+	ctx, span := o.tracer.start(ctx, call.Operation, call.Request, database, stats)
+
+	// the latency of query is until the schema is received, since the records are
+	// read lazily, and the rows of stream insert are counted as written once they are sent
+	start := time.Now()
+	resp, err := invoker(ctx, call)
+	header := respHeaderOf(resp)
+	o.metrics.observe(call.Operation, database, stats, start, header.Code, err)
+	o.tracer.end(span, header, err)
+
+	if call.Operation == OperationStreamInsert && err != nil {
+		tables, rows := make([]string, 0, len(stats)), uint32(0)
+		for _, stat := range stats {
+			tables, rows = append(tables, stat.table), rows+stat.rows
+		}
+		o.cfg.getLogger().Error("failed to send, the batch is dropped", "database", database,
+			"tables", tables, "rows", rows, "error", err)
+	} else {
+		logResult(o.cfg.getLogger(), call.Operation, database, start, header, err)
+	}
+	return resp, err
+}
+
+func databaseOf(request proto.Message) string {
+	switch r := request.(type) {
+	case *greptimepb.GreptimeRequest:
+		return r.GetHeader().GetDbname()
+	case *greptimepb.PromqlRequest:
+		return r.GetHeader().GetDbname()
+	default:
+		return ""
+	}
+}
+
+// respHeaderOf parses the header of the response, it is empty if the response is nil
+func respHeaderOf(resp proto.Message) RespHeader {
+	if r, ok := resp.(getRespHeader); ok {
+		return ParseRespHeader(r)
+	}
+	return RespHeader{}
+}
+
+func greptimeRequestOf(call *Call) (*greptimepb.GreptimeRequest, error) {
+	request, ok := call.Request.(*greptimepb.GreptimeRequest)
+	if !ok || request == nil {
+		return nil, fmt.Errorf("unexpected request type %T of operation %q", call.Request, call.Operation)
+	}
+	return request, nil
+}
+
+func promqlRequestOf(call *Call) (*greptimepb.PromqlRequest, error) {
+	request, ok := call.Request.(*greptimepb.PromqlRequest)
+	if !ok || request == nil {
+		return nil, fmt.Errorf("unexpected request type %T of operation %q", call.Request, call.Operation)
+	}
+	return request, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestChainInterceptors(t *testing.T) {
+	var trace []string
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, call *Call, invoker Invoker) (proto.Message, error) {
+			trace = append(trace, name+" before")
+			resp, err := invoker(ctx, call)
+			trace = append(trace, name+" after")
+			return resp, err
+		}
+	}
+
+	invoker := chainInterceptors([]Interceptor{record("first"), record("second")},
+		func(ctx context.Context, call *Call) (proto.Message, error) {
+			trace = append(trace, "invoke")
+			return nil, nil
+		})
+	_, err := invoker(context.Background(), &Call{Operation: OperationInsert})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first before", "second before", "invoke", "second after", "first after"}, trace)
+}
+
+// tenantInterceptor tags every row of inserts with the tenant column
+func tenantInterceptor(tenant string) Interceptor {
+	return func(ctx context.Context, call *Call, invoker Invoker) (proto.Message, error) {
+		if request, ok := call.Request.(*greptimepb.GreptimeRequest); ok {
+			for _, insert := range request.GetInserts().GetInserts() {
+				values := make([]string, insert.RowCount)
+				for i := range values {
+					values[i] = tenant
+				}
+				insert.Columns = append(insert.Columns, &greptimepb.Column{
+					ColumnName:   "tenant",
+					SemanticType: greptimepb.SemanticType_TAG,
+					Datatype:     greptimepb.ColumnDataType_STRING,
+					Values:       &greptimepb.Column_Values{StringValues: values},
+				})
+			}
+		}
+		return invoker(ctx, call)
+	}
+}
+
+func TestInterceptorModifiesInsert(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	var responded *greptimepb.GreptimeResponse
+	audit := func(ctx context.Context, call *Call, invoker Invoker) (proto.Message, error) {
+		resp, err := invoker(ctx, call)
+		responded, _ = resp.(*greptimepb.GreptimeResponse)
+		return resp, err
+	}
+	cfg := NewCfg("localhost").WithDatabase("public").WithMetrics(reg).
+		WithInterceptors(audit, tenantInterceptor("team-a"))
+	metrics, err := cfg.newMetrics()
+	assert.Nil(t, err)
+
+	greptimeClient := &fakeGreptimeClient{header: &greptimepb.ResponseHeader{Status: &greptimepb.Status{}}}
+	client := &Client{cfg: cfg, greptimeClient: greptimeClient, metrics: metrics}
+
+	series := Series{}
+	series.AddTag("host", "localhost")
+	series.AddField("cpu", 0.5)
+	series.SetTimestamp(time.Now())
+	metric := Metric{}
+	metric.AddSeries(series)
+	metric.AddSeries(series)
+	inserts := InsertsRequest{}
+	inserts.Append(*(&InsertRequest{}).WithTable("monitor").WithMetric(metric))
+
+	resp, err := client.Insert(context.Background(), inserts)
+	assert.Nil(t, err)
+	assert.Same(t, responded, resp)
+
+	assert.Len(t, greptimeClient.requests, 1)
+	columns := greptimeClient.requests[0].GetInserts().GetInserts()[0].GetColumns()
+	tenant := columns[len(columns)-1]
+	assert.Equal(t, "tenant", tenant.GetColumnName())
+	assert.Equal(t, []string{"team-a", "team-a"}, tenant.GetValues().GetStringValues())
+
+	// the modified request is observed
+	size := proto.Size(greptimeClient.requests[0].GetInserts().GetInserts()[0])
+	assert.Equal(t, float64(size), testutil.ToFloat64(metrics.sentBytes.WithLabelValues(OperationInsert, "public", "monitor")))
+}
+
+func TestInterceptorRejects(t *testing.T) {
+	errQuota := errors.New("quota exceeded")
+	var ops []string
+	quota := func(ctx context.Context, call *Call, invoker Invoker) (proto.Message, error) {
+		ops = append(ops, call.Operation)
+		return nil, errQuota
+	}
+	cfg := NewCfg("localhost").WithDatabase("public").WithInterceptors(quota)
+	promqlClient := &fakePromqlClient{}
+	client := &Client{cfg: cfg, promqlClient: promqlClient}
+
+	req := NewQueryRequest().WithInstantPromql(NewInstantPromql("up").WithTime(time.Unix(100, 0)))
+	_, err := client.PromqlQuery(context.Background(), *req)
+	assert.ErrorIs(t, err, errQuota)
+	assert.Empty(t, promqlClient.requests)
+
+	_, err = client.Query(context.Background(), *NewQueryRequest().WithSql("SELECT 1"))
+	assert.ErrorIs(t, err, errQuota)
+	assert.Equal(t, []string{OperationPromql, OperationQuery}, ops)
+}
+
+func TestInterceptorReplacesRequestWithWrongType(t *testing.T) {
+	replace := func(ctx context.Context, call *Call, invoker Invoker) (proto.Message, error) {
+		call.Request = &greptimepb.PromqlRequest{}
+		return invoker(ctx, call)
+	}
+	cfg := NewCfg("localhost").WithDatabase("public").WithInterceptors(replace)
+	greptimeClient := &fakeGreptimeClient{}
+	client := &Client{cfg: cfg, greptimeClient: greptimeClient}
+
+	_, err := client.execute(context.Background(), *NewQueryRequest().WithSql("DROP TABLE monitor"))
+	assert.ErrorContains(t, err, "unexpected request type")
+	assert.Empty(t, greptimeClient.requests)
+}
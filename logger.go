@@ -0,0 +1,101 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the structured logger of the client, see [Config.WithLogger].
+// keysAndValues are alternating keys and values, like `"database", "public"`.
+//
+// *slog.Logger implements it since go 1.21, and [NewLogrusLogger] adapts logrus.
+type Logger interface {
+	Debug(msg string, keysAndValues ...any)
+	Info(msg string, keysAndValues ...any)
+	Warn(msg string, keysAndValues ...any)
+	Error(msg string, keysAndValues ...any)
+}
+
+// nopLogger discards all logs, which is the default
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+type logrusLogger struct {
+	logger logrus.FieldLogger
+}
+
+// NewLogrusLogger helps to log via logrus, the standard logger of logrus is used
+// if logger is nil. keysAndValues are converted into fields.
+func NewLogrusLogger(logger logrus.FieldLogger) Logger {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return &logrusLogger{logger: logger}
+}
+
+func (l *logrusLogger) Debug(msg string, keysAndValues ...any) {
+	l.logger.WithFields(logrusFields(keysAndValues)).Debug(msg)
+}
+
+func (l *logrusLogger) Info(msg string, keysAndValues ...any) {
+	l.logger.WithFields(logrusFields(keysAndValues)).Info(msg)
+}
+
+func (l *logrusLogger) Warn(msg string, keysAndValues ...any) {
+	l.logger.WithFields(logrusFields(keysAndValues)).Warn(msg)
+}
+
+func (l *logrusLogger) Error(msg string, keysAndValues ...any) {
+	l.logger.WithFields(logrusFields(keysAndValues)).Error(msg)
+}
+
+// logrusFields converts keysAndValues into fields, the value without key is kept
+// with key `!BADKEY` like slog
+func logrusFields(keysAndValues []any) logrus.Fields {
+	fields := make(logrus.Fields, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 == len(keysAndValues) {
+			fields["!BADKEY"] = keysAndValues[i]
+			break
+		}
+		fields[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+	return fields
+}
+
+// logResult logs the result of the request, the failures are logged as errors
+// except rate limited, and the successes are only logged in debug level
+func logResult(logger Logger, op, database string, start time.Time, header RespHeader, err error) {
+	elapsed := time.Since(start)
+	switch {
+	case err != nil:
+		logger.Error("request failed", "operation", op, "database", database, "elapsed", elapsed, "error", err)
+	case header.IsRateLimited():
+		logger.Warn("request is rate limited", "operation", op, "database", database, "msg", header.Msg)
+	case !header.IsSuccess():
+		logger.Error("greptimedb responded error", "operation", op, "database", database,
+			"code", header.Code, "msg", header.Msg)
+	default:
+		logger.Debug("request finished", "operation", op, "database", database, "elapsed", elapsed)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/sirupsen/logrus"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordLogger records the logs in `level: msg` format
+type recordLogger struct {
+	logs []string
+}
+
+func (l *recordLogger) record(level, msg string, keysAndValues []any) {
+	l.logs = append(l.logs, fmt.Sprintf("%s: %s %v", level, msg, keysAndValues))
+}
+
+func (l *recordLogger) Debug(msg string, keysAndValues ...any) { l.record("debug", msg, keysAndValues) }
+func (l *recordLogger) Info(msg string, keysAndValues ...any)  { l.record("info", msg, keysAndValues) }
+func (l *recordLogger) Warn(msg string, keysAndValues ...any)  { l.record("warn", msg, keysAndValues) }
+func (l *recordLogger) Error(msg string, keysAndValues ...any) { l.record("error", msg, keysAndValues) }
+
+func TestLogrusLogger(t *testing.T) {
+	l, hook := logrustest.NewNullLogger()
+	l.SetLevel(logrus.DebugLevel)
+	logger := NewLogrusLogger(l)
+
+	logger.Debug("debug", "database", "public")
+	logger.Info("info")
+	logger.Warn("warn", "code", 6001, "dangling")
+	logger.Error("error", "error", errors.New("failed"))
+
+	entries := hook.AllEntries()
+	assert.Len(t, entries, 4)
+	assert.Equal(t, logrus.DebugLevel, entries[0].Level)
+	assert.Equal(t, logrus.Fields{"database": "public"}, entries[0].Data)
+	assert.Equal(t, logrus.InfoLevel, entries[1].Level)
+	assert.Equal(t, logrus.WarnLevel, entries[2].Level)
+	assert.Equal(t, logrus.Fields{"code": 6001, "!BADKEY": "dangling"}, entries[2].Data)
+	assert.Equal(t, logrus.ErrorLevel, entries[3].Level)
+	assert.Equal(t, "error", entries[3].Message)
+
+	assert.NotNil(t, NewLogrusLogger(nil))
+}
+
+func TestLogResult(t *testing.T) {
+	logger := &recordLogger{}
+	start := time.Now()
+
+	logResult(logger, OperationInsert, "public", start, RespHeader{}, nil)
+	logResult(logger, OperationInsert, "public", start, RespHeader{Code: uint32(StatusRateLimited), Msg: "banned"}, nil)
+	logResult(logger, OperationInsert, "public", start, RespHeader{Code: 4001, Msg: "table not found"}, nil)
+	logResult(logger, OperationInsert, "public", start, RespHeader{}, errors.New("connection refused"))
+
+	assert.Len(t, logger.logs, 4)
+	assert.Contains(t, logger.logs[0], "debug: request finished")
+	assert.Contains(t, logger.logs[1], "warn: request is rate limited")
+	assert.Contains(t, logger.logs[2], "error: greptimedb responded error")
+	assert.Contains(t, logger.logs[2], "table not found")
+	assert.Contains(t, logger.logs[3], "error: request failed")
+}
+
+func TestClientLogger(t *testing.T) {
+	logger := &recordLogger{}
+	cfg := NewCfg("localhost").WithDatabase("public").WithLogger(logger)
+	promqlClient := &fakePromqlClient{
+		header: &greptimepb.ResponseHeader{Status: &greptimepb.Status{StatusCode: uint32(StatusRateLimited), ErrMsg: "banned"}},
+	}
+	client := &Client{cfg: cfg, promqlClient: promqlClient}
+
+	_, err := client.PromqlQuery(context.Background(), *NewQueryRequest().WithInstantPromql(NewInstantPromql("up")))
+	assert.Nil(t, err)
+	assert.Len(t, logger.logs, 1)
+	assert.Contains(t, logger.logs[0], "warn: request is rate limited")
+
+	// nothing is logged by default
+	assert.Equal(t, nopLogger{}, NewCfg("localhost").getLogger())
+}
@@ -33,7 +33,8 @@ func (n *mask) set(idx uint) *mask {
 }
 
 // shrink is to help to generate the bytes number the caller is interested
-// via LittleEndian
+// via LittleEndian. The bytes are zero-padded to bSize, since the bitset only
+// holds the words up to the last bit set.
 func (n *mask) shrink(bSize int) ([]byte, error) {
 	if n.bs.Len() == 0 {
 		return nil, nil
@@ -44,5 +45,12 @@ func (n *mask) shrink(bSize int) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return buf.Bytes()[:bSize], nil
+	b := make([]byte, bSize)
+	copy(b, buf.Bytes())
+	return b, nil
+}
+
+// nullMaskByteSize helps to calculate how many bytes needed for n rows in Mask.shrink
+func nullMaskByteSize(n int) int {
+	return (n + 7) / 8
 }
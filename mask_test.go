@@ -32,3 +32,16 @@ func TestMask(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, []byte{1, 4}, b)
 }
+
+func TestMaskShrinkPadding(t *testing.T) {
+	mask := mask{}
+	mask.set(1)
+
+	b, err := mask.shrink(nullMaskByteSize(600))
+	assert.Nil(t, err)
+	assert.Len(t, b, 75)
+	assert.Equal(t, byte(2), b[0])
+	for _, v := range b[1:] {
+		assert.Zero(t, v)
+	}
+}
@@ -17,7 +17,6 @@ package greptime
 import (
 	"errors"
 	"fmt"
-	"math"
 	"time"
 
 	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
@@ -29,6 +28,7 @@ import (
 // Metric represents multiple rows of data, and also Metric can specify
 // the timestamp column name and precision
 type Metric struct {
+	naming             NamingPolicy
 	timestampAlias     string
 	timestampPrecision time.Duration
 	// orders and columns SHOULD NOT contain timestampAlias key
@@ -50,8 +50,18 @@ func (m *Metric) GetSeries() []Series {
 	return m.series
 }
 
+func (m *Metric) namingPolicy() NamingPolicy {
+	return m.naming
+}
+
+func (m *Metric) rowCount() int {
+	return len(m.series)
+}
+
+// buildMetricFromReader builds the Metric from the result of sql, the names of
+// columns are kept as they are in the result, see [NamingPreserve]
 func buildMetricFromReader(r *flight.Reader) (*Metric, error) {
-	metric := Metric{}
+	metric := Metric{naming: NamingPreserve}
 
 	if r == nil {
 		return nil, errors.New("Internal Error, empty reader pointer")
@@ -61,7 +71,7 @@ func buildMetricFromReader(r *flight.Reader) (*Metric, error) {
 	for r.Next() {
 		record := r.Record()
 		for i := 0; i < int(record.NumRows()); i++ {
-			series := Series{}
+			series := Series{naming: NamingPreserve}
 			for j := 0; j < int(record.NumCols()); j++ {
 				column := record.Column(j)
 				colVal, err := fromColumn(column, i)
@@ -79,6 +89,77 @@ func buildMetricFromReader(r *flight.Reader) (*Metric, error) {
 	return &metric, nil
 }
 
+// buildPromqlMetricFromReader is like buildMetricFromReader, but it restores the
+// semantics of the columns in the result of promql:
+//
+//   - timestamp column is the evaluation time, which is set as timestamp of Series
+//   - string columns are labels, which are set as tags
+//   - other columns are values, which are set as fields
+func buildPromqlMetricFromReader(r *flight.Reader) (*Metric, error) {
+	metric := Metric{naming: NamingPreserve}
+
+	if r == nil {
+		return nil, errors.New("Internal Error, empty reader pointer")
+	}
+
+	fields := r.Schema().Fields()
+	tsIdx := -1
+	for i := range fields {
+		if fields[i].Type.ID() != arrow.TIMESTAMP {
+			continue
+		}
+		precision, err := extractPrecision(&fields[i])
+		if err != nil {
+			return nil, err
+		}
+		if err := metric.SetTimePrecision(precision); err != nil {
+			return nil, err
+		}
+		if err := metric.SetTimestampAlias(fields[i].Name); err != nil {
+			return nil, err
+		}
+		tsIdx = i
+		break
+	}
+
+	for r.Next() {
+		record := r.Record()
+		for i := 0; i < int(record.NumRows()); i++ {
+			series := Series{naming: NamingPreserve}
+			for j := 0; j < int(record.NumCols()); j++ {
+				colVal, err := fromColumn(record.Column(j), i)
+				if err != nil {
+					return nil, err
+				}
+				if colVal == nil {
+					continue
+				}
+
+				switch {
+				case j == tsIdx:
+					err = series.SetTimestamp(colVal.(time.Time))
+				case fields[j].Type.ID() == arrow.STRING:
+					err = series.AddTag(fields[j].Name, colVal)
+				default:
+					err = series.AddField(fields[j].Name, colVal)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			if err := metric.AddSeries(series); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	return &metric, nil
+}
+
 func extractPrecision(field *arrow.Field) (time.Duration, error) {
 	if field == nil {
 		return 0, errors.New("field should not be empty")
@@ -187,9 +268,27 @@ func (m *Metric) SetTimePrecision(precision time.Duration) error {
 	return nil
 }
 
+// SetNamingPolicy helps to specify how the names of the table, the timestamp
+// column and the columns of [Series] are normalized, like [NamingPreserve] to
+// keep HTTPStatus as it is. It MUST be set before [Metric.SetTimestampAlias] and
+// [Metric.AddSeries].
+//
+// # Pay attention
+//
+//   - the Series with its own policy, see [Series.SetNamingPolicy], is kept as it is
+//   - the name of table is kept as it is if the policy is not set, and it is
+//     normalized via the policy otherwise
+func (m *Metric) SetNamingPolicy(naming NamingPolicy) error {
+	if len(m.timestampAlias) > 0 || len(m.series) > 0 {
+		return ErrNamingPolicyAfterColumns
+	}
+	m.naming = naming
+	return nil
+}
+
 // SetTimestampAlias helps to specify the timestamp column name, default is ts.
 func (m *Metric) SetTimestampAlias(alias string) error {
-	alias, err := toColumnName(alias)
+	alias, err := m.naming.normalize(alias)
 	if err != nil {
 		return err
 	}
@@ -226,6 +325,18 @@ func (m *Metric) AddSeries(s Series) error {
 		m.series = []Series{}
 	}
 
+	if errs := s.check(); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if m.naming != nil && s.naming == nil {
+		renamed, err := s.rename(m.naming)
+		if err != nil {
+			return err
+		}
+		s = renamed
+	}
+
 	for _, key := range s.orders {
 		sCol := s.columns[key]
 		if mCol, seen := m.columns[key]; seen {
@@ -242,7 +353,42 @@ func (m *Metric) AddSeries(s Series) error {
 	return nil
 }
 
-func (m *Metric) intoGreptimeColumn() ([]*greptimepb.Column, error) {
+// Validate helps to report all the problems of the Metric at once, instead of
+// the first one when inserting:
+//
+//   - no series in the Metric
+//   - a tag or field named as the timestamp column
+//   - the same column of different types or semantics in different series
+//   - the series modified after being added, like a value not matching its type,
+//     since the maps of [Series] are shared between the copies, see [Series.Clone]
+func (m *Metric) Validate() error {
+	var errs []error
+	if len(m.series) == 0 {
+		errs = append(errs, ErrNoSeriesInMetric)
+	}
+	if _, exist := m.columns[m.GetTimestampAlias()]; exist {
+		errs = append(errs, fmt.Errorf("column %q conflicts with the timestamp column", m.GetTimestampAlias()))
+	}
+
+	for i, s := range m.series {
+		for _, err := range s.check() {
+			errs = append(errs, fmt.Errorf("series %d: %w", i, err))
+		}
+		for _, key := range s.orders {
+			col, exist := m.columns[key]
+			if !exist {
+				errs = append(errs, fmt.Errorf("series %d: column %q is not in Metric", i, key))
+				continue
+			}
+			if err := checkColumnEquality(key, col, s.columns[key]); err != nil {
+				errs = append(errs, fmt.Errorf("series %d: %w", i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Metric) intoGreptimeColumn(zero ZeroTimestampPolicy) ([]*greptimepb.Column, error) {
 	if len(m.series) == 0 {
 		return nil, ErrNoSeriesInMetric
 	}
@@ -252,7 +398,7 @@ func (m *Metric) intoGreptimeColumn() ([]*greptimepb.Column, error) {
 		return nil, err
 	}
 
-	tsColumn, err := m.intoTimestampColumn()
+	tsColumn, err := m.intoTimestampColumn(zero)
 	if err != nil {
 		return nil, err
 	}
@@ -262,7 +408,7 @@ func (m *Metric) intoGreptimeColumn() ([]*greptimepb.Column, error) {
 
 // nullMaskByteSize helps to calculate how many bytes needed in Mask.shrink
 func (m *Metric) nullMaskByteSize() int {
-	return int(math.Ceil(float64(len(m.series)) / 8.0))
+	return nullMaskByteSize(len(m.series))
 }
 
 // intoDataColumns does not contain timestamp semantic column
@@ -311,39 +457,11 @@ func (m *Metric) intoDataColumns() ([]*greptimepb.Column, error) {
 	return result, nil
 }
 
-func (m *Metric) intoTimestampColumn() (*greptimepb.Column, error) {
-	datatype, err := precisionToDataType(m.timestampPrecision)
-	if err != nil {
-		return nil, err
-	}
-	tsColumn := &greptimepb.Column{
-		ColumnName:   m.GetTimestampAlias(),
-		SemanticType: greptimepb.SemanticType_TIMESTAMP,
-		Datatype:     datatype,
-		Values:       &greptimepb.Column_Values{},
-		NullMask:     nil,
-	}
-	nullMask := mask{}
-	for _, s := range m.series {
-		switch datatype {
-		case greptimepb.ColumnDataType_TIMESTAMP_SECOND:
-			setColumn(tsColumn, s.timestamp.Unix())
-		case greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND:
-			setColumn(tsColumn, s.timestamp.UnixMicro())
-		case greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:
-			setColumn(tsColumn, s.timestamp.UnixNano())
-		default: // greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND
-			setColumn(tsColumn, s.timestamp.UnixMilli())
-		}
-	}
-
-	if b, err := nullMask.shrink(m.nullMaskByteSize()); err != nil {
-		return nil, err
-	} else {
-		tsColumn.NullMask = b
-	}
-
-	return tsColumn, nil
+// intoTimestampColumn builds the timestamp column, the zero timestamps are NULL
+// if the policy is ZeroTimestampServerNow
+func (m *Metric) intoTimestampColumn(zero ZeroTimestampPolicy) (*greptimepb.Column, error) {
+	return intoTimestampColumn(m.GetTimestampAlias(), m.timestampPrecision, len(m.series),
+		func(i int) time.Time { return m.series[i].timestamp }, zero)
 }
 
 func setColumn(col *greptimepb.Column, val any) error {
@@ -15,13 +15,74 @@
 package greptime
 
 import (
+	"bytes"
+	"io"
 	"testing"
 	"time"
 
 	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/apache/arrow/go/v13/arrow"
+	"github.com/apache/arrow/go/v13/arrow/array"
+	"github.com/apache/arrow/go/v13/arrow/flight"
+	"github.com/apache/arrow/go/v13/arrow/ipc"
+	"github.com/apache/arrow/go/v13/arrow/memory"
 	"github.com/stretchr/testify/assert"
 )
 
+// flightStream is an in-memory flight data stream
+type flightStream struct {
+	data []*flight.FlightData
+}
+
+// Send copies the data, since the writer reuses the buffers
+func (s *flightStream) Send(data *flight.FlightData) error {
+	s.data = append(s.data, &flight.FlightData{
+		DataHeader: bytes.Clone(data.DataHeader),
+		DataBody:   bytes.Clone(data.DataBody),
+	})
+	return nil
+}
+
+func (s *flightStream) Recv() (*flight.FlightData, error) {
+	if len(s.data) == 0 {
+		return nil, io.EOF
+	}
+	data := s.data[0]
+	s.data = s.data[1:]
+	return data, nil
+}
+
+func newFlightReader(t *testing.T, records ...arrow.Record) *flight.Reader {
+	stream := &flightStream{}
+	writer := flight.NewRecordWriter(stream, ipc.WithSchema(records[0].Schema()))
+	for _, record := range records {
+		assert.Nil(t, writer.Write(record))
+	}
+	assert.Nil(t, writer.Close())
+
+	reader, err := flight.NewRecordReader(stream)
+	assert.Nil(t, err)
+	return reader
+}
+
+// newPromqlRecord builds the record like the result of promql: host label, value and ts
+func newPromqlRecord(hosts []string, vals []float64, ts []int64) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "host", Type: arrow.BinaryTypes.String},
+		{Name: "val", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "greptime_timestamp", Type: &arrow.TimestampType{Unit: arrow.Millisecond}},
+	}, nil)
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.StringBuilder).AppendValues(hosts, nil)
+	builder.Field(1).(*array.Float64Builder).AppendValues(vals, nil)
+	for _, t := range ts {
+		builder.Field(2).(*array.TimestampBuilder).Append(arrow.Timestamp(t))
+	}
+	return builder.NewRecord()
+}
+
 func TestMetric(t *testing.T) {
 	s := Series{}
 	s.AddTag("tag1", "tag val")
@@ -103,7 +164,7 @@ func TestGreptimeColumn(t *testing.T) {
 	assert.Nil(t, m.AddSeries(s1))
 	assert.Nil(t, m.AddSeries(s2))
 
-	cols, err := m.intoGreptimeColumn()
+	cols, err := m.intoGreptimeColumn(ZeroTimestampAsIs)
 	assert.Nil(t, err)
 	assert.Equal(t, 9, len(cols))
 
@@ -322,3 +383,60 @@ func TestGetTags(t *testing.T) {
 	m.AddSeries(s)
 	assert.Equal(t, []string{"t1", "t2", "f1"}, m.GetTagsAndFields())
 }
+
+func TestBuildPromqlMetricFromReader(t *testing.T) {
+	reader := newFlightReader(t,
+		newPromqlRecord([]string{"127.0.0.1", "127.0.0.2"}, []float64{0.1, 0.2}, []int64{1677728740000, 1677728740000}),
+		newPromqlRecord([]string{"127.0.0.1"}, []float64{0.3}, []int64{1677728741000}),
+	)
+	defer reader.Release()
+
+	metric, err := buildPromqlMetricFromReader(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "greptime_timestamp", metric.GetTimestampAlias())
+	assert.Equal(t, time.Millisecond, metric.timestampPrecision)
+	assert.Equal(t, []string{"host", "val"}, metric.GetTagsAndFields())
+	assert.Equal(t, greptimepb.SemanticType_TAG, metric.columns["host"].semantic)
+	assert.Equal(t, greptimepb.SemanticType_FIELD, metric.columns["val"].semantic)
+
+	series := metric.GetSeries()
+	assert.Len(t, series, 3)
+
+	host, ok := series[1].GetString("host")
+	assert.True(t, ok)
+	assert.Equal(t, "127.0.0.2", host)
+	val, ok := series[2].GetFloat("val")
+	assert.True(t, ok)
+	assert.Equal(t, 0.3, val)
+	assert.Equal(t, time.UnixMilli(1677728741000), series[2].timestamp)
+}
+
+func TestMetricValidate(t *testing.T) {
+	metric := Metric{}
+	assert.ErrorIs(t, metric.Validate(), ErrNoSeriesInMetric)
+
+	s1 := Series{}
+	assert.Nil(t, s1.AddTag("host", "a"))
+	assert.Nil(t, s1.AddField("ts", int64(1)))
+	assert.Nil(t, metric.AddSeries(s1))
+
+	s2 := Series{}
+	assert.Nil(t, s2.AddTag("host", "b"))
+	assert.Nil(t, s2.AddField("cpu", 0.5))
+	assert.Nil(t, metric.AddSeries(s2))
+
+	// modified after being added, since the maps are shared
+	s1.vals["host"] = 1
+	s2.columns["cpu"] = column{typ: greptimepb.ColumnDataType_INT64, semantic: greptimepb.SemanticType_FIELD}
+	s2.vals["cpu"] = int64(1)
+
+	err := metric.Validate()
+	assert.ErrorContains(t, err, `column "ts" conflicts with the timestamp column`)
+	assert.ErrorContains(t, err, `series 0: value of column "host" is int, but STRING is expected`)
+	assert.ErrorContains(t, err, `series 1: the type of 'cpu' does not match: 'FLOAT64' and 'INT64'`)
+	assert.NotErrorIs(t, err, ErrNoSeriesInMetric)
+
+	// the inconsistent series is rejected
+	s2.orders = append(s2.orders, "host")
+	assert.ErrorContains(t, (&Metric{}).AddSeries(s2), `column "host" is duplicated`)
+}
@@ -0,0 +1,192 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const metricsNamespace = "greptimedb_client"
+
+// clientMetrics are the Prometheus collectors of the client, see [Config.WithMetrics].
+// The methods are no-op if it is nil.
+type clientMetrics struct {
+	requests      *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	rateLimited   *prometheus.CounterVec
+	tableRequests *prometheus.CounterVec
+	rowsWritten   *prometheus.CounterVec
+	sentBytes     *prometheus.CounterVec
+}
+
+// newClientMetrics creates the collectors and registers them into reg. The collectors
+// registered by other clients are reused, so that multiple clients can share reg.
+func newClientMetrics(reg prometheus.Registerer) (*clientMetrics, error) {
+	labels := []string{"operation", "database"}
+	withStatus := []string{"operation", "database", "status"}
+	withTable := []string{"operation", "database", "table"}
+
+	m := &clientMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "requests_total",
+			Help:      "Total number of requests sent to greptimedb.",
+		}, withStatus),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests sent to greptimedb.",
+			Buckets:   prometheus.DefBuckets,
+		}, withStatus),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "rate_limited_total",
+			Help:      "Total number of requests rejected by the rate limit of greptimedb.",
+		}, labels),
+		tableRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "table_requests_total",
+			Help:      "Total number of tables in the insert requests sent to greptimedb.",
+		}, append(withTable[:len(withTable):len(withTable)], "status")),
+		rowsWritten: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "rows_written_total",
+			Help:      "Total number of rows written into greptimedb.",
+		}, withTable),
+		sentBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "sent_bytes_total",
+			Help:      "Total size of requests sent to greptimedb in bytes.",
+		}, withTable),
+	}
+
+	var err error
+	if m.requests, err = register(reg, m.requests); err != nil {
+		return nil, err
+	}
+	if m.duration, err = register(reg, m.duration); err != nil {
+		return nil, err
+	}
+	if m.rateLimited, err = register(reg, m.rateLimited); err != nil {
+		return nil, err
+	}
+	if m.tableRequests, err = register(reg, m.tableRequests); err != nil {
+		return nil, err
+	}
+	if m.rowsWritten, err = register(reg, m.rowsWritten); err != nil {
+		return nil, err
+	}
+	if m.sentBytes, err = register(reg, m.sentBytes); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// newMetrics creates the metrics if MetricsRegisterer is specified
+func (c *Config) newMetrics() (*clientMetrics, error) {
+	if c.MetricsRegisterer == nil {
+		return nil, nil
+	}
+	return newClientMetrics(c.MetricsRegisterer)
+}
+
+// register returns the registered collector if c has been registered
+func register[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	err := reg.Register(c)
+	if err == nil {
+		return c, nil
+	}
+
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing, nil
+		}
+	}
+	return c, err
+}
+
+// requestStat is the statistics of a table in the request, table is empty if the
+// request is not for a specific table, like queries.
+type requestStat struct {
+	table string
+	rows  uint32
+	bytes int
+}
+
+// statsOf returns the statistics of each table if the request is to insert
+func statsOf(request proto.Message) []requestStat {
+	r, _ := request.(*greptimepb.GreptimeRequest)
+	inserts := r.GetInserts().GetInserts()
+	if len(inserts) == 0 {
+		return []requestStat{{bytes: proto.Size(request)}}
+	}
+
+	stats := make([]requestStat, 0, len(inserts))
+	for _, insert := range inserts {
+		stats = append(stats, requestStat{
+			table: insert.GetTableName(),
+			rows:  insert.GetRowCount(),
+			bytes: proto.Size(insert),
+		})
+	}
+	return stats
+}
+
+// statusLabel is the status code responded by greptimedb, or the gRPC code if the
+// request failed without the status code
+func statusLabel(code uint32, err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return strconv.FormatUint(uint64(e.Code), 10)
+	}
+	if err != nil {
+		return status.Code(err).String()
+	}
+	return strconv.FormatUint(uint64(code), 10)
+}
+
+// observe records the request after the response is received. The request is
+// counted and timed once, and the tables of an insert request are counted for
+// each table.
+func (m *clientMetrics) observe(op, database string, stats []requestStat, start time.Time, code uint32, err error) {
+	if m == nil {
+		return
+	}
+
+	statusCode := statusLabel(code, err)
+	m.requests.WithLabelValues(op, database, statusCode).Inc()
+	m.duration.WithLabelValues(op, database, statusCode).Observe(time.Since(start).Seconds())
+	if err == nil && StatusCode(code) == StatusRateLimited {
+		m.rateLimited.WithLabelValues(op, database).Inc()
+	}
+
+	for _, stat := range stats {
+		m.sentBytes.WithLabelValues(op, database, stat.table).Add(float64(stat.bytes))
+		if len(stat.table) > 0 {
+			m.tableRequests.WithLabelValues(op, database, stat.table, statusCode).Inc()
+		}
+		if err == nil && code == 0 && stat.rows > 0 {
+			m.rowsWritten.WithLabelValues(op, database, stat.table).Add(float64(stat.rows))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientMetricsObserve(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := newClientMetrics(reg)
+	assert.Nil(t, err)
+
+	// metrics are shared by clients registered into the same registry
+	other, err := newClientMetrics(reg)
+	assert.Nil(t, err)
+	assert.Same(t, m.requests, other.requests)
+
+	request := &greptimepb.GreptimeRequest{
+		Header: &greptimepb.RequestHeader{Dbname: "public"},
+		Request: &greptimepb.GreptimeRequest_Inserts{Inserts: &greptimepb.InsertRequests{
+			Inserts: []*greptimepb.InsertRequest{
+				{TableName: "monitor", RowCount: 2},
+				{TableName: "cpu", RowCount: 3},
+			},
+		}},
+	}
+	stats := statsOf(request)
+	assert.Len(t, stats, 2)
+
+	m.observe(OperationInsert, "public", stats, time.Now(), 0, nil)
+	other.observe(OperationInsert, "public", stats, time.Now(), uint32(StatusRateLimited), nil)
+	m.observe(OperationInsert, "public", stats, time.Now(), 0, status.Error(codes.Unavailable, "connection refused"))
+
+	// the request is counted and timed once, the tables are counted for each table
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues(OperationInsert, "public", "0")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues(OperationInsert, "public", "6001")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues(OperationInsert, "public", "Unavailable")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.requests))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.duration))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.rateLimited.WithLabelValues(OperationInsert, "public")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.tableRequests.WithLabelValues(OperationInsert, "public", "monitor", "0")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.tableRequests.WithLabelValues(OperationInsert, "public", "cpu", "6001")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.tableRequests.WithLabelValues(OperationInsert, "public", "cpu", "Unavailable")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.rowsWritten.WithLabelValues(OperationInsert, "public", "monitor")))
+	assert.Equal(t, float64(3), testutil.ToFloat64(m.rowsWritten.WithLabelValues(OperationInsert, "public", "cpu")))
+	assert.Equal(t, 3*float64(stats[0].bytes), testutil.ToFloat64(m.sentBytes.WithLabelValues(OperationInsert, "public", "monitor")))
+
+	// the typed error is labeled by its status code
+	m.observe(OperationQuery, "public", nil, time.Now(), 0, &Error{Code: StatusTableNotFound})
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues(OperationQuery, "public", "4001")))
+
+	// no-op if metrics is not enabled
+	var disabled *clientMetrics
+	disabled.observe(OperationInsert, "public", stats, time.Now(), 0, nil)
+}
+
+func TestClientMetricsPromql(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	cfg := NewCfg("localhost").WithDatabase("public").WithMetrics(reg)
+	metrics, err := cfg.newMetrics()
+	assert.Nil(t, err)
+	client := &Client{cfg: cfg, promqlClient: &fakePromqlClient{}, metrics: metrics}
+
+	req := NewQueryRequest().WithInstantPromql(NewInstantPromql("up"))
+	_, err = client.PromqlQuery(context.Background(), *req)
+	assert.Nil(t, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.requests.WithLabelValues(OperationPromql, "public", "0")))
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.tableRequests))
+	assert.Greater(t, testutil.ToFloat64(metrics.sentBytes.WithLabelValues(OperationPromql, "public", "")), float64(0))
+
+	metrics, err = NewCfg("localhost").newMetrics()
+	assert.Nil(t, err)
+	assert.Nil(t, metrics)
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/stoewer/go-strcase"
+)
+
+// maxNameLength is the limit of the length of table and column names
+const maxNameLength = 100
+
+// NamingPolicy helps to normalize the names of tables and columns before they
+// are sent to greptimedb, the name is trimmed before being normalized. Besides
+// [NamingSnakeCase], [NamingLowerCase] and [NamingPreserve], a custom func can
+// be used:
+//
+//	metric.SetNamingPolicy(func(name string) string {
+//		return "app_" + greptime.NamingSnakeCase(name)
+//	})
+//
+// The normalized names are validated against the identifier rules of greptimedb:
+// not empty, shorter than 100 and without control characters. The names which
+// are not lowercase identifiers, like HTTPStatus, must be quoted in SQL, like
+// "HTTPStatus", which [Ident] does.
+//
+// See [Series.SetNamingPolicy] and [Metric.SetNamingPolicy].
+type NamingPolicy func(name string) string
+
+// NamingSnakeCase helps to convert the name into lowercase snake_case, like
+// HTTPStatus into http_status, which is the default policy
+func NamingSnakeCase(name string) string {
+	return strings.ToLower(strcase.SnakeCase(name))
+}
+
+// NamingLowerCase helps to convert the name into lowercase, like HTTPStatus into
+// httpstatus, which is how greptimedb treats the unquoted identifiers in SQL
+func NamingLowerCase(name string) string {
+	return strings.ToLower(name)
+}
+
+// NamingPreserve helps to keep the name as it is, so that the names round-trip
+// between inserting and querying
+func NamingPreserve(name string) string {
+	return name
+}
+
+// normalize trims, normalizes and validates the name, snake_case is used if the
+// policy is nil
+func (p NamingPolicy) normalize(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return "", ErrEmptyKey
+	}
+
+	if p == nil {
+		p = NamingSnakeCase
+	}
+	normalized := p(name)
+	if err := validateName(normalized); err != nil {
+		return "", err
+	}
+	return normalized, nil
+}
+
+// validateName validates the name against the identifier rules of greptimedb
+func validateName(name string) error {
+	if isEmptyString(name) {
+		return ErrEmptyKey
+	}
+	if len(name) >= maxNameLength {
+		return fmt.Errorf("the length of column name CAN NOT be longer than %d. %s", maxNameLength, name)
+	}
+	if !utf8.ValidString(name) || strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return fmt.Errorf("%w: %q", ErrInvalidIdentifier, name)
+	}
+	return nil
+}
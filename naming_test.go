@@ -0,0 +1,143 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamingPolicies(t *testing.T) {
+	cases := []struct {
+		naming   NamingPolicy
+		expected string
+	}{
+		{nil, "http_status"},
+		{NamingSnakeCase, "http_status"},
+		{NamingLowerCase, "httpstatus"},
+		{NamingPreserve, "HTTPStatus"},
+		{func(name string) string { return "app_" + NamingSnakeCase(name) }, "app_http_status"},
+	}
+	for _, c := range cases {
+		name, err := c.naming.normalize(" HTTPStatus ")
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, name)
+	}
+
+	_, err := NamingPolicy(NamingPreserve).normalize("  ")
+	assert.ErrorIs(t, err, ErrEmptyKey)
+	_, err = NamingPolicy(func(string) string { return "" }).normalize("host")
+	assert.ErrorIs(t, err, ErrEmptyKey)
+	_, err = NamingPolicy(NamingPreserve).normalize("host\x00name")
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+	_, err = NamingPolicy(NamingPreserve).normalize(strings.Repeat("h", maxNameLength))
+	assert.ErrorContains(t, err, "CAN NOT be longer than 100")
+}
+
+func TestSeriesNamingPolicy(t *testing.T) {
+	s := Series{}
+	assert.Nil(t, s.SetNamingPolicy(NamingPreserve))
+	assert.Nil(t, s.AddTag("HostName", "127.0.0.1"))
+	assert.Nil(t, s.AddField(" HTTPStatus", uint16(200)))
+	assert.Equal(t, []string{"HostName", "HTTPStatus"}, s.GetTagsAndFields())
+	status, ok := s.GetUint("HTTPStatus")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(200), status)
+
+	assert.ErrorIs(t, s.SetNamingPolicy(NamingLowerCase), ErrNamingPolicyAfterColumns)
+}
+
+func TestMetricNamingPolicy(t *testing.T) {
+	metric := Metric{}
+	assert.Nil(t, metric.SetNamingPolicy(NamingPreserve))
+	assert.Nil(t, metric.SetTimestampAlias("CreatedAt"))
+
+	// normalized via the policy of Metric
+	s1 := Series{}
+	assert.Nil(t, s1.AddTag("HostName", "127.0.0.1"))
+	assert.Nil(t, s1.AddField("HTTPStatus", uint16(200)))
+	assert.Nil(t, s1.SetTimestamp(time.UnixMilli(1)))
+	assert.Equal(t, []string{"host_name", "http_status"}, s1.GetTagsAndFields())
+	assert.Nil(t, metric.AddSeries(s1))
+
+	// kept as it is with its own policy
+	s2 := Series{}
+	assert.Nil(t, s2.SetNamingPolicy(NamingLowerCase))
+	assert.Nil(t, s2.AddField("HTTPStatus", uint16(404)))
+	assert.Nil(t, s2.SetTimestamp(time.UnixMilli(2)))
+	assert.Nil(t, metric.AddSeries(s2))
+
+	assert.Equal(t, []string{"HostName", "HTTPStatus", "httpstatus"}, metric.GetTagsAndFields())
+	host, _ := metric.GetSeries()[0].GetString("HostName")
+	assert.Equal(t, "127.0.0.1", host)
+
+	req, err := (&InsertRequest{}).WithTable("AppMonitor").WithMetric(metric).build(&Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, "AppMonitor", req.TableName)
+	names := []string{}
+	for _, col := range req.Columns {
+		names = append(names, col.ColumnName)
+	}
+	assert.Equal(t, []string{"HostName", "HTTPStatus", "httpstatus", "CreatedAt"}, names)
+
+	assert.ErrorIs(t, metric.SetNamingPolicy(NamingSnakeCase), ErrNamingPolicyAfterColumns)
+}
+
+func TestMetricNamingPolicyCollision(t *testing.T) {
+	metric := Metric{}
+	assert.Nil(t, metric.SetNamingPolicy(NamingLowerCase))
+
+	// HTTPStatus and httpstatus are both httpstatus in lowercase
+	s := Series{}
+	assert.Nil(t, s.AddField("HTTPStatus", uint16(200)))
+	assert.Nil(t, s.AddField("httpstatus", uint16(404)))
+	assert.Nil(t, s.SetTimestamp(time.UnixMilli(1)))
+	assert.NotNil(t, metric.AddSeries(s))
+	assert.Empty(t, metric.GetSeries())
+}
+
+func TestTableNamingPolicy(t *testing.T) {
+	s := Series{}
+	assert.Nil(t, s.AddField("cpu", 0.5))
+	assert.Nil(t, s.SetTimestamp(time.UnixMilli(1)))
+
+	// kept as it is by default
+	metric := Metric{}
+	assert.Nil(t, metric.AddSeries(s))
+	table, err := (&InsertRequest{}).WithTable("AppMonitor").WithMetric(metric).tableName()
+	assert.Nil(t, err)
+	assert.Equal(t, "AppMonitor", table)
+
+	metric = Metric{}
+	assert.Nil(t, metric.SetNamingPolicy(NamingSnakeCase))
+	assert.Nil(t, metric.AddSeries(s))
+	table, err = (&InsertRequest{}).WithTable(" AppMonitor").WithMetric(metric).tableName()
+	assert.Nil(t, err)
+	assert.Equal(t, "app_monitor", table)
+
+	_, err = (&InsertRequest{}).WithMetric(metric).tableName()
+	assert.ErrorIs(t, err, ErrEmptyTable)
+
+	metric = Metric{}
+	assert.Nil(t, metric.SetNamingPolicy(NamingPreserve))
+	assert.Nil(t, metric.AddSeries(s))
+	_, err = (&InsertRequest{}).WithTable("app\nmonitor").WithMetric(metric).tableName()
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+	_, err = (&InsertRequest{}).WithMetric(metric).tableName()
+	assert.ErrorIs(t, err, ErrEmptyTable)
+}
@@ -16,35 +16,139 @@ package prom
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/prometheus/common/model"
 )
 
+// ErrorType is the errorType in response of Prometheus HTTP API
+type ErrorType string
+
+const (
+	ErrBadData     ErrorType = "bad_data"
+	ErrExec        ErrorType = "execution"
+	ErrTimeout     ErrorType = "timeout"
+	ErrCanceled    ErrorType = "canceled"
+	ErrInternal    ErrorType = "internal"
+	ErrUnavailable ErrorType = "unavailable"
+	ErrNotFound    ErrorType = "not_found"
+	ErrRateLimited ErrorType = "RateLimited"
+)
+
+// Error is the error returned by Prometheus HTTP API, you can retrieve it via:
+//
+//	var e *prom.Error
+//	if errors.As(err, &e) {
+//		fmt.Println(e.Type, e.Msg)
+//	}
+type Error struct {
+	Type ErrorType
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Msg)
+}
+
+// IsRateLimited checkes if this error is caused by rate limit restriction
+func (e *Error) IsRateLimited() bool {
+	return strings.EqualFold(string(e.Type), string(ErrRateLimited))
+}
+
 // apiResponse implements error interface
 type apiResponse struct {
 	Status string          `json:"status"`
-	Data   json.RawMessage `json:"data"`
+	Data   json.RawMessage `json:"data,omitempty"`
+
+	Type string `json:"errorType,omitempty"`
+	Msg  string `json:"error,omitempty"`
 
-	Type string `json:"errorType"`
-	Msg  string `json:"error"`
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 func (r *apiResponse) isError() bool {
 	return !strings.EqualFold(r.Status, "success")
 }
 
-// IsRateLimited checkes if this error is caused by rate limit restriction
-func (e *apiResponse) isRateLimited() bool {
-	return strings.EqualFold(e.Type, "RateLimited")
-}
-
 func (e *apiResponse) Error() string {
 	return fmt.Sprintf("%s: %s", e.Type, e.Msg)
 }
 
+// As helps errors.As to retrieve [Error] from apiResponse
+func (e *apiResponse) As(target any) bool {
+	if t, ok := target.(**Error); ok {
+		*t = &Error{Type: ErrorType(e.Type), Msg: e.Msg}
+		return true
+	}
+	return false
+}
+
 func UnmarshalApiResponse(body []byte) (*QueryResult, error) {
+	var res QueryResult
+	warnings, err := unmarshalData(body, &res)
+	if err != nil {
+		return nil, err
+	}
+	res.Warnings = warnings
+
+	return &res, nil
+}
+
+// MarshalApiResponse is the reverse of [UnmarshalApiResponse], it helps to build the
+// response body of a successful query
+func MarshalApiResponse(res *QueryResult) ([]byte, error) {
+	data, err := json.Marshal(struct {
+		Type   model.ValueType `json:"resultType"`
+		Result model.Value     `json:"result"`
+	}{Type: res.Type, Result: res.Val})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&apiResponse{
+		Status:   "success",
+		Data:     data,
+		Warnings: res.Warnings,
+	})
+}
+
+// UnmarshalSeriesResponse helps to unmarshal the response of [series] API
+//
+// [series]: https://prometheus.io/docs/prometheus/latest/querying/api/#finding-series-by-label-matchers
+func UnmarshalSeriesResponse(body []byte) ([]model.LabelSet, error) {
+	var res []model.LabelSet
+	if _, err := unmarshalData(body, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UnmarshalLabelsResponse helps to unmarshal the response of [labels] API
+//
+// [labels]: https://prometheus.io/docs/prometheus/latest/querying/api/#getting-label-names
+func UnmarshalLabelsResponse(body []byte) (model.LabelNames, error) {
+	var res model.LabelNames
+	if _, err := unmarshalData(body, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UnmarshalLabelValuesResponse helps to unmarshal the response of [label values] API
+//
+// [label values]: https://prometheus.io/docs/prometheus/latest/querying/api/#querying-label-values
+func UnmarshalLabelValuesResponse(body []byte) (model.LabelValues, error) {
+	var res model.LabelValues
+	if _, err := unmarshalData(body, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// unmarshalData unmarshals the `data` field of the response into v, and returns the warnings
+func unmarshalData(body []byte, v any) ([]string, error) {
 	var resp apiResponse
 	if err := json.Unmarshal(body, &resp); err != nil {
 		return nil, err
@@ -54,18 +158,17 @@ func UnmarshalApiResponse(body []byte) (*QueryResult, error) {
 		return nil, &resp
 	}
 
-	var res QueryResult
-	if err := json.Unmarshal(resp.Data, &res); err != nil {
-		fmt.Printf("failed to unmarshal range promql, body:%s, err: %+v", resp.Data, err)
-		return nil, err
+	if err := json.Unmarshal(resp.Data, v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data of %d bytes: %w", len(resp.Data), err)
 	}
 
-	return &res, nil
+	return resp.Warnings, nil
 }
 
+// IsRateLimitedError checks if the error is caused by rate limit restriction
 func IsRateLimitedError(err error) bool {
-	e, ok := err.(*apiResponse)
-	return ok && e.isRateLimited()
+	var e *Error
+	return errors.As(err, &e) && e.IsRateLimited()
 }
 
 // QueryResult contains result data for a query.
@@ -75,6 +178,27 @@ type QueryResult struct {
 
 	// The decoded value.
 	Val model.Value
+
+	// Warnings are the warnings in the response, which is not in `data` field
+	Warnings []string `json:"-"`
+}
+
+// Vector returns the decoded value if the result type is vector
+func (qr *QueryResult) Vector() (model.Vector, bool) {
+	v, ok := qr.Val.(model.Vector)
+	return v, ok
+}
+
+// Matrix returns the decoded value if the result type is matrix
+func (qr *QueryResult) Matrix() (model.Matrix, bool) {
+	v, ok := qr.Val.(model.Matrix)
+	return v, ok
+}
+
+// Scalar returns the decoded value if the result type is scalar
+func (qr *QueryResult) Scalar() (*model.Scalar, bool) {
+	v, ok := qr.Val.(*model.Scalar)
+	return v, ok
 }
 
 func (qr *QueryResult) UnmarshalJSON(b []byte) error {
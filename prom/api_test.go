@@ -16,8 +16,11 @@ package prom
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -45,3 +48,84 @@ func TestApiResponseError(t *testing.T) {
 	assert.Equal(t, "RateLimited", e.Type)
 	assert.Equal(t, "Read request banned for xxx until 1692722423416", e.Msg)
 }
+
+func TestApiResponseAsError(t *testing.T) {
+	body := []byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`)
+	res, err := UnmarshalApiResponse(body)
+	assert.Nil(t, res)
+	assert.False(t, IsRateLimitedError(err))
+
+	var e *Error
+	assert.True(t, errors.As(fmt.Errorf("wrapped: %w", err), &e))
+	assert.Equal(t, ErrBadData, e.Type)
+	assert.Equal(t, "parse error", e.Msg)
+
+	assert.True(t, IsRateLimitedError(fmt.Errorf("wrapped: %w", &Error{Type: ErrRateLimited})))
+	assert.False(t, IsRateLimitedError(errors.New("RateLimited")))
+}
+
+func TestUnmarshalApiResponse(t *testing.T) {
+	body := []byte(`{"status":"success","warnings":["partial result"],"data":{"resultType":"vector","result":[{"metric":{"host":"a"},"value":[1692722423.416,"1.5"]}]}}`)
+	res, err := UnmarshalApiResponse(body)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ValVector, res.Type)
+	assert.Equal(t, []string{"partial result"}, res.Warnings)
+
+	vector, ok := res.Vector()
+	assert.True(t, ok)
+	assert.Len(t, vector, 1)
+	assert.Equal(t, model.LabelValue("a"), vector[0].Metric["host"])
+	assert.Equal(t, model.SampleValue(1.5), vector[0].Value)
+	assert.Equal(t, model.Time(1692722423416), vector[0].Timestamp)
+
+	_, ok = res.Matrix()
+	assert.False(t, ok)
+
+	body = []byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"host":"a"},"values":[[1692722423,"1"],[1692722424,"2"]]}]}}`)
+	res, err = UnmarshalApiResponse(body)
+	assert.Nil(t, err)
+	assert.Empty(t, res.Warnings)
+	matrix, ok := res.Matrix()
+	assert.True(t, ok)
+	assert.Len(t, matrix[0].Values, 2)
+
+	body = []byte(`{"status":"success","data":{"resultType":"scalar","result":[1692722423,"3"]}}`)
+	res, err = UnmarshalApiResponse(body)
+	assert.Nil(t, err)
+	scalar, ok := res.Scalar()
+	assert.True(t, ok)
+	assert.Equal(t, model.SampleValue(3), scalar.Value)
+}
+
+func TestMarshalApiResponse(t *testing.T) {
+	body := []byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"host":"a"},"values":[[1692722423,"1"],[1692722424,"2"]]}]},"warnings":["partial result"]}`)
+	res, err := UnmarshalApiResponse(body)
+	assert.Nil(t, err)
+
+	b, err := MarshalApiResponse(res)
+	assert.Nil(t, err)
+	assert.JSONEq(t, string(body), string(b))
+}
+
+func TestUnmarshalMetadataResponse(t *testing.T) {
+	series, err := UnmarshalSeriesResponse([]byte(`{"status":"success","data":[{"__name__":"up","job":"prometheus"}]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []model.LabelSet{{"__name__": "up", "job": "prometheus"}}, series)
+
+	labels, err := UnmarshalLabelsResponse([]byte(`{"status":"success","data":["__name__","job"]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, model.LabelNames{"__name__", "job"}, labels)
+
+	values, err := UnmarshalLabelValuesResponse([]byte(`{"status":"success","data":["prometheus"]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, model.LabelValues{"prometheus"}, values)
+
+	_, err = UnmarshalLabelsResponse([]byte(`{"status":"error","errorType":"RateLimited","error":"banned"}`))
+	assert.True(t, IsRateLimitedError(err))
+
+	// the data is not in the error
+	_, err = UnmarshalLabelsResponse([]byte(`{"status":"success","data":{"job":"prometheus"}}`))
+	var typeErr *json.UnmarshalTypeError
+	assert.ErrorAs(t, err, &typeErr)
+	assert.NotContains(t, err.Error(), "prometheus")
+}
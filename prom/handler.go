@@ -0,0 +1,225 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prom
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// Querier executes PromQL and returns the body of the response, which is in the
+// format of Prometheus HTTP API. greptime.Client.PromQuerier implements it.
+type Querier interface {
+	InstantQuery(ctx context.Context, query string, ts time.Time) ([]byte, error)
+	RangeQuery(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]byte, error)
+}
+
+// Handler serves the [query] and [range query] APIs of Prometheus via [Querier], so
+// that Grafana or other Prometheus clients can query GreptimeDB through it:
+//
+//	http.Handle("/api/v1/", prom.NewHandler(client.PromQuerier("public")))
+//
+// The requests are routed by the suffix of the path, `/api/v1/query` and
+// `/api/v1/query_range`, so it can be mounted under any prefix.
+//
+// [query]: https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
+// [range query]: https://prometheus.io/docs/prometheus/latest/querying/api/#range-queries
+type Handler struct {
+	querier Querier
+}
+
+// NewHandler helps to init the Handler
+func NewHandler(querier Querier) *Handler {
+	return &Handler{querier: querier}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeError(w, &Error{Type: ErrBadData, Msg: fmt.Sprintf("method %s is not allowed", r.Method)})
+		return
+	}
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/api/v1/query"):
+		h.serveQuery(w, r)
+	case strings.HasSuffix(r.URL.Path, "/api/v1/query_range"):
+		h.serveQueryRange(w, r)
+	default:
+		writeError(w, &Error{Type: ErrNotFound, Msg: fmt.Sprintf("path %s is not found", r.URL.Path)})
+	}
+}
+
+func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, &Error{Type: ErrBadData, Msg: err.Error()})
+		return
+	}
+
+	ts := time.Now()
+	if v := r.Form.Get("time"); len(v) > 0 {
+		var err error
+		if ts, err = parseTime(v); err != nil {
+			writeError(w, &Error{Type: ErrBadData, Msg: fmt.Sprintf("invalid parameter 'time': %s", err)})
+			return
+		}
+	}
+
+	body, err := h.querier.InstantQuery(r.Context(), r.Form.Get("query"), ts)
+	writeResponse(w, body, err)
+}
+
+func (h *Handler) serveQueryRange(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, &Error{Type: ErrBadData, Msg: err.Error()})
+		return
+	}
+
+	start, err := parseTime(r.Form.Get("start"))
+	if err != nil {
+		writeError(w, &Error{Type: ErrBadData, Msg: fmt.Sprintf("invalid parameter 'start': %s", err)})
+		return
+	}
+
+	end, err := parseTime(r.Form.Get("end"))
+	if err != nil {
+		writeError(w, &Error{Type: ErrBadData, Msg: fmt.Sprintf("invalid parameter 'end': %s", err)})
+		return
+	}
+
+	if end.Before(start) {
+		writeError(w, &Error{Type: ErrBadData, Msg: "end timestamp must not be before start time"})
+		return
+	}
+
+	step, err := parseDuration(r.Form.Get("step"))
+	if err != nil {
+		writeError(w, &Error{Type: ErrBadData, Msg: fmt.Sprintf("invalid parameter 'step': %s", err)})
+		return
+	}
+
+	if step <= 0 {
+		writeError(w, &Error{Type: ErrBadData, Msg: "zero or negative query resolution step widths are not accepted"})
+		return
+	}
+
+	body, err := h.querier.RangeQuery(r.Context(), r.Form.Get("query"), start, end, step)
+	writeResponse(w, body, err)
+}
+
+// parseTime parses the time in unix timestamp or RFC3339 like Prometheus
+func parseTime(s string) (time.Time, error) {
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+		}
+		secs, frac := math.Modf(t)
+		return time.Unix(int64(secs), int64(math.Round(frac*1e3))*int64(time.Millisecond)), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
+
+// parseDuration parses the duration in seconds or Prometheus duration format like 5m
+func parseDuration(s string) (time.Duration, error) {
+	if d, err := strconv.ParseFloat(s, 64); err == nil {
+		if math.IsNaN(d) || math.IsInf(d, 0) {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+		}
+		ts := d * float64(time.Second)
+		if ts > float64(math.MaxInt64) || ts < float64(math.MinInt64) {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration. It overflows int64", s)
+		}
+		return time.Duration(ts), nil
+	}
+	if d, err := model.ParseDuration(s); err == nil {
+		return time.Duration(d), nil
+	}
+	return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+}
+
+// writeResponse writes the body responded by the querier as is, and the status
+// code is decided by the errorType in the body
+func writeResponse(w http.ResponseWriter, body []byte, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var resp apiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		writeError(w, &Error{Type: ErrInternal, Msg: fmt.Sprintf("invalid response: %s", err)})
+		return
+	}
+
+	status := http.StatusOK
+	if resp.isError() {
+		status = statusCode(ErrorType(resp.Type))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Type: ErrInternal, Msg: err.Error()}
+		if errors.Is(err, context.Canceled) {
+			e.Type = ErrCanceled
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			e.Type = ErrTimeout
+		}
+	}
+
+	body, _ := json.Marshal(&apiResponse{
+		Status: "error",
+		Type:   string(e.Type),
+		Msg:    e.Msg,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode(e.Type))
+	w.Write(body)
+}
+
+// statusCode maps the errorType into HTTP status code the same as Prometheus
+func statusCode(typ ErrorType) int {
+	switch typ {
+	case ErrBadData:
+		return http.StatusBadRequest
+	case ErrExec:
+		return http.StatusUnprocessableEntity
+	case ErrCanceled, ErrTimeout, ErrUnavailable:
+		return http.StatusServiceUnavailable
+	case ErrNotFound:
+		return http.StatusNotFound
+	case ErrRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
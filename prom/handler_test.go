@@ -0,0 +1,165 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prom
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeQuerier struct {
+	query string
+	ts    time.Time
+	start time.Time
+	end   time.Time
+	step  time.Duration
+
+	body []byte
+	err  error
+}
+
+func (q *fakeQuerier) InstantQuery(ctx context.Context, query string, ts time.Time) ([]byte, error) {
+	q.query, q.ts = query, ts
+	return q.body, q.err
+}
+
+func (q *fakeQuerier) RangeQuery(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]byte, error) {
+	q.query, q.start, q.end, q.step = query, start, end, step
+	return q.body, q.err
+}
+
+func serve(h http.Handler, method, path string, params url.Values) *httptest.ResponseRecorder {
+	var r *http.Request
+	if method == http.MethodPost {
+		r = httptest.NewRequest(method, path, strings.NewReader(params.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		r = httptest.NewRequest(method, path+"?"+params.Encode(), nil)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) *apiResponse {
+	var resp apiResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "error", resp.Status)
+	return &resp
+}
+
+func TestHandlerInstantQuery(t *testing.T) {
+	body := `{"status":"success","data":{"resultType":"vector","result":[]}}`
+	q := &fakeQuerier{body: []byte(body)}
+	h := NewHandler(q)
+
+	w := serve(h, http.MethodGet, "/prometheus/api/v1/query", url.Values{"query": {"up"}, "time": {"1677728740.5"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, body, w.Body.String())
+	assert.Equal(t, "up", q.query)
+	assert.Equal(t, time.UnixMilli(1677728740500), q.ts)
+
+	w = serve(h, http.MethodPost, "/api/v1/query", url.Values{"query": {"up"}, "time": {"2023-03-02T03:45:40Z"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, time.Unix(1677728740, 0).Equal(q.ts))
+
+	// time is now if not specified
+	before := time.Now()
+	w = serve(h, http.MethodGet, "/api/v1/query", url.Values{"query": {"up"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, q.ts.Before(before))
+
+	for _, ts := range []string{"yesterday", "NaN", "Inf"} {
+		w = serve(h, http.MethodGet, "/api/v1/query", url.Values{"query": {"up"}, "time": {ts}})
+		assert.Equal(t, http.StatusBadRequest, w.Code, ts)
+		assert.Equal(t, string(ErrBadData), decodeError(t, w).Type)
+	}
+}
+
+func TestHandlerRangeQuery(t *testing.T) {
+	body := `{"status":"success","data":{"resultType":"matrix","result":[]}}`
+	q := &fakeQuerier{body: []byte(body)}
+	h := NewHandler(q)
+
+	params := url.Values{"query": {"up"}, "start": {"1677728740"}, "end": {"1677728800"}, "step": {"15s"}}
+	w := serve(h, http.MethodGet, "/api/v1/query_range", params)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, body, w.Body.String())
+	assert.Equal(t, time.Unix(1677728740, 0), q.start)
+	assert.Equal(t, time.Unix(1677728800, 0), q.end)
+	assert.Equal(t, 15*time.Second, q.step)
+
+	params.Set("step", "0.5")
+	w = serve(h, http.MethodPost, "/api/v1/query_range", params)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 500*time.Millisecond, q.step)
+
+	invalids := []url.Values{
+		{"query": {"up"}, "start": {"invalid"}, "end": {"1677728800"}, "step": {"15s"}},
+		{"query": {"up"}, "start": {"1677728740"}, "end": {"invalid"}, "step": {"15s"}},
+		{"query": {"up"}, "start": {"1677728740"}, "end": {"1677728800"}, "step": {"invalid"}},
+		{"query": {"up"}, "start": {"1677728740"}, "end": {"1677728800"}, "step": {"0"}},
+		{"query": {"up"}, "start": {"1677728800"}, "end": {"1677728740"}, "step": {"15s"}},
+		{"query": {"up"}, "start": {"NaN"}, "end": {"1677728800"}, "step": {"15s"}},
+		{"query": {"up"}, "start": {"-Inf"}, "end": {"1677728800"}, "step": {"15s"}},
+		{"query": {"up"}, "start": {"1677728740"}, "end": {"+Inf"}, "step": {"15s"}},
+		{"query": {"up"}, "start": {"1677728740"}, "end": {"1677728800"}, "step": {"NaN"}},
+		{"query": {"up"}, "start": {"1677728740"}, "end": {"1677728800"}, "step": {"Inf"}},
+	}
+	for _, params := range invalids {
+		w = serve(h, http.MethodGet, "/api/v1/query_range", params)
+		assert.Equal(t, http.StatusBadRequest, w.Code, params.Encode())
+		assert.Equal(t, string(ErrBadData), decodeError(t, w).Type)
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	q := &fakeQuerier{err: &Error{Type: ErrRateLimited, Msg: "banned"}}
+	h := NewHandler(q)
+
+	w := serve(h, http.MethodGet, "/api/v1/query", url.Values{"query": {"up"}})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	resp := decodeError(t, w)
+	assert.Equal(t, "banned", resp.Msg)
+	_, err := UnmarshalApiResponse(w.Body.Bytes())
+	assert.True(t, IsRateLimitedError(err))
+
+	// error responded by greptimedb is written as is
+	body := `{"status":"error","errorType":"execution","error":"table not found"}`
+	q.body, q.err = []byte(body), nil
+	w = serve(h, http.MethodGet, "/api/v1/query", url.Values{"query": {"up"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, body, w.Body.String())
+
+	q.body, q.err = nil, context.DeadlineExceeded
+	w = serve(h, http.MethodGet, "/api/v1/query", url.Values{"query": {"up"}})
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, string(ErrTimeout), decodeError(t, w).Type)
+
+	w = serve(h, http.MethodGet, "/api/v1/labels", url.Values{})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(h, http.MethodDelete, "/api/v1/query", url.Values{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prom
+
+import (
+	"sort"
+
+	"github.com/prometheus/common/model"
+)
+
+// MergeMatrix helps to stitch the matrices of adjacent ranges of the same query
+// together. Streams with the same labels are merged into one, samples are sorted by
+// timestamp, and the sample of the latter matrix wins if the timestamps are the same,
+// which happens at the boundaries of the ranges.
+func MergeMatrix(matrices ...model.Matrix) model.Matrix {
+	var merged model.Matrix
+	streams := map[model.Fingerprint]*model.SampleStream{}
+
+	for _, matrix := range matrices {
+		for _, stream := range matrix {
+			fp := stream.Metric.Fingerprint()
+			s, ok := streams[fp]
+			if !ok {
+				s = &model.SampleStream{Metric: stream.Metric}
+				streams[fp] = s
+				merged = append(merged, s)
+			}
+			s.Values = append(s.Values, stream.Values...)
+			s.Histograms = append(s.Histograms, stream.Histograms...)
+		}
+	}
+
+	for _, s := range merged {
+		s.Values = dedupValues(s.Values)
+		s.Histograms = dedupHistograms(s.Histograms)
+	}
+
+	sort.Sort(merged)
+	return merged
+}
+
+// dedupValues sorts the samples stably, and keeps the last one of the same timestamp
+func dedupValues(values []model.SamplePair) []model.SamplePair {
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].Timestamp < values[j].Timestamp
+	})
+
+	res := values[:0]
+	for i, v := range values {
+		if i+1 < len(values) && values[i+1].Timestamp == v.Timestamp {
+			continue
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// dedupHistograms is the same as dedupValues but for native histograms
+func dedupHistograms(histograms []model.SampleHistogramPair) []model.SampleHistogramPair {
+	sort.SliceStable(histograms, func(i, j int) bool {
+		return histograms[i].Timestamp < histograms[j].Timestamp
+	})
+
+	res := histograms[:0]
+	for i, h := range histograms {
+		if i+1 < len(histograms) && histograms[i+1].Timestamp == h.Timestamp {
+			continue
+		}
+		res = append(res, h)
+	}
+	return res
+}
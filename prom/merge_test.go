@@ -0,0 +1,49 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prom
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeMatrix(t *testing.T) {
+	up := model.Metric{"__name__": "up", "job": "prometheus"}
+	down := model.Metric{"__name__": "up", "job": "node"}
+
+	first := model.Matrix{
+		{Metric: up, Values: []model.SamplePair{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 1}}},
+	}
+	second := model.Matrix{
+		{Metric: down, Values: []model.SamplePair{{Timestamp: 3000, Value: 0}}},
+		// the sample at the boundary is responded again
+		{Metric: up, Values: []model.SamplePair{{Timestamp: 2000, Value: 2}, {Timestamp: 3000, Value: 3}}},
+	}
+
+	merged := MergeMatrix(second, first)
+	assert.Equal(t, model.Matrix{
+		{Metric: down, Values: []model.SamplePair{{Timestamp: 3000, Value: 0}}},
+		{Metric: up, Values: []model.SamplePair{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 1}, {Timestamp: 3000, Value: 3}}},
+	}, merged)
+
+	merged = MergeMatrix(first, second)
+	assert.Equal(t, []model.SamplePair{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 2}, {Timestamp: 3000, Value: 3}},
+		merged[1].Values)
+
+	assert.Empty(t, MergeMatrix())
+	assert.Empty(t, MergeMatrix(model.Matrix{}, nil))
+}
@@ -16,6 +16,7 @@ package greptime
 
 import (
 	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/apache/arrow/go/v13/arrow/flight"
 )
 
 type query interface {
@@ -28,6 +29,12 @@ type query interface {
 	buildPromqlRequest(header *greptimepb.RequestHeader) (*greptimepb.PromqlRequest, error)
 }
 
+// metricBuilder can be implemented by query if the response of [Client.Query]
+// should be converted into Metric in its own way
+type metricBuilder interface {
+	buildMetric(r *flight.Reader) (*Metric, error)
+}
+
 // QueryRequest helps to query data from greptimedb, and the response is in Metric.
 // But if you expect the response format is the same as Prometheus, you should consider
 // [PromqlRequest].
@@ -56,6 +63,24 @@ func (r *QueryRequest) WithSql(sql string) *QueryRequest {
 	return r
 }
 
+// WithSqlArgs helps to bind arguments into the `?` placeholders of sql in client side,
+// the arguments are quoted and escaped, so that sql built from user input can not be
+// injected. Supported arguments are:
+//
+//   - nil, and nil pointer is bound as NULL
+//   - bool, string, []byte, integers, floats and their pointers
+//   - time.Time in millisecond, or [TimestampArg] with the specified precision
+//   - [Ident] for table name or column name
+//   - [driver.Valuer]
+//
+// For example:
+//
+//	req.WithSqlArgs("SELECT * FROM ? WHERE host = ? AND ts > ?", greptime.Ident("monitor"), host, start)
+func (r *QueryRequest) WithSqlArgs(sql string, args ...any) *QueryRequest {
+	r.query = &Sql{sql: sql, args: args, bind: true}
+	return r
+}
+
 func (r *QueryRequest) WithInstantPromql(instantPromql *InstantPromql) *QueryRequest {
 	r.query = instantPromql
 	return r
@@ -91,3 +116,10 @@ func (r *QueryRequest) buildPromqlRequest(cfg *Config) (*greptimepb.PromqlReques
 
 	return r.query.buildPromqlRequest(header)
 }
+
+func (r *QueryRequest) buildMetric(reader *flight.Reader) (*Metric, error) {
+	if builder, ok := r.query.(metricBuilder); ok {
+		return builder.buildMetric(reader)
+	}
+	return buildMetricFromReader(reader)
+}
@@ -0,0 +1,363 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/GreptimeTeam/greptimedb-client-go/prom"
+	"github.com/prometheus/common/model"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxExtentsPerKey limits the number of disjoint ranges cached for the same query
+const maxExtentsPerKey = 16
+
+// alignTime rounds t down to the multiple of step since unix epoch
+func alignTime(t time.Time, step time.Duration) time.Time {
+	rem := time.Duration(t.UnixNano() % int64(step))
+	if rem < 0 {
+		rem += step
+	}
+	return t.Add(-rem)
+}
+
+// resolved returns a copy of the range with the relative range resolved, the
+// range is sent as it is.
+func (rp *RangePromql) resolved() *RangePromql {
+	if rp.check() != nil {
+		return rp
+	}
+
+	start, end := rp.timeRange()
+	resolved := *rp
+	resolved.Start, resolved.End, resolved.Last = start, end, 0
+	return &resolved
+}
+
+// lastStep returns the last evaluation time of the range, which is End rounded
+// down to the steps since Start. The ranges of the same Start and lastStep have
+// the same result.
+func (rp *RangePromql) lastStep() time.Time {
+	steps := rp.End.Sub(rp.Start) / rp.Step
+	return rp.Start.Add(steps * rp.Step)
+}
+
+// phase returns the offset of the evaluation times from the multiples of Step
+// since unix epoch, the ranges of the same phase share the evaluation times.
+func (rp *RangePromql) phase() time.Duration {
+	return rp.Start.Sub(alignTime(rp.Start, rp.Step))
+}
+
+// cacheable returns the query to be cached, and the relative range of RangePromql
+// is resolved. InstantPromql evaluated at now is not cacheable.
+func cacheable(q query) (query, bool) {
+	switch q := q.(type) {
+	case *Sql:
+		return q, true
+	case *InstantPromql:
+		return q, !q.Ts.IsZero()
+	case *RangePromql:
+		return q.resolved(), true
+	default:
+		return q, false
+	}
+}
+
+func queryCacheKey(request *greptimepb.GreptimeRequest) (string, bool) {
+	db := request.GetHeader().GetDbname()
+	switch q := request.GetQuery().GetQuery().(type) {
+	case *greptimepb.QueryRequest_Sql:
+		return fmt.Sprintf("query|%s|sql|%s", db, q.Sql), true
+	case *greptimepb.QueryRequest_PromRangeQuery:
+		r := q.PromRangeQuery
+		return fmt.Sprintf("query|%s|promql|%s|%s|%s|%s", db, r.Start, r.End, r.Step, r.Query), true
+	default:
+		return "", false
+	}
+}
+
+// cachedQuery is the same as [Client.Query] but the result is cached
+func (c *Client) cachedQuery(ctx context.Context, req QueryRequest) (*Metric, error) {
+	q, ok := cacheable(req.query)
+	if !ok {
+		return c.query(ctx, req)
+	}
+	req.query = q
+
+	// the End of the range is rounded down to the last step in the key only
+	keyReq := req
+	if rp, ok := q.(*RangePromql); ok && rp.check() == nil {
+		keyRange := *rp
+		keyRange.End = rp.lastStep()
+		keyReq.query = &keyRange
+	}
+	request, err := keyReq.buildGreptimeRequest(c.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := queryCacheKey(request)
+	if !ok {
+		return c.query(ctx, req)
+	}
+
+	if v, ok := c.cfg.QueryCache.Get(key); ok {
+		if metric, ok := v.(*Metric); ok {
+			return metric, nil
+		}
+	}
+
+	metric, err := c.query(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c.cfg.QueryCache.Set(key, metric, c.cfg.QueryCacheTTL)
+	return metric, nil
+}
+
+// cachedPromqlQuery is the same as [Client.PromqlQuery] but the successful response
+// is cached. The range query reuses the cached extents and only queries the
+// missing head or tail.
+func (c *Client) cachedPromqlQuery(ctx context.Context, req QueryRequest) (*greptimepb.PromqlResponse, error) {
+	q, ok := cacheable(req.query)
+	if !ok {
+		return c.promqlQuery(ctx, req)
+	}
+	req.query = q
+
+	request, err := req.buildPromqlRequest(c.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db := request.GetHeader().GetDbname()
+	if rp, ok := q.(*RangePromql); ok {
+		return c.extentPromqlQuery(ctx, req, rp, db)
+	}
+
+	iq := request.GetInstantQuery()
+	key := fmt.Sprintf("promql|%s|instant|%s|%s", db, iq.GetTime(), iq.GetQuery())
+	if v, ok := c.cfg.QueryCache.Get(key); ok {
+		if resp, ok := v.(*greptimepb.PromqlResponse); ok {
+			return proto.Clone(resp).(*greptimepb.PromqlResponse), nil
+		}
+	}
+
+	resp, err := c.promqlQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := successResult(resp); ok {
+		c.cfg.QueryCache.Set(key, proto.Clone(resp), c.cfg.QueryCacheTTL)
+	}
+	return resp, nil
+}
+
+// successResult decodes the response if it is successful
+func successResult(resp *greptimepb.PromqlResponse) (*prom.QueryResult, bool) {
+	if !ParseRespHeader(resp).IsSuccess() {
+		return nil, false
+	}
+	res, err := prom.UnmarshalApiResponse(resp.GetBody())
+	return res, err == nil
+}
+
+// promqlExtent is the cached matrix of a range. The samples before the end of the
+// extent are assumed immutable, and the sample at the end is queried again when
+// the extent is extended. The end is the last evaluation time of the range, and
+// cachedAt is when the oldest samples of the extent were queried, so that the
+// extent expires as a whole even if it is extended continuously.
+type promqlExtent struct {
+	start    time.Time
+	end      time.Time
+	matrix   model.Matrix
+	warnings []string
+	cachedAt time.Time
+}
+
+func (e *promqlExtent) overlaps(start, end time.Time) bool {
+	return !e.start.After(end) && !e.end.Before(start)
+}
+
+func (c *Client) extentPromqlQuery(ctx context.Context, req QueryRequest, rp *RangePromql, db string) (*greptimepb.PromqlResponse, error) {
+	key := promqlExtentKey(db, rp)
+
+	if extent := c.findExtent(key, rp.Start, rp.lastStep()); extent != nil {
+		if res, err := c.fillExtent(ctx, req, rp, key, extent); err == nil {
+			body, err := prom.MarshalApiResponse(res)
+			if err != nil {
+				return nil, err
+			}
+			return &greptimepb.PromqlResponse{
+				Header: &greptimepb.ResponseHeader{Status: &greptimepb.Status{}},
+				Body:   body,
+			}, nil
+		}
+		// query the whole range if failed to query the missing part
+	}
+
+	resp, err := c.promqlQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res, ok := successResult(resp); ok {
+		if matrix, ok := res.Matrix(); ok {
+			c.storeExtent(key, &promqlExtent{start: rp.Start, end: rp.lastStep(), matrix: matrix,
+				warnings: res.Warnings, cachedAt: time.Now()})
+		}
+	}
+	return resp, nil
+}
+
+// promqlExtentKey is the key of the extents of the range query, the extents of
+// different phases are not merged since their evaluation times differ
+func promqlExtentKey(db string, rp *RangePromql) string {
+	return fmt.Sprintf("promql|%s|range|%s|%s|%s", db, rp.Step, rp.phase(), rp.Query)
+}
+
+// fillExtent queries the head and tail of the range missing in the extent, and
+// stores the merged result as a new extent
+func (c *Client) fillExtent(ctx context.Context, req QueryRequest, rp *RangePromql, key string, extent *promqlExtent) (*prom.QueryResult, error) {
+	var missing [][2]time.Time
+	if rp.Start.Before(extent.start) {
+		missing = append(missing, [2]time.Time{rp.Start, extent.start})
+	}
+	if rp.lastStep().After(extent.end) {
+		missing = append(missing, [2]time.Time{extent.end, rp.End})
+	}
+
+	matrices := []model.Matrix{extent.matrix}
+	warnings := append([]string{}, extent.warnings...)
+	for _, r := range missing {
+		sub := NewRangePromql(rp.Query).WithStart(r[0]).WithEnd(r[1]).WithStep(rp.Step)
+		resp, err := c.promqlQuery(ctx, QueryRequest{header: req.header, query: sub})
+		if err != nil {
+			return nil, err
+		}
+
+		res, ok := successResult(resp)
+		if !ok {
+			return nil, fmt.Errorf("failed to query range [%s, %s]", r[0], r[1])
+		}
+		matrix, ok := res.Matrix()
+		if !ok {
+			return nil, fmt.Errorf("unexpected result type %q", res.Type)
+		}
+		matrices = append(matrices, matrix)
+		warnings = append(warnings, res.Warnings...)
+	}
+
+	res := &prom.QueryResult{
+		Type:     model.ValMatrix,
+		Val:      trimMatrix(prom.MergeMatrix(matrices...), rp.Start, rp.End),
+		Warnings: dedupStrings(warnings),
+	}
+
+	if len(missing) > 0 {
+		// the merged extent is as old as the cached one
+		c.storeExtent(key, &promqlExtent{start: rp.Start, end: rp.lastStep(), matrix: res.Val.(model.Matrix),
+			warnings: res.Warnings, cachedAt: extent.cachedAt})
+	}
+	return res, nil
+}
+
+// findExtent finds the unexpired extent overlapping the range
+func (c *Client) findExtent(key string, start, end time.Time) *promqlExtent {
+	c.extentMu.Lock()
+	defer c.extentMu.Unlock()
+
+	for _, extent := range c.loadExtents(key) {
+		if extent.overlaps(start, end) {
+			return extent
+		}
+	}
+	return nil
+}
+
+// storeExtent replaces the extents overlapping the new one
+func (c *Client) storeExtent(key string, extent *promqlExtent) {
+	c.extentMu.Lock()
+	defer c.extentMu.Unlock()
+
+	extents := []*promqlExtent{}
+	for _, e := range c.loadExtents(key) {
+		if !e.overlaps(extent.start, extent.end) {
+			extents = append(extents, e)
+		}
+	}
+	extents = append(extents, extent)
+	if len(extents) > maxExtentsPerKey {
+		extents = extents[len(extents)-maxExtentsPerKey:]
+	}
+	c.cfg.QueryCache.Set(key, extents, c.cfg.QueryCacheTTL)
+}
+
+// loadExtents returns the unexpired extents, the caller MUST hold extentMu
+func (c *Client) loadExtents(key string) []*promqlExtent {
+	v, ok := c.cfg.QueryCache.Get(key)
+	if !ok {
+		return nil
+	}
+	extents, _ := v.([]*promqlExtent)
+
+	now := time.Now()
+	res := make([]*promqlExtent, 0, len(extents))
+	for _, extent := range extents {
+		if c.cfg.QueryCacheTTL <= 0 || now.Sub(extent.cachedAt) <= c.cfg.QueryCacheTTL {
+			res = append(res, extent)
+		}
+	}
+	return res
+}
+
+// trimMatrix drops the samples out of the range, and the streams without samples
+func trimMatrix(matrix model.Matrix, start, end time.Time) model.Matrix {
+	from, to := model.TimeFromUnixNano(start.UnixNano()), model.TimeFromUnixNano(end.UnixNano())
+
+	res := model.Matrix{}
+	for _, stream := range matrix {
+		trimmed := &model.SampleStream{Metric: stream.Metric}
+		for _, v := range stream.Values {
+			if v.Timestamp >= from && v.Timestamp <= to {
+				trimmed.Values = append(trimmed.Values, v)
+			}
+		}
+		for _, h := range stream.Histograms {
+			if h.Timestamp >= from && h.Timestamp <= to {
+				trimmed.Histograms = append(trimmed.Histograms, h)
+			}
+		}
+		if len(trimmed.Values) > 0 || len(trimmed.Histograms) > 0 {
+			res = append(res, trimmed)
+		}
+	}
+	return res
+}
+
+func dedupStrings(strs []string) []string {
+	var res []string
+	seen := map[string]bool{}
+	for _, s := range strs {
+		if !seen[s] {
+			seen[s] = true
+			res = append(res, s)
+		}
+	}
+	return res
+}
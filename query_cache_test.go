@@ -0,0 +1,278 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/GreptimeTeam/greptimedb-client-go/prom"
+	"github.com/apache/arrow/go/v13/arrow/flight"
+	"github.com/apache/arrow/go/v13/arrow/ipc"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakePromqlClient responds the matrix whose values are the timestamps in seconds
+type fakePromqlClient struct {
+	requests []*greptimepb.PromqlRequest
+	header   *greptimepb.ResponseHeader
+	md       metadata.MD
+}
+
+// parsePromqlTime parses the time or step in seconds into duration since unix epoch
+func parsePromqlTime(s string) time.Duration {
+	secs, _ := strconv.ParseFloat(s, 64)
+	return time.Duration(secs * float64(time.Second))
+}
+
+func (c *fakePromqlClient) Handle(ctx context.Context, in *greptimepb.PromqlRequest, opts ...grpc.CallOption) (*greptimepb.PromqlResponse, error) {
+	c.requests = append(c.requests, in)
+	c.md, _ = metadata.FromOutgoingContext(ctx)
+	if c.header != nil {
+		return &greptimepb.PromqlResponse{Header: c.header}, nil
+	}
+
+	var res *prom.QueryResult
+	if rq := in.GetRangeQuery(); rq != nil {
+		res = newMatrixResult(model.Metric{"__name__": "up"},
+			parsePromqlTime(rq.Start), parsePromqlTime(rq.End), parsePromqlTime(rq.Step))
+	} else {
+		ts := model.Time(parsePromqlTime(in.GetInstantQuery().Time).Milliseconds())
+		res = &prom.QueryResult{Type: model.ValVector, Val: model.Vector{{Metric: model.Metric{}, Value: 1, Timestamp: ts}}}
+	}
+
+	body, err := prom.MarshalApiResponse(res)
+	if err != nil {
+		return nil, err
+	}
+	return &greptimepb.PromqlResponse{Header: &greptimepb.ResponseHeader{Status: &greptimepb.Status{}}, Body: body}, nil
+}
+
+func newCachedPromqlClient() (*Client, *fakePromqlClient) {
+	fake := &fakePromqlClient{}
+	cfg := NewCfg("localhost").WithDatabase("public").WithQueryCache(nil, time.Minute)
+	return &Client{cfg: cfg, promqlClient: fake}, fake
+}
+
+func TestAlignTime(t *testing.T) {
+	assert.Equal(t, time.Unix(120, 0), alignTime(time.Unix(125, 500), 15*time.Second))
+	assert.Equal(t, time.Unix(-15, 0), alignTime(time.Unix(-10, 0), 15*time.Second))
+	assert.Equal(t, time.Unix(420, 0), alignTime(time.Unix(500, 0), 7*time.Minute))
+}
+
+func TestCachedRangePromqlQuery(t *testing.T) {
+	client, fake := newCachedPromqlClient()
+	ctx := context.Background()
+
+	query := func(start, end int64) model.Matrix {
+		rp := NewRangePromql("up").WithStart(time.Unix(start, 0)).WithEnd(time.Unix(end, 0)).WithStep(10 * time.Second)
+		res, err := client.PromqlQueryResult(ctx, *NewQueryRequest().WithRangePromql(rp))
+		assert.Nil(t, err)
+		matrix, ok := res.Matrix()
+		assert.True(t, ok)
+		return matrix
+	}
+	expected := func(start, end int64) model.Matrix {
+		return newMatrixResult(model.Metric{"__name__": "up"},
+			time.Duration(start)*time.Second, time.Duration(end)*time.Second, 10*time.Second).Val.(model.Matrix)
+	}
+
+	// the range is sent as it is
+	assert.Equal(t, expected(105, 205), query(105, 209))
+	assert.Len(t, fake.requests, 1)
+	assert.Equal(t, "105", fake.requests[0].GetRangeQuery().Start)
+	assert.Equal(t, "209", fake.requests[0].GetRangeQuery().End)
+
+	// fully cached
+	assert.Equal(t, expected(125, 185), query(125, 189))
+	assert.Len(t, fake.requests, 1)
+
+	// only the tail is queried, and the last step of the cached range is queried again
+	assert.Equal(t, expected(155, 265), query(155, 265))
+	assert.Len(t, fake.requests, 2)
+	assert.Equal(t, "205", fake.requests[1].GetRangeQuery().Start)
+	assert.Equal(t, "265", fake.requests[1].GetRangeQuery().End)
+
+	// head and tail are queried
+	assert.Equal(t, expected(105, 305), query(105, 308))
+	assert.Len(t, fake.requests, 4)
+	assert.Equal(t, "105", fake.requests[2].GetRangeQuery().Start)
+	assert.Equal(t, "155", fake.requests[2].GetRangeQuery().End)
+	assert.Equal(t, "265", fake.requests[3].GetRangeQuery().Start)
+	assert.Equal(t, "308", fake.requests[3].GetRangeQuery().End)
+
+	// the range of different evaluation times is queried as a whole
+	assert.Equal(t, expected(100, 200), query(100, 200))
+	assert.Len(t, fake.requests, 5)
+	assert.Equal(t, "100", fake.requests[4].GetRangeQuery().Start)
+
+	// disjoint range, different step or database are queried as a whole
+	assert.Equal(t, expected(1000, 1100), query(1000, 1100))
+	assert.Len(t, fake.requests, 6)
+
+	rp := NewRangePromql("up").WithStart(time.Unix(100, 0)).WithEnd(time.Unix(200, 0)).WithStep(20 * time.Second)
+	_, err := client.PromqlQuery(ctx, *NewQueryRequest().WithRangePromql(rp))
+	assert.Nil(t, err)
+	_, err = client.PromqlQuery(ctx, *NewQueryRequest().WithDatabase("other").WithRangePromql(rp))
+	assert.Nil(t, err)
+	assert.Len(t, fake.requests, 8)
+	assert.Equal(t, "other", fake.requests[7].GetHeader().GetDbname())
+}
+
+func TestCachedRangePromqlQueryTTL(t *testing.T) {
+	client, fake := newCachedPromqlClient()
+	ctx := context.Background()
+
+	rp := NewRangePromql("up").WithStart(time.Unix(100, 0)).WithEnd(time.Unix(200, 0)).WithStep(10 * time.Second)
+	key := promqlExtentKey("public", rp)
+	query := func(end int64) {
+		_, err := client.PromqlQuery(ctx, *NewQueryRequest().WithRangePromql(rp.WithEnd(time.Unix(end, 0))))
+		assert.Nil(t, err)
+	}
+	age := func(d time.Duration) time.Time {
+		extent := client.findExtent(key, rp.Start, rp.Start)
+		if !assert.NotNil(t, extent) {
+			return time.Time{}
+		}
+		extent.cachedAt = extent.cachedAt.Add(-d)
+		return extent.cachedAt
+	}
+
+	query(200)
+	cachedAt := age(50 * time.Second)
+
+	// the extended extent is as old as the cached samples
+	query(300)
+	assert.Len(t, fake.requests, 2)
+	assert.Equal(t, "200", fake.requests[1].GetRangeQuery().Start)
+	assert.Equal(t, cachedAt, client.findExtent(key, rp.Start, rp.Start).cachedAt)
+
+	// the sliding window does not keep the old samples alive
+	age(20 * time.Second)
+	query(310)
+	assert.Len(t, fake.requests, 3)
+	assert.Equal(t, "100", fake.requests[2].GetRangeQuery().Start)
+	assert.Equal(t, "310", fake.requests[2].GetRangeQuery().End)
+}
+
+func TestCachedInstantPromqlQuery(t *testing.T) {
+	client, fake := newCachedPromqlClient()
+	ctx := context.Background()
+
+	req := NewQueryRequest().WithInstantPromql(NewInstantPromql("up").WithTime(time.Unix(100, 0)))
+	first, err := client.PromqlQuery(ctx, *req)
+	assert.Nil(t, err)
+	second, err := client.PromqlQuery(ctx, *req)
+	assert.Nil(t, err)
+	assert.True(t, proto.Equal(first, second))
+	assert.Len(t, fake.requests, 1)
+
+	// evaluated at now
+	req = NewQueryRequest().WithInstantPromql(NewInstantPromql("up"))
+	_, err = client.PromqlQuery(ctx, *req)
+	assert.Nil(t, err)
+	_, err = client.PromqlQuery(ctx, *req)
+	assert.Nil(t, err)
+	assert.Len(t, fake.requests, 3)
+}
+
+func TestCachedPromqlQueryError(t *testing.T) {
+	client, fake := newCachedPromqlClient()
+	fake.header = &greptimepb.ResponseHeader{Status: &greptimepb.Status{StatusCode: 6001, ErrMsg: "banned"}}
+	ctx := context.Background()
+
+	rp := NewRangePromql("up").WithStart(time.Unix(100, 0)).WithEnd(time.Unix(200, 0)).WithStep(10 * time.Second)
+	for i := 0; i < 2; i++ {
+		_, err := client.PromqlQueryResult(ctx, *NewQueryRequest().WithRangePromql(rp))
+		assert.True(t, prom.IsRateLimitedError(err))
+	}
+	assert.Len(t, fake.requests, 2)
+
+	// failed to query the tail, and the whole range is queried again
+	fake.header = nil
+	_, err := client.PromqlQueryResult(ctx, *NewQueryRequest().WithRangePromql(rp))
+	assert.Nil(t, err)
+	fake.header = &greptimepb.ResponseHeader{Status: &greptimepb.Status{StatusCode: 6001, ErrMsg: "banned"}}
+	_, err = client.PromqlQueryResult(ctx, *NewQueryRequest().WithRangePromql(rp.WithEnd(time.Unix(300, 0))))
+	assert.True(t, prom.IsRateLimitedError(err))
+	assert.Len(t, fake.requests, 5)
+}
+
+type doGetStream struct {
+	grpc.ClientStream
+	flightStream
+}
+
+// fakeFlightClient responds the same record to DoGet
+type fakeFlightClient struct {
+	flight.Client
+	tickets int
+}
+
+func (c *fakeFlightClient) DoGet(ctx context.Context, in *flight.Ticket, opts ...grpc.CallOption) (flight.FlightService_DoGetClient, error) {
+	c.tickets++
+	record := newPromqlRecord([]string{"localhost"}, []float64{1}, []int64{1000})
+	defer record.Release()
+
+	stream := &doGetStream{}
+	writer := flight.NewRecordWriter(&stream.flightStream, ipc.WithSchema(record.Schema()))
+	if err := writer.Write(record); err != nil {
+		return nil, err
+	}
+	return stream, writer.Close()
+}
+
+func TestCachedQuery(t *testing.T) {
+	fake := &fakeFlightClient{}
+	cfg := NewCfg("localhost").WithDatabase("public").WithQueryCache(NewLRUQueryCache(10), time.Minute)
+	client := &Client{cfg: cfg, flightClient: fake}
+	ctx := context.Background()
+
+	req := NewQueryRequest().WithSqlArgs("SELECT * FROM monitor WHERE host = ?", "localhost")
+	first, err := client.Query(ctx, *req)
+	assert.Nil(t, err)
+	second, err := client.Query(ctx, *req)
+	assert.Nil(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, 1, fake.tickets)
+
+	_, err = client.Query(ctx, *NewQueryRequest().WithSqlArgs("SELECT * FROM monitor WHERE host = ?", "127.0.0.1"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, fake.tickets)
+
+	// the ends of the same last step share the result
+	rp := NewRangePromql("up").WithStart(time.Unix(101, 0)).WithEnd(time.Unix(201, 0)).WithStep(10 * time.Second)
+	_, err = client.Query(ctx, *NewQueryRequest().WithRangePromql(rp))
+	assert.Nil(t, err)
+	_, err = client.Query(ctx, *NewQueryRequest().WithRangePromql(rp.WithEnd(time.Unix(209, 0))))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, fake.tickets)
+	_, err = client.Query(ctx, *NewQueryRequest().WithRangePromql(rp.WithStart(time.Unix(105, 0))))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, fake.tickets)
+
+	_, err = client.Query(ctx, *NewQueryRequest().WithInstantPromql(NewInstantPromql("up")))
+	assert.Nil(t, err)
+	_, err = client.Query(ctx, *NewQueryRequest().WithInstantPromql(NewInstantPromql("up")))
+	assert.Nil(t, err)
+	assert.Equal(t, 6, fake.tickets)
+}
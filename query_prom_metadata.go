@@ -0,0 +1,138 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/GreptimeTeam/greptimedb-client-go/prom"
+	"github.com/prometheus/common/model"
+)
+
+// promApiPrefix is the prefix of Prometheus compatible HTTP API in greptimedb
+const promApiPrefix = "/v1/prometheus/api/v1"
+
+// PromSeries helps to find the series matching the matchers in the default database,
+// like `up{job="prometheus"}`. start and end can be left empty.
+// You can visit [series] for detail.
+//
+// The metadata APIs are only provided via HTTP, see [Config.WithHTTPPort].
+//
+// [series]: https://prometheus.io/docs/prometheus/latest/querying/api/#finding-series-by-label-matchers
+func (c *Client) PromSeries(ctx context.Context, matchers []string, start, end time.Time) ([]model.LabelSet, error) {
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("at least one matcher is required in series")
+	}
+
+	body, err := c.promMetadata(ctx, promApiPrefix+"/series", buildPromMetadataParams(matchers, start, end))
+	if err != nil {
+		return nil, err
+	}
+	return prom.UnmarshalSeriesResponse(body)
+}
+
+// PromLabels helps to get the label names of the series matching the matchers in
+// the default database. matchers, start and end can be left empty.
+// You can visit [labels] for detail.
+//
+// [labels]: https://prometheus.io/docs/prometheus/latest/querying/api/#getting-label-names
+func (c *Client) PromLabels(ctx context.Context, matchers []string, start, end time.Time) (model.LabelNames, error) {
+	body, err := c.promMetadata(ctx, promApiPrefix+"/labels", buildPromMetadataParams(matchers, start, end))
+	if err != nil {
+		return nil, err
+	}
+	return prom.UnmarshalLabelsResponse(body)
+}
+
+// PromLabelValues helps to get the values of the label of the series matching the
+// matchers in the default database. matchers, start and end can be left empty.
+// You can visit [label values] for detail.
+//
+// [label values]: https://prometheus.io/docs/prometheus/latest/querying/api/#querying-label-values
+func (c *Client) PromLabelValues(ctx context.Context, name string, matchers []string, start, end time.Time) (model.LabelValues, error) {
+	if !model.LabelName(name).IsValid() {
+		return nil, fmt.Errorf("invalid label name: %q", name)
+	}
+
+	path := fmt.Sprintf("%s/label/%s/values", promApiPrefix, url.PathEscape(name))
+	body, err := c.promMetadata(ctx, path, buildPromMetadataParams(matchers, start, end))
+	if err != nil {
+		return nil, err
+	}
+	return prom.UnmarshalLabelValuesResponse(body)
+}
+
+func buildPromMetadataParams(matchers []string, start, end time.Time) url.Values {
+	params := url.Values{}
+	for _, matcher := range matchers {
+		params.Add("match[]", matcher)
+	}
+	if !start.IsZero() {
+		params.Set("start", formatPromqlTime(start))
+	}
+	if !end.IsZero() {
+		params.Set("end", formatPromqlTime(end))
+	}
+	return params
+}
+
+// promMetadata posts the params as form to the Prometheus compatible HTTP API,
+// and returns the body if it is in the format of Prometheus API response. The
+// database is in the query string, which is where greptimedb takes it from.
+func (c *Client) promMetadata(ctx context.Context, path string, params url.Values) ([]byte, error) {
+	if isEmptyString(c.cfg.Database) {
+		return nil, ErrEmptyDatabase
+	}
+
+	uri, err := url.Parse(c.cfg.getHTTPAddr() + path)
+	if err != nil {
+		return nil, err
+	}
+	uri.RawQuery = url.Values{"db": []string{c.cfg.Database}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if !isEmptyString(c.cfg.Username) && !isEmptyString(c.cfg.Password) {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.cfg.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prometheus API responds errors in JSON with non-2xx status code
+	if resp.StatusCode/100 != 2 && !json.Valid(body) {
+		return nil, fmt.Errorf("failed to request '%s', status: %s, body: %s", path, resp.Status, body)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/GreptimeTeam/greptimedb-client-go/prom"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func newPromMetadataClient(t *testing.T, handler http.HandlerFunc) *Client {
+	return newPromMetadataClientOf(t, "public", handler)
+}
+
+func newPromMetadataClientOf(t *testing.T, database string, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	assert.Nil(t, err)
+	port, err := strconv.Atoi(u.Port())
+	assert.Nil(t, err)
+
+	cfg := NewCfg(u.Hostname()).
+		WithHTTPPort(port).
+		WithDatabase(database).
+		WithAuth("user", "pass").
+		WithDialOptions(grpc.WithTransportCredentials(insecure.NewCredentials()))
+	client, err := NewClient(cfg)
+	assert.Nil(t, err)
+	return client
+}
+
+func TestPromSeries(t *testing.T) {
+	client := newPromMetadataClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/prometheus/api/v1/series", r.URL.Path)
+		assert.Nil(t, r.ParseForm())
+		assert.Equal(t, "public", r.URL.Query().Get("db"))
+		assert.Empty(t, r.PostForm.Get("db"))
+		assert.Equal(t, []string{`up{job="prometheus"}`, "monitor"}, r.Form["match[]"])
+		assert.Equal(t, "1677728740.5", r.Form.Get("start"))
+		assert.Equal(t, "1677728800", r.Form.Get("end"))
+		username, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", username)
+		assert.Equal(t, "pass", password)
+
+		w.Write([]byte(`{"status":"success","data":[{"__name__":"up","job":"prometheus"},{"__name__":"monitor","host":"127.0.0.1"}]}`))
+	})
+
+	series, err := client.PromSeries(context.Background(),
+		[]string{`up{job="prometheus"}`, "monitor"},
+		time.UnixMilli(1677728740500),
+		time.Unix(1677728800, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, []model.LabelSet{
+		{"__name__": "up", "job": "prometheus"},
+		{"__name__": "monitor", "host": "127.0.0.1"},
+	}, series)
+
+	_, err = client.PromSeries(context.Background(), nil, time.Time{}, time.Time{})
+	assert.NotNil(t, err)
+}
+
+func TestPromLabels(t *testing.T) {
+	client := newPromMetadataClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/prometheus/api/v1/labels", r.URL.Path)
+		assert.Nil(t, r.ParseForm())
+		assert.Empty(t, r.Form["match[]"])
+		assert.Empty(t, r.Form.Get("start"))
+
+		w.Write([]byte(`{"status":"success","data":["__name__","host","job"]}`))
+	})
+
+	labels, err := client.PromLabels(context.Background(), nil, time.Time{}, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, model.LabelNames{"__name__", "host", "job"}, labels)
+}
+
+func TestPromLabelValues(t *testing.T) {
+	client := newPromMetadataClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/prometheus/api/v1/label/host/values", r.URL.Path)
+		assert.Nil(t, r.ParseForm())
+		assert.Equal(t, []string{"monitor"}, r.Form["match[]"])
+
+		w.Write([]byte(`{"status":"success","data":["127.0.0.1","127.0.0.2"]}`))
+	})
+
+	values, err := client.PromLabelValues(context.Background(), "host", []string{"monitor"}, time.Time{}, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, model.LabelValues{"127.0.0.1", "127.0.0.2"}, values)
+
+	_, err = client.PromLabelValues(context.Background(), "invalid-label", nil, time.Time{}, time.Time{})
+	assert.NotNil(t, err)
+}
+
+func TestPromMetadataDatabase(t *testing.T) {
+	// the fake responds the labels of the database in the query string
+	labels := map[string]string{
+		"public":  `{"status":"success","data":["__name__","job"]}`,
+		"metrics": `{"status":"success","data":["__name__","host"]}`,
+	}
+	client := newPromMetadataClientOf(t, "metrics", func(w http.ResponseWriter, r *http.Request) {
+		body, ok := labels[r.URL.Query().Get("db")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"database not found"}`))
+			return
+		}
+		w.Write([]byte(body))
+	})
+
+	names, err := client.PromLabels(context.Background(), nil, time.Time{}, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, model.LabelNames{"__name__", "host"}, names)
+}
+
+func TestPromMetadataError(t *testing.T) {
+	client := newPromMetadataClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/prometheus/api/v1/labels":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"invalid matcher"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("404 page not found"))
+		}
+	})
+
+	_, err := client.PromLabels(context.Background(), []string{"{"}, time.Time{}, time.Time{})
+	var e *prom.Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, prom.ErrBadData, e.Type)
+
+	_, err = client.PromLabelValues(context.Background(), "host", nil, time.Time{}, time.Time{})
+	assert.ErrorContains(t, err, "404")
+}
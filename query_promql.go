@@ -15,16 +15,22 @@
 package greptime
 
 import (
+	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/GreptimeTeam/greptimedb-client-go/prom"
+	"github.com/apache/arrow/go/v13/arrow/flight"
 )
 
 var (
 	_ query = (*InstantPromql)(nil)
 	_ query = (*RangePromql)(nil)
+
+	_ metricBuilder = (*InstantPromql)(nil)
 )
 
 // InstantPromql helps to fire a request to greptimedb compatible with Prometheus instant query,
@@ -33,11 +39,11 @@ var (
 // [instant query]: https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
 type InstantPromql struct {
 	Query string
-	Ts    time.Time
+	Ts    time.Time // the evaluation time, it is now when the query is sent if not specified
 }
 
 func NewInstantPromql(query string) *InstantPromql {
-	return &InstantPromql{query, time.Now()}
+	return &InstantPromql{Query: query}
 }
 
 // WithQuery helps to update the query
@@ -46,7 +52,7 @@ func (ip *InstantPromql) WithQuery(query string) *InstantPromql {
 	return ip
 }
 
-// WithTime to specify the evaluation time. Default is now.
+// WithTime to specify the evaluation time. Default is the time when the query is sent.
 func (ip *InstantPromql) WithTime(ts time.Time) *InstantPromql {
 	ip.Ts = ts
 	return ip
@@ -60,8 +66,30 @@ func (ip *InstantPromql) check() error {
 	return nil
 }
 
+// evalTime returns the evaluation time, which is now if not specified
+func (ip *InstantPromql) evalTime() time.Time {
+	if ip.Ts.IsZero() {
+		return time.Now()
+	}
+	return ip.Ts
+}
+
+// buildGreptimeRequest converts the instant query into range query whose start
+// and end are both the evaluation time, which is how Prometheus evaluates the
+// instant query.
 func (ip *InstantPromql) buildGreptimeRequest(header *greptimepb.RequestHeader) (*greptimepb.GreptimeRequest, error) {
-	return nil, ErrNotImplemented
+	if err := ip.check(); err != nil {
+		return nil, err
+	}
+
+	ts := ip.evalTime()
+	rp := NewRangePromql(ip.Query).WithStart(ts).WithEnd(ts).WithStep(time.Second)
+	return rp.buildGreptimeRequest(header)
+}
+
+// buildMetric converts labels into tags, value into field, and evaluation time into timestamp
+func (ip *InstantPromql) buildMetric(r *flight.Reader) (*Metric, error) {
+	return buildPromqlMetricFromReader(r)
 }
 
 func (ip *InstantPromql) buildPromqlRequest(header *greptimepb.RequestHeader) (*greptimepb.PromqlRequest, error) {
@@ -72,13 +100,10 @@ func (ip *InstantPromql) buildPromqlRequest(header *greptimepb.RequestHeader) (*
 	promql := &greptimepb.PromqlRequest_InstantQuery{
 		InstantQuery: &greptimepb.PromInstantQuery{
 			Query: ip.Query,
+			Time:  formatPromqlTime(ip.evalTime()),
 		},
 	}
 
-	if !ip.Ts.IsZero() {
-		promql.InstantQuery.Time = fmt.Sprintf("%d", ip.Ts.Unix())
-	}
-
 	request := &greptimepb.PromqlRequest{
 		Header: header,
 		Promql: promql,
@@ -96,6 +121,15 @@ type RangePromql struct {
 	Start time.Time
 	End   time.Time
 	Step  time.Duration
+
+	// Last is the relative range ending at the time when the query is sent,
+	// Start and End are ignored if Last is specified.
+	Last time.Duration
+
+	// SplitInterval and SplitConcurrency control how a long range is split, see
+	// [RangePromql.WithSplit].
+	SplitInterval    time.Duration
+	SplitConcurrency int
 }
 
 func NewRangePromql(query string) *RangePromql {
@@ -122,18 +156,43 @@ func (rp *RangePromql) WithEnd(end time.Time) *RangePromql {
 	return rp
 }
 
+// WithLast helps to specify the relative range like the last 15 minutes, which is
+// resolved into Start and End when the query is sent, so the same RangePromql can be
+// sent repeatedly to query the latest data.
+func (rp *RangePromql) WithLast(last time.Duration) *RangePromql {
+	rp.Last = last
+	return rp
+}
+
 // WithStep helps to specify the step of the range
 func (rp *RangePromql) WithStep(step time.Duration) *RangePromql {
 	rp.Step = step
 	return rp
 }
 
+// WithSplit helps to split a long range into sub-ranges no longer than interval,
+// which are executed concurrently with at most concurrency queries in flight.
+// The interval is rounded up to a multiple of Step, so that the samples are
+// evaluated at the same timestamps as the unsplit query.
+//
+// It only takes effect in [Client.PromqlQueryResult], the matrices of the sub-ranges
+// are merged via [prom.MergeMatrix].
+func (rp *RangePromql) WithSplit(interval time.Duration, concurrency int) *RangePromql {
+	rp.SplitInterval = interval
+	rp.SplitConcurrency = concurrency
+	return rp
+}
+
 func (rp *RangePromql) check() error {
 	if isEmptyString(rp.Query) {
 		return ErrEmptyPromql
 	}
 
-	if rp.Start.IsZero() || rp.End.IsZero() {
+	if rp.Last < 0 {
+		return ErrEmptyRange
+	}
+
+	if rp.Last == 0 && (rp.Start.IsZero() || rp.End.IsZero()) {
 		return ErrEmptyRange
 	}
 
@@ -144,11 +203,21 @@ func (rp *RangePromql) check() error {
 	return nil
 }
 
+// timeRange resolves the relative range if Last is specified
+func (rp *RangePromql) timeRange() (time.Time, time.Time) {
+	if rp.Last > 0 {
+		end := time.Now()
+		return end.Add(-rp.Last), end
+	}
+	return rp.Start, rp.End
+}
+
 func (rp *RangePromql) toGreptimedbPromRangeQuery() *greptimepb.PromRangeQuery {
+	start, end := rp.timeRange()
 	return &greptimepb.PromRangeQuery{
 		Query: rp.Query,
-		Start: fmt.Sprintf("%d", rp.Start.Unix()),
-		End:   fmt.Sprintf("%d", rp.End.Unix()),
+		Start: formatPromqlTime(start),
+		End:   formatPromqlTime(end),
 		Step:  strconv.FormatFloat(rp.Step.Seconds(), 'f', -1, 64),
 	}
 }
@@ -173,6 +242,10 @@ func (rp *RangePromql) buildGreptimeRequest(header *greptimepb.RequestHeader) (*
 }
 
 func (rp *RangePromql) buildPromqlRequest(header *greptimepb.RequestHeader) (*greptimepb.PromqlRequest, error) {
+	if err := rp.check(); err != nil {
+		return nil, err
+	}
+
 	request := &greptimepb.PromqlRequest{
 		Header: header,
 		Promql: &greptimepb.PromqlRequest_RangeQuery{
@@ -182,3 +255,64 @@ func (rp *RangePromql) buildPromqlRequest(header *greptimepb.RequestHeader) (*gr
 
 	return request, nil
 }
+
+// formatPromqlTime formats the time into unix timestamp in seconds with the fraction
+// up to nanosecond, like `1677728740.123`, which has no timezone issue.
+func formatPromqlTime(t time.Time) string {
+	secs, nanos := t.Unix(), int64(t.Nanosecond())
+	if nanos == 0 {
+		return strconv.FormatInt(secs, 10)
+	}
+
+	sign := ""
+	if secs < 0 {
+		// t.Unix() rounds down, e.g. -0.25s is -1s + 750ms
+		sign, secs, nanos = "-", -secs-1, int64(time.Second)-nanos
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%09d", nanos), "0")
+	return fmt.Sprintf("%s%d.%s", sign, secs, frac)
+}
+
+var _ prom.Querier = (*promQuerier)(nil)
+
+// promQuerier implements [prom.Querier] via [Client.PromqlQuery]
+type promQuerier struct {
+	client   *Client
+	database string
+}
+
+// PromQuerier helps to serve Prometheus HTTP API via [prom.Handler], the PromQL is
+// executed in the database, and the default database is used if it is empty.
+func (c *Client) PromQuerier(database string) prom.Querier {
+	return &promQuerier{client: c, database: database}
+}
+
+func (q *promQuerier) InstantQuery(ctx context.Context, query string, ts time.Time) ([]byte, error) {
+	req := NewQueryRequest().WithDatabase(q.database).WithInstantPromql(NewInstantPromql(query).WithTime(ts))
+	return q.query(ctx, req)
+}
+
+func (q *promQuerier) RangeQuery(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]byte, error) {
+	rp := NewRangePromql(query).WithStart(start).WithEnd(end).WithStep(step)
+	req := NewQueryRequest().WithDatabase(q.database).WithRangePromql(rp)
+	return q.query(ctx, req)
+}
+
+func (q *promQuerier) query(ctx context.Context, req *QueryRequest) ([]byte, error) {
+	if _, err := req.buildPromqlRequest(q.client.cfg); err != nil {
+		return nil, &prom.Error{Type: prom.ErrBadData, Msg: err.Error()}
+	}
+
+	resp, err := q.client.PromqlQuery(ctx, *req)
+	if err != nil {
+		return nil, err
+	}
+
+	if header := ParseRespHeader(resp); header.IsRateLimited() {
+		return nil, &prom.Error{Type: prom.ErrRateLimited, Msg: header.Msg}
+	} else if err := header.Err(); err != nil && len(resp.GetBody()) == 0 {
+		return nil, &prom.Error{Type: prom.ErrExec, Msg: err.Error()}
+	}
+
+	return resp.GetBody(), nil
+}
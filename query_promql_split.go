@@ -0,0 +1,138 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GreptimeTeam/greptimedb-client-go/prom"
+	"github.com/prometheus/common/model"
+)
+
+// split helps to split the range into sub-ranges of SplitInterval rounded up to a
+// multiple of Step. The sub-ranges don't overlap, and the evaluation timestamps
+// are the same as the unsplit range. nil is returned if no need to split.
+func (rp *RangePromql) split() []*RangePromql {
+	if rp.SplitInterval <= 0 || rp.check() != nil {
+		return nil
+	}
+
+	start, end := rp.timeRange()
+	if end.Sub(start) < rp.SplitInterval {
+		return nil
+	}
+
+	interval := (rp.SplitInterval + rp.Step - 1) / rp.Step * rp.Step
+	if end.Sub(start) < interval {
+		return nil
+	}
+
+	var subs []*RangePromql
+	for s := start; !s.After(end); s = s.Add(interval) {
+		e := s.Add(interval - rp.Step)
+		if e.After(end) {
+			e = end
+		}
+		subs = append(subs, NewRangePromql(rp.Query).WithStart(s).WithEnd(e).WithStep(rp.Step))
+	}
+	return subs
+}
+
+type promqlQueryFunc func(ctx context.Context, req QueryRequest) (*prom.QueryResult, error)
+
+// queryAndMergeMatrix executes the sub-ranges with at most concurrency queries in
+// flight, and merges the matrices. The remaining queries are canceled once one fails.
+func queryAndMergeMatrix(ctx context.Context, header reqHeader, subs []*RangePromql,
+	concurrency int, query promqlQueryFunc) (*prom.QueryResult, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(subs) {
+		concurrency = len(subs)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		once    sync.Once
+		err     error
+		results = make([]*prom.QueryResult, len(subs))
+		indexes = make(chan int, len(subs))
+	)
+
+	for i := range subs {
+		indexes <- i
+	}
+	close(indexes)
+
+	for n := 0; n < concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					return
+				}
+
+				req := QueryRequest{header: header, query: subs[i]}
+				res, e := query(ctx, req)
+				if e != nil {
+					once.Do(func() {
+						err = e
+						cancel()
+					})
+					return
+				}
+				results[i] = res
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return mergeMatrixResults(subs, results)
+}
+
+func mergeMatrixResults(subs []*RangePromql, results []*prom.QueryResult) (*prom.QueryResult, error) {
+	var warnings []string
+	matrices := make([]model.Matrix, 0, len(results))
+
+	for i, res := range results {
+		matrix, ok := res.Matrix()
+		if !ok {
+			return nil, fmt.Errorf("unexpected result type %q of range [%s, %s]",
+				res.Type, subs[i].Start.Format(time.RFC3339), subs[i].End.Format(time.RFC3339))
+		}
+		matrices = append(matrices, matrix)
+		warnings = append(warnings, res.Warnings...)
+	}
+
+	return &prom.QueryResult{
+		Type:     model.ValMatrix,
+		Val:      prom.MergeMatrix(matrices...),
+		Warnings: dedupStrings(warnings),
+	}, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GreptimeTeam/greptimedb-client-go/prom"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangePromqlSplit(t *testing.T) {
+	start := time.Unix(1677728740, 0)
+	rp := NewRangePromql("up").WithStart(start).WithEnd(start.Add(time.Hour)).WithStep(7 * time.Minute)
+	assert.Nil(t, rp.split())
+
+	// interval is rounded up to 21m
+	subs := rp.WithSplit(20*time.Minute, 2).split()
+	assert.Len(t, subs, 3)
+	for i, sub := range subs {
+		assert.Equal(t, "up", sub.Query)
+		assert.Equal(t, 7*time.Minute, sub.Step)
+		assert.Equal(t, start.Add(time.Duration(i)*21*time.Minute), sub.Start)
+		assert.Zero(t, sub.SplitInterval)
+	}
+	assert.Equal(t, start.Add(14*time.Minute), subs[0].End)
+	assert.Equal(t, start.Add(35*time.Minute), subs[1].End)
+	// the last evaluation timestamp is the same as the unsplit range
+	assert.Equal(t, start.Add(56*time.Minute), subs[2].End)
+
+	// the range is not longer than the interval
+	assert.Nil(t, rp.WithSplit(2*time.Hour, 2).split())
+
+	// the relative range is resolved before splitting
+	subs = NewRangePromql("up").WithLast(time.Hour).WithStep(time.Minute).WithSplit(30*time.Minute, 2).split()
+	assert.Len(t, subs, 3)
+	assert.Equal(t, time.Hour, subs[2].End.Sub(subs[0].Start))
+	assert.Zero(t, subs[0].Last)
+
+	// invalid range is left to be checked when the request is built
+	assert.Nil(t, NewRangePromql("up").WithStep(time.Minute).WithSplit(time.Minute, 1).split())
+}
+
+func newMatrixResult(metric model.Metric, start, end, step time.Duration) *prom.QueryResult {
+	stream := &model.SampleStream{Metric: metric}
+	for ts := start; ts <= end; ts += step {
+		stream.Values = append(stream.Values, model.SamplePair{
+			Timestamp: model.Time(ts.Milliseconds()),
+			Value:     model.SampleValue(ts.Seconds()),
+		})
+	}
+	return &prom.QueryResult{Type: model.ValMatrix, Val: model.Matrix{stream}}
+}
+
+func TestQueryAndMergeMatrix(t *testing.T) {
+	rp := NewRangePromql("up").WithStart(time.Unix(0, 0)).WithEnd(time.Unix(100, 0)).WithStep(10 * time.Second)
+	subs := rp.WithSplit(30*time.Second, 2).split()
+	assert.Len(t, subs, 4)
+
+	var inflight, maxInflight int32
+	metric := model.Metric{"__name__": "up"}
+	query := func(ctx context.Context, req QueryRequest) (*prom.QueryResult, error) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInflight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInflight, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		assert.Equal(t, "public", req.header.database)
+		sub := req.query.(*RangePromql)
+		res := newMatrixResult(metric, time.Duration(sub.Start.UnixNano()), time.Duration(sub.End.UnixNano()), sub.Step)
+		res.Warnings = []string{"partial result"}
+		return res, nil
+	}
+
+	res, err := queryAndMergeMatrix(context.Background(), reqHeader{database: "public"}, subs, 2, query)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), maxInflight)
+	assert.Equal(t, []string{"partial result"}, res.Warnings)
+	matrix, ok := res.Matrix()
+	assert.True(t, ok)
+	assert.Equal(t, newMatrixResult(metric, 0, 100*time.Second, 10*time.Second).Val, matrix)
+}
+
+func TestQueryAndMergeMatrixError(t *testing.T) {
+	rp := NewRangePromql("up").WithStart(time.Unix(0, 0)).WithEnd(time.Unix(100, 0)).WithStep(10 * time.Second)
+	subs := rp.WithSplit(10*time.Second, 1).split()
+
+	var calls int32
+	rateLimited := &prom.Error{Type: prom.ErrRateLimited, Msg: "banned"}
+	query := func(ctx context.Context, req QueryRequest) (*prom.QueryResult, error) {
+		if atomic.AddInt32(&calls, 1) == 2 {
+			return nil, rateLimited
+		}
+		return newMatrixResult(nil, 0, 0, time.Second), nil
+	}
+
+	_, err := queryAndMergeMatrix(context.Background(), reqHeader{}, subs, 1, query)
+	assert.True(t, prom.IsRateLimitedError(err))
+	// the remaining sub-ranges are not queried
+	assert.Equal(t, int32(2), calls)
+
+	query = func(ctx context.Context, req QueryRequest) (*prom.QueryResult, error) {
+		return &prom.QueryResult{Type: model.ValScalar, Val: &model.Scalar{}}, nil
+	}
+	_, err = queryAndMergeMatrix(context.Background(), reqHeader{}, subs, 4, query)
+	assert.ErrorContains(t, err, "unexpected result type")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = queryAndMergeMatrix(ctx, reqHeader{}, subs, 4, query)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
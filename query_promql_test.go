@@ -19,108 +19,10 @@ import (
 	"testing"
 	"time"
 
-	"github.com/GreptimeTeam/greptimedb-client-go/prom"
-	"github.com/prometheus/common/model"
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
 	"github.com/stretchr/testify/assert"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
-func getClient(t *testing.T) *Client {
-	options := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	}
-	cfg := NewCfg(host).
-		WithPort(grpcPort).
-		WithDatabase(database).
-		WithDialOptions(options...)
-	client, err := NewClient(cfg)
-	assert.Nil(t, err)
-	assert.NotNil(t, client)
-	return client
-}
-
-func insert(t *testing.T, client *Client, table string, value float64, secs int64) {
-	series := Series{}
-	series.AddTag("host", "127.0.0.1")
-	series.SetTimestamp(time.Unix(secs, 0))
-	series.AddField("val", value)
-
-	metric := Metric{}
-	metric.AddSeries(series)
-
-	insert := InsertRequest{}
-	insert.WithTable(table).WithMetric(metric)
-
-	inserts := InsertsRequest{}
-	inserts.WithDatabase(database).Append(insert)
-
-	resp, err := client.Insert(context.Background(), inserts)
-	assert.Nil(t, err)
-	assert.True(t, ParseRespHeader(resp).IsSuccess())
-	assert.False(t, ParseRespHeader(resp).IsRateLimited())
-	assert.Equal(t, uint32(1), resp.GetAffectedRows().GetValue())
-}
-
-func TestRangePromql(t *testing.T) {
-	table := "test_range_promql"
-	var secs int64 = 1677728740
-	val := 0.45
-	client := getClient(t)
-	insert(t, client, table, val, secs)
-
-	rp := NewRangePromql(table).WithStart(time.Unix(secs, 0)).WithEnd(time.Unix(secs, 0)).WithStep(time.Second)
-	req := NewQueryRequest().WithRangePromql(rp).WithDatabase(database)
-	resp, err := client.PromqlQuery(context.Background(), *req)
-
-	assert.Nil(t, err)
-	assert.True(t, ParseRespHeader(resp).IsSuccess())
-	assert.False(t, ParseRespHeader(resp).IsRateLimited())
-
-	result, err := prom.UnmarshalApiResponse(resp.GetBody())
-	assert.Nil(t, err)
-	assert.NotNil(t, result.Val)
-
-	assert.Equal(t, model.ValMatrix, result.Val.Type())
-	matrix, ok := result.Val.(model.Matrix)
-	assert.True(t, ok)
-	assert.Equal(t, 1, matrix.Len())
-
-	sample := matrix[0]
-	assert.Equal(t, table, string(sample.Metric["__name__"]))
-	assert.Equal(t, 1, len(sample.Values))
-	assert.Equal(t, val, float64(sample.Values[0].Value))
-}
-
-func TestInstantPromql(t *testing.T) {
-	table := "test_instant_promql"
-	var secs int64 = 1677728740
-	val := 0.45
-	client := getClient(t)
-	insert(t, client, table, val, secs)
-
-	promql := NewInstantPromql(table).WithTime(time.Unix(secs, 0))
-	req := NewQueryRequest().WithInstantPromql(promql)
-	resp, err := client.PromqlQuery(context.Background(), *req)
-
-	assert.Nil(t, err)
-	assert.True(t, ParseRespHeader(resp).IsSuccess())
-	assert.False(t, ParseRespHeader(resp).IsRateLimited())
-
-	result, err := prom.UnmarshalApiResponse(resp.GetBody())
-	assert.Nil(t, err)
-	assert.NotNil(t, result.Val)
-
-	assert.Equal(t, model.ValVector, result.Val.Type())
-	vectors, ok := result.Val.(model.Vector)
-	assert.True(t, ok)
-	assert.Equal(t, 1, len(vectors))
-	vector := vectors[0]
-
-	assert.Equal(t, table, string(vector.Metric["__name__"]))
-	assert.Equal(t, val, float64(vector.Value))
-}
-
 func TestRangePromqlEmptyStep(t *testing.T) {
 	rp := RangePromql{
 		Query: "up",
@@ -130,3 +32,26 @@ func TestRangePromqlEmptyStep(t *testing.T) {
 
 	assert.ErrorIs(t, rp.check(), ErrEmptyStep)
 }
+
+func TestQueryPromqlMetric(t *testing.T) {
+	cfg := NewCfg("localhost").WithDatabase("public")
+	client := &Client{cfg: cfg, flightClient: &fakeFlightClient{}}
+	ctx := context.Background()
+
+	// the result of RangePromql is in columns as it is, like the one of sql
+	rp := NewRangePromql("up").WithStart(time.Unix(0, 0)).WithEnd(time.Unix(1, 0)).WithStep(time.Second)
+	metric, err := client.Query(ctx, *NewQueryRequest().WithRangePromql(rp))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"host", "val", "greptime_timestamp"}, metric.GetTagsAndFields())
+	series := metric.GetSeries()[0]
+	assert.Equal(t, greptimepb.SemanticType_FIELD, series.columns["host"].semantic)
+	assert.True(t, series.Timestamp().IsZero())
+
+	// the labels of InstantPromql are tags, and the evaluation time is timestamp
+	metric, err = client.Query(ctx, *NewQueryRequest().WithInstantPromql(NewInstantPromql("up").WithTime(time.Unix(1, 0))))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"host", "val"}, metric.GetTagsAndFields())
+	series = metric.GetSeries()[0]
+	assert.Equal(t, greptimepb.SemanticType_TAG, series.columns["host"].semantic)
+	assert.Equal(t, time.UnixMilli(1000), series.Timestamp())
+}
@@ -22,6 +22,10 @@ import (
 // as Promql Query
 type Sql struct {
 	sql string
+
+	// args are bound into sql if bind is true, see [QueryRequest.WithSqlArgs]
+	args []any
+	bind bool
 }
 
 var (
@@ -33,9 +37,17 @@ func (s *Sql) buildGreptimeRequest(header *greptimepb.RequestHeader) (*greptimep
 		return nil, ErrEmptySql
 	}
 
+	sql := s.sql
+	if s.bind {
+		var err error
+		if sql, err = bindSql(s.sql, s.args); err != nil {
+			return nil, err
+		}
+	}
+
 	request := &greptimepb.GreptimeRequest_Query{
 		Query: &greptimepb.QueryRequest{
-			Query: &greptimepb.QueryRequest_Sql{Sql: s.sql},
+			Query: &greptimepb.QueryRequest_Sql{Sql: sql},
 		},
 	}
 
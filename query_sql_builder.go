@@ -0,0 +1,346 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SelectBuilder helps to build the select sql of timeseries data without
+// concatenating strings by hand, identifiers are validated and quoted, and
+// values are bound like [QueryRequest.WithSqlArgs]. For example:
+//
+//	req, err := greptime.NewSelect("monitor").
+//		Columns("host", "cpu").
+//		WhereTag("host", "127.0.0.1").
+//		TimeRange(start, end).
+//		OrderBy("ts").
+//		Limit(10).
+//		Build()
+//
+// The errors are collected and returned in [SelectBuilder.Build].
+type SelectBuilder struct {
+	database string
+	table    string
+
+	columns []string
+	where   []string
+
+	timeIndex string
+	precision time.Duration
+	start     time.Time
+	end       time.Time
+
+	align   time.Duration
+	alignBy []string
+	fill    string
+
+	groupBy []string
+	orderBy []string
+	limit   int
+
+	err error
+}
+
+// NewSelect helps to init a SelectBuilder to select from the table
+func NewSelect(table string) *SelectBuilder {
+	return &SelectBuilder{table: table}
+}
+
+// WithDatabase helps to specify different database from the default one.
+func (b *SelectBuilder) WithDatabase(database string) *SelectBuilder {
+	b.database = database
+	return b
+}
+
+func (b *SelectBuilder) addErr(err error) {
+	if err != nil {
+		b.err = errors.Join(b.err, err)
+	}
+}
+
+func (b *SelectBuilder) quote(ident string) string {
+	quoted, err := quoteIdent(ident)
+	b.addErr(err)
+	return quoted
+}
+
+func (b *SelectBuilder) quoteAll(idents []string) []string {
+	quoted := make([]string, 0, len(idents))
+	for _, ident := range idents {
+		quoted = append(quoted, b.quote(ident))
+	}
+	return quoted
+}
+
+// Columns helps to specify the projection, default is all columns
+func (b *SelectBuilder) Columns(columns ...string) *SelectBuilder {
+	b.columns = append(b.columns, b.quoteAll(columns)...)
+	return b
+}
+
+// RangeAggregate helps to select the aggregation over the range like
+// `avg("cpu") RANGE '5m'`, which MUST be used with [SelectBuilder.Align].
+// You can visit [range query] for detail.
+//
+// [range query]: https://docs.greptime.com/reference/sql/range
+func (b *SelectBuilder) RangeAggregate(function, column string, rng time.Duration) *SelectBuilder {
+	if !identPartRegex.MatchString(function) {
+		b.addErr(fmt.Errorf("%w: function %q", ErrInvalidIdentifier, function))
+	}
+	if rng <= 0 {
+		b.addErr(fmt.Errorf("range of '%s' MUST be positive", column))
+	} else if rng%time.Millisecond != 0 {
+		b.addErr(fmt.Errorf("range of '%s' MUST be whole milliseconds, got %s", column, rng))
+	}
+	expr := fmt.Sprintf("%s(%s) RANGE '%s'", function, b.quote(column), formatSqlDuration(rng))
+	b.columns = append(b.columns, expr)
+	return b
+}
+
+// WhereTag helps to filter by `tag = val`, multiple filters are combined by AND
+func (b *SelectBuilder) WhereTag(tag string, val any) *SelectBuilder {
+	literal, err := formatSqlArg(val)
+	b.addErr(err)
+	b.where = append(b.where, fmt.Sprintf("%s = %s", b.quote(tag), literal))
+	return b
+}
+
+// WhereTagIn helps to filter by `tag IN (vals...)`
+func (b *SelectBuilder) WhereTagIn(tag string, vals ...any) *SelectBuilder {
+	if len(vals) == 0 {
+		b.addErr(fmt.Errorf("at least one value is required for tag '%s'", tag))
+	}
+	literals := make([]string, 0, len(vals))
+	for _, val := range vals {
+		literal, err := formatSqlArg(val)
+		b.addErr(err)
+		literals = append(literals, literal)
+	}
+	b.where = append(b.where, fmt.Sprintf("%s IN (%s)", b.quote(tag), strings.Join(literals, ", ")))
+	return b
+}
+
+// WithTimeIndex helps to specify the timestamp column name, default is ts.
+// It is the same as [Metric.SetTimestampAlias].
+func (b *SelectBuilder) WithTimeIndex(column string) *SelectBuilder {
+	b.timeIndex = column
+	return b
+}
+
+// WithTimePrecision helps to specify the precision of the time range, default is
+// millisecond. It is the same as [Metric.SetTimePrecision].
+func (b *SelectBuilder) WithTimePrecision(precision time.Duration) *SelectBuilder {
+	if !isValidPrecision(precision) {
+		b.addErr(ErrInvalidTimePrecision)
+	}
+	b.precision = precision
+	return b
+}
+
+func (b *SelectBuilder) getTimeIndex() string {
+	if isEmptyString(b.timeIndex) {
+		return "ts"
+	}
+	return b.timeIndex
+}
+
+// TimeRange helps to filter by `ts BETWEEN start AND end`, both are inclusive,
+// and they are truncated into the precision of [SelectBuilder.WithTimePrecision].
+func (b *SelectBuilder) TimeRange(start, end time.Time) *SelectBuilder {
+	if end.Before(start) {
+		b.addErr(fmt.Errorf("end '%v' MUST NOT be before start '%v'", end, start))
+	}
+	b.start = start
+	b.end = end
+	return b
+}
+
+func (b *SelectBuilder) buildTimeRange() (string, error) {
+	timeIndex, err := quoteIdent(b.getTimeIndex())
+	if err != nil {
+		return "", err
+	}
+	start, err := formatTimestamp(b.start, b.precision)
+	if err != nil {
+		return "", err
+	}
+	end, err := formatTimestamp(b.end, b.precision)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s BETWEEN %s AND %s", timeIndex, start, end), nil
+}
+
+// Align helps to specify the ALIGN clause of range query, and the data is
+// aligned by the columns if specified.
+func (b *SelectBuilder) Align(align time.Duration, by ...string) *SelectBuilder {
+	if align <= 0 {
+		b.addErr(errors.New("align MUST be positive"))
+	} else if align%time.Millisecond != 0 {
+		b.addErr(fmt.Errorf("align MUST be whole milliseconds, got %s", align))
+	}
+	b.align = align
+	b.alignBy = b.quoteAll(by)
+	return b
+}
+
+// Fill helps to specify the FILL clause of range query, which MUST be used with
+// [SelectBuilder.Align]. It can be NULL, PREV, LINEAR or a constant number.
+func (b *SelectBuilder) Fill(fill string) *SelectBuilder {
+	switch strings.ToUpper(fill) {
+	case "NULL", "PREV", "LINEAR":
+		b.fill = strings.ToUpper(fill)
+	default:
+		// the number is formatted again, so that only the finite decimal is in sql
+		f, err := strconv.ParseFloat(fill, 64)
+		if err == nil {
+			b.fill, err = formatFloat(f, 64)
+		}
+		if err != nil {
+			b.addErr(fmt.Errorf("fill '%s' is not valid", fill))
+		}
+	}
+	return b
+}
+
+// GroupBy helps to group the result by the columns
+func (b *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
+	b.groupBy = append(b.groupBy, b.quoteAll(columns)...)
+	return b
+}
+
+// OrderBy helps to order the result by the column in ascending order
+func (b *SelectBuilder) OrderBy(column string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, b.quote(column)+" ASC")
+	return b
+}
+
+// OrderByDesc helps to order the result by the column in descending order
+func (b *SelectBuilder) OrderByDesc(column string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, b.quote(column)+" DESC")
+	return b
+}
+
+// Limit helps to limit the number of rows, 0 means no limit
+func (b *SelectBuilder) Limit(limit int) *SelectBuilder {
+	if limit < 0 {
+		b.addErr(fmt.Errorf("limit '%d' MUST NOT be negative", limit))
+	}
+	b.limit = limit
+	return b
+}
+
+// Sql returns the built sql or the errors collected
+func (b *SelectBuilder) Sql() (string, error) {
+	if isEmptyString(b.table) {
+		return "", ErrEmptyTable
+	}
+
+	table, err := quoteIdent(b.table)
+	if err = errors.Join(b.err, err); err != nil {
+		return "", err
+	}
+
+	where := b.where
+	if !b.start.IsZero() || !b.end.IsZero() {
+		timeRange, err := b.buildTimeRange()
+		if err != nil {
+			return "", err
+		}
+		where = append(where[:len(where):len(where)], timeRange)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		sb.WriteString("*")
+	} else {
+		sb.WriteString(strings.Join(b.columns, ", "))
+	}
+
+	sb.WriteString(" FROM ")
+	sb.WriteString(table)
+
+	if len(where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(where, " AND "))
+	}
+
+	if !isEmptyString(b.fill) && b.align == 0 {
+		return "", errors.New("fill MUST be used with align")
+	}
+
+	if b.align > 0 {
+		fmt.Fprintf(&sb, " ALIGN '%s'", formatSqlDuration(b.align))
+		if len(b.alignBy) > 0 {
+			fmt.Fprintf(&sb, " BY (%s)", strings.Join(b.alignBy, ", "))
+		}
+		if !isEmptyString(b.fill) {
+			sb.WriteString(" FILL ")
+			sb.WriteString(b.fill)
+		}
+	}
+
+	if len(b.groupBy) > 0 {
+		sb.WriteString(" GROUP BY ")
+		sb.WriteString(strings.Join(b.groupBy, ", "))
+	}
+
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(b.orderBy, ", "))
+	}
+
+	if b.limit > 0 {
+		fmt.Fprintf(&sb, " LIMIT %d", b.limit)
+	}
+
+	return sb.String(), nil
+}
+
+// Build helps to build the QueryRequest
+func (b *SelectBuilder) Build() (*QueryRequest, error) {
+	sql, err := b.Sql()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewQueryRequest().WithDatabase(b.database).WithSql(sql), nil
+}
+
+// formatSqlDuration formats the duration in the largest exact unit, like 5m, 1500ms.
+// The duration MUST be whole milliseconds, which is checked by the callers.
+func formatSqlDuration(d time.Duration) string {
+	units := []struct {
+		unit   time.Duration
+		suffix string
+	}{
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+		{time.Millisecond, "ms"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d%s", d/u.unit, u.suffix)
+		}
+	}
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
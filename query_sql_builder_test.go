@@ -0,0 +1,134 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectBuilder(t *testing.T) {
+	start := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour + 1500*time.Microsecond)
+
+	sql, err := NewSelect("monitor").Sql()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM "monitor"`, sql)
+
+	sql, err = NewSelect("monitor").
+		Columns("host", "cpu").
+		WhereTag("host", "127.0.0.1").
+		WhereTagIn("region", "az1", "az2").
+		TimeRange(start, end).
+		OrderByDesc("ts").
+		Limit(10).
+		Sql()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT "host", "cpu" FROM "monitor" WHERE "host" = '127.0.0.1' AND "region" IN ('az1', 'az2') `+
+		`AND "ts" BETWEEN '2023-08-01 00:00:00.000Z' AND '2023-08-01 01:00:00.001Z' ORDER BY "ts" DESC LIMIT 10`, sql)
+
+	// precision can be specified after time range
+	sql, err = NewSelect("monitor").
+		TimeRange(start, end).
+		WithTimeIndex("timestamp").
+		WithTimePrecision(time.Microsecond).
+		Sql()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM "monitor" WHERE "timestamp" BETWEEN '2023-08-01 00:00:00.000000Z' AND '2023-08-01 01:00:00.001500Z'`, sql)
+
+	sql, err = NewSelect("monitor").
+		Columns("ts", "host").
+		RangeAggregate("avg", "cpu", 5*time.Minute).
+		RangeAggregate("max", "memory", 1500*time.Millisecond).
+		Align(time.Minute, "host").
+		Fill("prev").
+		OrderBy("host").
+		Sql()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT "ts", "host", avg("cpu") RANGE '5m', max("memory") RANGE '1500ms' FROM "monitor" `+
+		`ALIGN '1m' BY ("host") FILL PREV ORDER BY "host" ASC`, sql)
+
+	sql, err = NewSelect("monitor").
+		RangeAggregate("avg", "cpu", time.Minute).
+		Align(time.Minute).
+		Fill("+1.50").
+		Sql()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT avg("cpu") RANGE '1m' FROM "monitor" ALIGN '1m' FILL 1.5`, sql)
+
+	sql, err = NewSelect("monitor").
+		Columns("host").
+		GroupBy("host").
+		Sql()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT "host" FROM "monitor" GROUP BY "host"`, sql)
+}
+
+func TestSelectBuilderError(t *testing.T) {
+	_, err := NewSelect("").Sql()
+	assert.ErrorIs(t, err, ErrEmptyTable)
+
+	_, err = NewSelect("monitor; DROP TABLE monitor").Sql()
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+
+	_, err = NewSelect("monitor").Columns("host", "cpu)").Sql()
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+
+	_, err = NewSelect("monitor").RangeAggregate("avg(cpu) --", "cpu", time.Minute).Align(time.Minute).Sql()
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+
+	_, err = NewSelect("monitor").WithTimePrecision(time.Minute).Sql()
+	assert.ErrorIs(t, err, ErrInvalidTimePrecision)
+
+	_, err = NewSelect("monitor").TimeRange(time.Now(), time.Now().Add(-time.Hour)).Sql()
+	assert.NotNil(t, err)
+
+	_, err = NewSelect("monitor").Fill("0; DROP TABLE monitor").Align(time.Minute).Sql()
+	assert.NotNil(t, err)
+
+	_, err = NewSelect("monitor").Fill("prev").Sql()
+	assert.NotNil(t, err)
+
+	for _, fill := range []string{"NaN", "-Inf", "+infinity", "1e400"} {
+		_, err = NewSelect("monitor").Fill(fill).Align(time.Minute).Sql()
+		assert.NotNil(t, err, fill)
+	}
+
+	// the durations are not truncated into milliseconds
+	_, err = NewSelect("monitor").RangeAggregate("avg", "cpu", 1500*time.Microsecond).Align(time.Minute).Sql()
+	assert.NotNil(t, err)
+
+	_, err = NewSelect("monitor").RangeAggregate("avg", "cpu", time.Minute).Align(500 * time.Microsecond).Sql()
+	assert.NotNil(t, err)
+
+	_, err = NewSelect("monitor").Limit(-1).Sql()
+	assert.NotNil(t, err)
+
+	req, err := NewSelect("monitor").WhereTagIn("host").Build()
+	assert.NotNil(t, err)
+	assert.Nil(t, req)
+}
+
+func TestSelectBuilderBuild(t *testing.T) {
+	req, err := NewSelect("monitor").WithDatabase("public").WhereTag("host", "localhost").Build()
+	assert.Nil(t, err)
+
+	request, err := req.buildGreptimeRequest(&Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, "public", request.GetHeader().GetDbname())
+	assert.Equal(t, `SELECT * FROM "monitor" WHERE "host" = 'localhost'`, request.GetQuery().GetSql())
+}
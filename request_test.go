@@ -15,6 +15,7 @@
 package greptime
 
 import (
+	"strconv"
 	"testing"
 	"time"
 
@@ -39,10 +40,19 @@ func TestQueryBuildGreptimeRequest(t *testing.T) {
 	assert.Nil(t, err)
 
 	// test instant promql
-	rb.WithInstantPromql(NewInstantPromql("up == 0"))
+	ts := time.Unix(1677728740, 0)
+	rb.WithInstantPromql(NewInstantPromql("up == 0").WithTime(ts))
+	request, err = rb.buildGreptimeRequest(&Config{})
+	assert.NotNil(t, request)
+	assert.Nil(t, err)
+	rangeQuery := request.GetQuery().GetPromRangeQuery()
+	assert.Equal(t, "up == 0", rangeQuery.Query)
+	assert.Equal(t, rangeQuery.Start, rangeQuery.End)
+
+	rb.WithInstantPromql(NewInstantPromql(""))
 	request, err = rb.buildGreptimeRequest(&Config{})
 	assert.Nil(t, request)
-	assert.ErrorIs(t, err, ErrNotImplemented)
+	assert.ErrorIs(t, err, ErrEmptyPromql)
 
 	// test range promql
 	rp := &RangePromql{
@@ -98,13 +108,13 @@ func TestInsertBuilder(t *testing.T) {
 	r := InsertRequest{}
 
 	// empty table
-	req, err := r.build()
+	req, err := r.build(cfg)
 	assert.Equal(t, ErrEmptyTable, err)
 	assert.Nil(t, req)
 
 	// empty series
 	r.WithTable("monitor")
-	req, err = r.build()
+	req, err = r.build(cfg)
 	assert.Equal(t, ErrNoSeriesInMetric, err)
 	assert.Nil(t, req)
 
@@ -135,3 +145,61 @@ func TestInsertBuilder(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, reqs)
 }
+
+func TestFormatPromqlTime(t *testing.T) {
+	assert.Equal(t, "1677728740", formatPromqlTime(time.Unix(1677728740, 0)))
+	assert.Equal(t, "1677728740.123", formatPromqlTime(time.UnixMilli(1677728740123)))
+	assert.Equal(t, "1677728740.000001", formatPromqlTime(time.UnixMicro(1677728740000001)))
+	assert.Equal(t, "1677728740.123456789", formatPromqlTime(time.Unix(1677728740, 123456789)))
+	assert.Equal(t, "-0.25", formatPromqlTime(time.UnixMilli(-250)))
+	assert.Equal(t, "-1.5", formatPromqlTime(time.UnixMilli(-1500)))
+
+	// timezone does not matter
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	assert.Equal(t, "1677728740.5", formatPromqlTime(time.UnixMilli(1677728740500).In(loc)))
+}
+
+func TestInstantPromqlEvaluatedLazily(t *testing.T) {
+	ip := NewInstantPromql("up")
+	assert.True(t, ip.Ts.IsZero())
+
+	before := time.Now()
+	request, err := ip.buildPromqlRequest(nil)
+	assert.Nil(t, err)
+	ts, err := strconv.ParseFloat(request.GetInstantQuery().Time, 64)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, ts, float64(before.UnixMilli())/1000)
+
+	ip.WithTime(time.UnixMilli(1677728740123))
+	request, err = ip.buildPromqlRequest(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "1677728740.123", request.GetInstantQuery().Time)
+}
+
+func TestRangePromqlWithLast(t *testing.T) {
+	rp := NewRangePromql("up").WithLast(15 * time.Minute).WithStep(30 * time.Second)
+	assert.Nil(t, rp.check())
+
+	before := time.Now()
+	request, err := rp.buildPromqlRequest(nil)
+	assert.Nil(t, err)
+	after := time.Now()
+
+	rangeQuery := request.GetRangeQuery()
+	start, err := strconv.ParseFloat(rangeQuery.Start, 64)
+	assert.Nil(t, err)
+	end, err := strconv.ParseFloat(rangeQuery.End, 64)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, end, float64(before.UnixMilli())/1000)
+	assert.LessOrEqual(t, end, float64(after.UnixNano())/1e9)
+	assert.InDelta(t, 15*60, end-start, 0.001)
+	assert.Equal(t, "30", rangeQuery.Step)
+	assert.True(t, rp.Start.IsZero())
+
+	rp = NewRangePromql("up").WithStep(time.Second)
+	_, err = rp.buildPromqlRequest(nil)
+	assert.ErrorIs(t, err, ErrEmptyRange)
+
+	rp.WithLast(-time.Minute)
+	assert.ErrorIs(t, rp.check(), ErrEmptyRange)
+}
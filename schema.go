@@ -0,0 +1,474 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+)
+
+// schemaSql retrieves the columns of the table, the arguments are database and table
+const schemaSql = "SELECT column_name, data_type, semantic_type FROM information_schema.columns WHERE table_schema = ? AND table_name = ?"
+
+// tableSchema is the schema of a table in greptimedb, the columns are nil if the
+// table does not exist
+type tableSchema struct {
+	columns   map[string]schemaColumn
+	timestamp string // name of the timestamp column
+	fetchedAt time.Time
+}
+
+// schemaColumn is the column in greptimedb, the data type is not validated if
+// it is unknown to the client, like DATE
+type schemaColumn struct {
+	column
+	typed bool
+}
+
+// schemaCache caches the schemas of the tables keyed by database and table, see
+// [Config.WithSchemaValidation]. The zero value is ready to use.
+type schemaCache struct {
+	mu      sync.Mutex
+	schemas map[string]*tableSchema
+}
+
+func schemaKey(database, table string) string {
+	return database + "." + table
+}
+
+// get returns the schema if it is cached and not expired
+func (c *schemaCache) get(key string, ttl time.Duration) (*tableSchema, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	schema, ok := c.schemas[key]
+	if !ok || (ttl > 0 && time.Since(schema.fetchedAt) > ttl) {
+		return nil, false
+	}
+	return schema, true
+}
+
+func (c *schemaCache) set(key string, schema *tableSchema) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.schemas == nil {
+		c.schemas = map[string]*tableSchema{}
+	}
+	c.schemas[key] = schema
+}
+
+// invalidate drops the schemas, so that they are fetched again in the next insert
+func (c *schemaCache) invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.schemas, key)
+	}
+}
+
+// schema returns the cached schema of the table, or fetches it from
+// information_schema.columns if it is not cached or expired. cached reports
+// whether the schema is from the cache.
+func (c *Client) schema(ctx context.Context, database, table string) (_ *tableSchema, cached bool, _ error) {
+	key := schemaKey(database, table)
+	if schema, ok := c.schemas.get(key, c.cfg.SchemaCacheTTL); ok {
+		return schema, true, nil
+	}
+
+	req := NewQueryRequest().WithDatabase(database).WithSqlArgs(schemaSql, database, table)
+	metric, err := c.query(ctx, *req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch the schema of %s: %w", key, err)
+	}
+
+	schema := &tableSchema{fetchedAt: time.Now()}
+	for _, s := range metric.GetSeries() {
+		name, _ := s.GetString("column_name")
+		dataType, _ := s.GetString("data_type")
+		semanticType, _ := s.GetString("semantic_type")
+
+		semantic, ok := greptimepb.SemanticType_value[strings.ToUpper(semanticType)]
+		if !ok {
+			return nil, false, fmt.Errorf("unknown semantic type %q of column %q in %s", semanticType, name, key)
+		}
+		if schema.columns == nil {
+			schema.columns = map[string]schemaColumn{}
+		}
+		typ, typed := parseDataType(dataType)
+		schema.columns[name] = schemaColumn{column: column{typ: typ, semantic: greptimepb.SemanticType(semantic)}, typed: typed}
+		if greptimepb.SemanticType(semantic) == greptimepb.SemanticType_TIMESTAMP {
+			schema.timestamp = name
+		}
+	}
+
+	c.schemas.set(key, schema)
+	return schema, false, nil
+}
+
+// refreshSchemas drops the cached schemas of all the tables in the request if
+// the insert fails, or the stale ones changed by the insert
+func (c *Client) refreshSchemas(request *greptimepb.GreptimeRequest, stale []string, err error) {
+	if err == nil {
+		c.schemas.invalidate(stale...)
+		return
+	}
+
+	database := request.GetHeader().GetDbname()
+	for _, insert := range request.GetInserts().GetInserts() {
+		c.schemas.invalidate(schemaKey(database, insert.GetTableName()))
+	}
+}
+
+// dataTypeNames are the names of the data types in information_schema.columns,
+// both the names like `TimestampMillisecond` and the SQL names like `timestamp(3)`
+// are recognized
+var dataTypeNames = map[string]greptimepb.ColumnDataType{
+	"int8":                 greptimepb.ColumnDataType_INT8,
+	"tinyint":              greptimepb.ColumnDataType_INT8,
+	"int16":                greptimepb.ColumnDataType_INT16,
+	"smallint":             greptimepb.ColumnDataType_INT16,
+	"int32":                greptimepb.ColumnDataType_INT32,
+	"int":                  greptimepb.ColumnDataType_INT32,
+	"int64":                greptimepb.ColumnDataType_INT64,
+	"bigint":               greptimepb.ColumnDataType_INT64,
+	"uint8":                greptimepb.ColumnDataType_UINT8,
+	"tinyint unsigned":     greptimepb.ColumnDataType_UINT8,
+	"uint16":               greptimepb.ColumnDataType_UINT16,
+	"smallint unsigned":    greptimepb.ColumnDataType_UINT16,
+	"uint32":               greptimepb.ColumnDataType_UINT32,
+	"int unsigned":         greptimepb.ColumnDataType_UINT32,
+	"uint64":               greptimepb.ColumnDataType_UINT64,
+	"bigint unsigned":      greptimepb.ColumnDataType_UINT64,
+	"float32":              greptimepb.ColumnDataType_FLOAT32,
+	"float":                greptimepb.ColumnDataType_FLOAT32,
+	"float64":              greptimepb.ColumnDataType_FLOAT64,
+	"double":               greptimepb.ColumnDataType_FLOAT64,
+	"boolean":              greptimepb.ColumnDataType_BOOLEAN,
+	"string":               greptimepb.ColumnDataType_STRING,
+	"binary":               greptimepb.ColumnDataType_BINARY,
+	"varbinary":            greptimepb.ColumnDataType_BINARY,
+	"timestampsecond":      greptimepb.ColumnDataType_TIMESTAMP_SECOND,
+	"timestamp(0)":         greptimepb.ColumnDataType_TIMESTAMP_SECOND,
+	"timestampmillisecond": greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND,
+	"timestamp(3)":         greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND,
+	"timestampmicrosecond": greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND,
+	"timestamp(6)":         greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND,
+	"timestampnanosecond":  greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND,
+	"timestamp(9)":         greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND,
+}
+
+func parseDataType(name string) (greptimepb.ColumnDataType, bool) {
+	typ, ok := dataTypeNames[strings.ToLower(strings.TrimSpace(name))]
+	return typ, ok
+}
+
+// validateSchema validates the inserts against the cached schemas of the tables,
+// the returned request has the numbers coerced if [Config.SchemaCoercion] is set.
+// The keys of tables whose schemas will be changed by the inserts are returned,
+// like new tables or new columns, which should be refreshed after inserting.
+func (c *Client) validateSchema(ctx context.Context, req InsertsRequest) (InsertsRequest, []string, error) {
+	database := req.header.database
+	if isEmptyString(database) {
+		database = c.cfg.Database
+	}
+
+	validated := InsertsRequest{header: req.header}
+	var errs []error
+	var stale []string
+	for _, insert := range req.inserts {
+		table, err := insert.tableName()
+		if err != nil {
+			return req, nil, err
+		}
+
+		// the columns of ColumnarMetric are validated without coercion
+		input, coerce := insert.metric, c.cfg.SchemaCoercion
+		if insert.columnar != nil {
+			input, coerce = insert.columnar.schema(), false
+		}
+
+		schema, cached, err := c.schema(ctx, database, table)
+		if err != nil {
+			return req, nil, err
+		}
+
+		metric, changed, err := schema.validate(table, input, coerce)
+		if err != nil && cached {
+			// the table may be altered since cached, validate against the latest schema
+			c.schemas.invalidate(schemaKey(database, table))
+			if schema, _, err = c.schema(ctx, database, table); err != nil {
+				return req, nil, err
+			}
+			metric, changed, err = schema.validate(table, input, coerce)
+		}
+		if fields := schema.newFields(metric); err == nil && len(fields) > 0 && c.cfg.evolves(table) {
+			if err := c.addFields(ctx, database, table, metric, fields); err != nil {
+				return req, nil, err
+			}
+			if schema, _, err = c.schema(ctx, database, table); err != nil {
+				return req, nil, err
+			}
+			metric, changed, err = schema.validate(table, input, coerce)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if changed {
+			stale = append(stale, schemaKey(database, table))
+		}
+		if insert.columnar != nil {
+			validated.Append(insert)
+		} else {
+			validated.Append(*(&InsertRequest{}).WithTable(insert.table).WithMetric(metric))
+		}
+	}
+
+	if len(errs) > 0 {
+		return req, nil, errors.Join(errs...)
+	}
+	return validated, stale, nil
+}
+
+// validate validates the metric against the schema, and reports an [*Error] for
+// each mismatched column. changed is true if the metric adds new columns or the
+// table does not exist.
+func (s *tableSchema) validate(table string, metric Metric, coerce bool) (_ Metric, changed bool, _ error) {
+	if s.columns == nil {
+		return metric, true, nil
+	}
+
+	mismatch := func(name, format string, args ...any) error {
+		return &Error{
+			Code:   StatusInvalidArguments,
+			Msg:    fmt.Sprintf("column %s of table %s: %s", name, table, fmt.Sprintf(format, args...)),
+			Table:  table,
+			Column: name,
+		}
+	}
+
+	var errs []error
+	if alias := metric.GetTimestampAlias(); alias != s.timestamp {
+		errs = append(errs, mismatch(alias, "timestamp column is %s in the table", s.timestamp))
+	} else if datatype, err := precisionToDataType(metric.timestampPrecision); err != nil {
+		errs = append(errs, mismatch(alias, "%s", err))
+	} else if expected := s.columns[alias]; expected.typed && datatype != expected.typ {
+		errs = append(errs, mismatch(alias, "timestamp is %s in the table, but %s is inserted", expected.typ, datatype))
+	}
+
+	coercions := map[string]greptimepb.ColumnDataType{}
+	for _, name := range metric.orders {
+		col := metric.columns[name]
+		expected, ok := s.columns[name]
+		switch {
+		case !ok:
+			changed = true
+		case expected.semantic != col.semantic:
+			errs = append(errs, mismatch(name, "%s is expected, but %s is inserted", expected.semantic, col.semantic))
+		case !expected.typed || expected.typ == col.typ:
+		case coerce && isNumericDataType(expected.typ) && isNumericDataType(col.typ):
+			coercions[name] = expected.typ
+		default:
+			errs = append(errs, mismatch(name, "%s %s is expected, but %s is inserted", expected.semantic, expected.typ, col.typ))
+		}
+	}
+
+	if len(errs) > 0 {
+		return metric, false, errors.Join(errs...)
+	}
+	if len(coercions) == 0 {
+		return metric, changed, nil
+	}
+
+	coerced, err := coerceMetric(metric, coercions)
+	if err != nil {
+		return metric, false, mismatch(err.column, "%s", err.msg)
+	}
+	return coerced, changed, nil
+}
+
+type coercionError struct {
+	column string
+	msg    string
+}
+
+// coerceMetric converts the values of the columns into the data types, the
+// metric is copied instead of being modified, since the series are shared with
+// the caller
+func coerceMetric(metric Metric, coercions map[string]greptimepb.ColumnDataType) (Metric, *coercionError) {
+	coerced := Metric{
+		naming:             metric.naming,
+		timestampAlias:     metric.timestampAlias,
+		timestampPrecision: metric.timestampPrecision,
+		orders:             append([]string{}, metric.orders...),
+		columns:            make(map[string]column, len(metric.columns)),
+		series:             make([]Series, 0, len(metric.series)),
+	}
+	for name, col := range metric.columns {
+		if typ, ok := coercions[name]; ok {
+			col.typ = typ
+		}
+		coerced.columns[name] = col
+	}
+
+	for _, s := range metric.series {
+		series := s.Clone()
+		for name, col := range series.columns {
+			typ, ok := coercions[name]
+			if !ok {
+				continue
+			}
+			col.typ = typ
+			series.columns[name] = col
+			if val, exist := series.vals[name]; exist {
+				v, ok := coerceNumber(val, typ)
+				if !ok {
+					return metric, &coercionError{column: name, msg: fmt.Sprintf("%v can not be coerced into %s", val, typ)}
+				}
+				series.vals[name] = v
+			}
+		}
+		coerced.series = append(coerced.series, series)
+	}
+	return coerced, nil
+}
+
+func isNumericDataType(typ greptimepb.ColumnDataType) bool {
+	switch typ {
+	case greptimepb.ColumnDataType_INT8, greptimepb.ColumnDataType_INT16,
+		greptimepb.ColumnDataType_INT32, greptimepb.ColumnDataType_INT64,
+		greptimepb.ColumnDataType_UINT8, greptimepb.ColumnDataType_UINT16,
+		greptimepb.ColumnDataType_UINT32, greptimepb.ColumnDataType_UINT64,
+		greptimepb.ColumnDataType_FLOAT32, greptimepb.ColumnDataType_FLOAT64:
+		return true
+	default:
+		return false
+	}
+}
+
+// coerceNumber converts the number into the data type without losing precision,
+// ok is false if the value is out of range or can not be represented exactly
+func coerceNumber(v any, typ greptimepb.ColumnDataType) (any, bool) {
+	switch typ {
+	case greptimepb.ColumnDataType_INT8:
+		if i, ok := intOf(v); ok && i >= math.MinInt8 && i <= math.MaxInt8 {
+			return int8(i), true
+		}
+	case greptimepb.ColumnDataType_INT16:
+		if i, ok := intOf(v); ok && i >= math.MinInt16 && i <= math.MaxInt16 {
+			return int16(i), true
+		}
+	case greptimepb.ColumnDataType_INT32:
+		if i, ok := intOf(v); ok && i >= math.MinInt32 && i <= math.MaxInt32 {
+			return int32(i), true
+		}
+	case greptimepb.ColumnDataType_INT64:
+		if i, ok := intOf(v); ok {
+			return i, true
+		}
+	case greptimepb.ColumnDataType_UINT8:
+		if u, ok := uintOf(v); ok && u <= math.MaxUint8 {
+			return uint8(u), true
+		}
+	case greptimepb.ColumnDataType_UINT16:
+		if u, ok := uintOf(v); ok && u <= math.MaxUint16 {
+			return uint16(u), true
+		}
+	case greptimepb.ColumnDataType_UINT32:
+		if u, ok := uintOf(v); ok && u <= math.MaxUint32 {
+			return uint32(u), true
+		}
+	case greptimepb.ColumnDataType_UINT64:
+		if u, ok := uintOf(v); ok {
+			return u, true
+		}
+	case greptimepb.ColumnDataType_FLOAT32:
+		if f, ok := floatOf(v); ok && float64(float32(f)) == f {
+			return float32(f), true
+		}
+	case greptimepb.ColumnDataType_FLOAT64:
+		if f, ok := floatOf(v); ok {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// intOf converts the integer into int64, floats are not converted
+func intOf(v any) (int64, bool) {
+	switch t := v.(type) {
+	case int8:
+		return int64(t), true
+	case int16:
+		return int64(t), true
+	case int32:
+		return int64(t), true
+	case int64:
+		return t, true
+	default:
+		if u, ok := uintOf(v); ok && u <= math.MaxInt64 {
+			return int64(u), true
+		}
+		return 0, false
+	}
+}
+
+// uintOf converts the non-negative integer into uint64, floats are not converted
+func uintOf(v any) (uint64, bool) {
+	switch t := v.(type) {
+	case uint8:
+		return uint64(t), true
+	case uint16:
+		return uint64(t), true
+	case uint32:
+		return uint64(t), true
+	case uint64:
+		return t, true
+	case int8, int16, int32, int64:
+		if i, _ := intOf(t); i >= 0 {
+			return uint64(i), true
+		}
+	}
+	return 0, false
+}
+
+// maxExactInt is the max integer float64 represents exactly
+const maxExactInt = 1 << 53
+
+// floatOf converts the number into float64 if it can be represented exactly
+func floatOf(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float32:
+		return float64(t), true
+	case float64:
+		return t, true
+	}
+	if i, ok := intOf(v); ok && i >= -maxExactInt && i <= maxExactInt {
+		return float64(i), true
+	}
+	if u, ok := uintOf(v); ok && u <= maxExactInt {
+		return float64(u), true
+	}
+	return 0, false
+}
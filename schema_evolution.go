@@ -0,0 +1,98 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+)
+
+// sqlTypeNames are the SQL names of the data types in DDL
+var sqlTypeNames = map[greptimepb.ColumnDataType]string{
+	greptimepb.ColumnDataType_INT8:                  "TINYINT",
+	greptimepb.ColumnDataType_INT16:                 "SMALLINT",
+	greptimepb.ColumnDataType_INT32:                 "INT",
+	greptimepb.ColumnDataType_INT64:                 "BIGINT",
+	greptimepb.ColumnDataType_UINT8:                 "TINYINT UNSIGNED",
+	greptimepb.ColumnDataType_UINT16:                "SMALLINT UNSIGNED",
+	greptimepb.ColumnDataType_UINT32:                "INT UNSIGNED",
+	greptimepb.ColumnDataType_UINT64:                "BIGINT UNSIGNED",
+	greptimepb.ColumnDataType_FLOAT32:               "FLOAT",
+	greptimepb.ColumnDataType_FLOAT64:               "DOUBLE",
+	greptimepb.ColumnDataType_BOOLEAN:               "BOOLEAN",
+	greptimepb.ColumnDataType_STRING:                "STRING",
+	greptimepb.ColumnDataType_BINARY:                "VARBINARY",
+	greptimepb.ColumnDataType_TIMESTAMP_SECOND:      "TIMESTAMP(0)",
+	greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND: "TIMESTAMP(3)",
+	greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND: "TIMESTAMP(6)",
+	greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:  "TIMESTAMP(9)",
+}
+
+// evolves reports whether the new fields can be added into the table, see
+// [Config.WithSchemaEvolution]
+func (c *Config) evolves(table string) bool {
+	for _, t := range c.SchemaEvolutionTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// newFields returns the fields of the metric absent in the existing table in order,
+// the new tags are not included since they can not be added as nullable columns
+func (s *tableSchema) newFields(metric Metric) []string {
+	if s.columns == nil {
+		return nil
+	}
+
+	var fields []string
+	for _, name := range metric.orders {
+		if _, ok := s.columns[name]; !ok && metric.columns[name].semantic == greptimepb.SemanticType_FIELD {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// addFields adds the fields of the metric into the table via ALTER TABLE as
+// nullable columns. The columns added concurrently by others are skipped, and
+// they are validated against the refreshed schema afterwards.
+func (c *Client) addFields(ctx context.Context, database, table string, metric Metric, fields []string) error {
+	defer c.schemas.invalidate(schemaKey(database, table))
+
+	for _, name := range fields {
+		typ, ok := sqlTypeNames[metric.columns[name].typ]
+		if !ok {
+			return fmt.Errorf("failed to add column %s into %s: unsupported data type %s", name, table, metric.columns[name].typ)
+		}
+
+		req := NewQueryRequest().WithDatabase(database).
+			WithSqlArgs("ALTER TABLE ? ADD COLUMN ? "+typ+" NULL", Ident(table), Ident(name))
+		_, err := c.execute(ctx, *req)
+		switch {
+		case errors.Is(err, StatusTableColumnExists):
+			c.cfg.getLogger().Debug("column already exists", "database", database, "table", table, "column", name)
+		case err != nil:
+			return fmt.Errorf("failed to add column %s into %s: %w", name, table, err)
+		default:
+			c.cfg.getLogger().Info("column is added", "database", database, "table", table, "column", name, "type", typ)
+		}
+	}
+	return nil
+}
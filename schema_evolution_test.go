@@ -0,0 +1,95 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFields(t *testing.T) {
+	metric := Metric{}
+	series := Series{}
+	assert.Nil(t, series.AddTag("host", "a"))
+	assert.Nil(t, series.AddTag("region", "hangzhou"))
+	assert.Nil(t, series.AddField("disk", 0.5))
+	assert.Nil(t, series.AddField("cpu", 0.5))
+	assert.Nil(t, series.AddField("load", int64(1)))
+	assert.Nil(t, series.SetTimestamp(time.Now()))
+	assert.Nil(t, metric.AddSeries(series))
+
+	// the new tag region is not included
+	assert.Equal(t, []string{"disk", "load"}, monitorSchema().newFields(metric))
+	assert.Empty(t, (&tableSchema{}).newFields(metric))
+}
+
+func TestConfigEvolves(t *testing.T) {
+	cfg := NewCfg("localhost")
+	assert.False(t, cfg.evolves("monitor"))
+
+	cfg.WithSchemaEvolution("monitor", "cpu")
+	assert.True(t, cfg.SchemaValidation)
+	assert.True(t, cfg.evolves("monitor"))
+	assert.False(t, cfg.evolves("memory"))
+}
+
+func TestSqlTypeNames(t *testing.T) {
+	// the data types built from Series can all be added
+	for _, v := range []any{int8(1), int16(1), int32(1), int64(1), uint8(1), uint16(1), uint32(1), uint64(1),
+		float32(1), float64(1), true, "1", []byte("1")} {
+		val, err := convert(v)
+		assert.Nil(t, err)
+		assert.Contains(t, sqlTypeNames, val.typ, "%T", v)
+	}
+	assert.Equal(t, "TIMESTAMP(3)", sqlTypeNames[greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND])
+}
+
+func TestAddFieldsLogs(t *testing.T) {
+	logger := &recordLogger{}
+	fake := &fakeGreptimeClient{}
+	client := &Client{cfg: NewCfg("localhost").WithDatabase("public").WithLogger(logger), greptimeClient: fake}
+
+	metric := Metric{}
+	series := Series{}
+	assert.Nil(t, series.AddField("disk", 0.5))
+	assert.Nil(t, series.SetTimestamp(time.Now()))
+	assert.Nil(t, metric.AddSeries(series))
+
+	columnLogs := func() []string {
+		var logs []string
+		for _, log := range logger.logs {
+			if strings.Contains(log, "column") {
+				logs = append(logs, log)
+			}
+		}
+		logger.logs = nil
+		return logs
+	}
+
+	// the column added concurrently is skipped, but not logged as added
+	fake.header = &greptimepb.ResponseHeader{Status: &greptimepb.Status{StatusCode: uint32(StatusTableColumnExists)}}
+	assert.Nil(t, client.addFields(context.Background(), "public", "monitor", metric, []string{"disk"}))
+	assert.Equal(t, []string{"debug: column already exists [database public table monitor column disk]"}, columnLogs())
+
+	fake.header = &greptimepb.ResponseHeader{Status: &greptimepb.Status{}}
+	assert.Nil(t, client.addFields(context.Background(), "public", "monitor", metric, []string{"disk"}))
+	assert.Equal(t, []string{"info: column is added [database public table monitor column disk type DOUBLE]"}, columnLogs())
+	assert.Len(t, fake.requests, 2)
+}
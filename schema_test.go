@@ -0,0 +1,213 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func monitorSchema() *tableSchema {
+	typed := func(typ greptimepb.ColumnDataType, semantic greptimepb.SemanticType) schemaColumn {
+		return schemaColumn{column: column{typ: typ, semantic: semantic}, typed: true}
+	}
+	return &tableSchema{
+		columns: map[string]schemaColumn{
+			"host":   typed(greptimepb.ColumnDataType_STRING, greptimepb.SemanticType_TAG),
+			"cpu":    typed(greptimepb.ColumnDataType_FLOAT64, greptimepb.SemanticType_FIELD),
+			"memory": typed(greptimepb.ColumnDataType_UINT32, greptimepb.SemanticType_FIELD),
+			"day":    {column: column{semantic: greptimepb.SemanticType_FIELD}},
+			"ts":     typed(greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND, greptimepb.SemanticType_TIMESTAMP),
+		},
+		timestamp: "ts",
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := monitorSchema()
+
+	metric := Metric{}
+	series := Series{}
+	assert.Nil(t, series.AddTag("host", "a"))
+	assert.Nil(t, series.AddField("cpu", 0.5))
+	assert.Nil(t, series.AddField("day", "ignored"))
+	assert.Nil(t, series.SetTimestamp(time.Now()))
+	assert.Nil(t, metric.AddSeries(series))
+
+	validated, changed, err := schema.validate("monitor", metric, false)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, metric, validated)
+
+	// new column changes the schema
+	series = Series{}
+	assert.Nil(t, series.AddField("disk", 0.5))
+	assert.Nil(t, series.SetTimestamp(time.Now()))
+	assert.Nil(t, metric.AddSeries(series))
+	_, changed, err = schema.validate("monitor", metric, false)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	// the table does not exist
+	_, changed, err = (&tableSchema{}).validate("monitor", metric, false)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+}
+
+func TestSchemaValidateMismatch(t *testing.T) {
+	schema := monitorSchema()
+
+	metric := Metric{}
+	assert.Nil(t, metric.SetTimePrecision(time.Second))
+	series := Series{}
+	assert.Nil(t, series.AddField("host", "a"))
+	assert.Nil(t, series.AddField("cpu", "high"))
+	assert.Nil(t, series.AddField("memory", int64(1)))
+	assert.Nil(t, series.SetTimestamp(time.Now()))
+	assert.Nil(t, metric.AddSeries(series))
+
+	_, _, err := schema.validate("monitor", metric, false)
+	assert.ErrorIs(t, err, StatusInvalidArguments)
+
+	var columns []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var ge *Error
+		assert.True(t, errors.As(e, &ge))
+		assert.Equal(t, "monitor", ge.Table)
+		columns = append(columns, ge.Column)
+	}
+	assert.Equal(t, []string{"ts", "host", "cpu", "memory"}, columns)
+	assert.ErrorContains(t, err, "column ts of table monitor: timestamp is TIMESTAMP_MILLISECOND in the table, but TIMESTAMP_SECOND is inserted")
+	assert.ErrorContains(t, err, "column host of table monitor: TAG is expected, but FIELD is inserted")
+	assert.ErrorContains(t, err, "column cpu of table monitor: FIELD FLOAT64 is expected, but STRING is inserted")
+
+	// the name of timestamp column
+	metric = Metric{}
+	assert.Nil(t, metric.SetTimestampAlias("created_at"))
+	series = Series{}
+	assert.Nil(t, series.SetTimestamp(time.Now()))
+	assert.Nil(t, metric.AddSeries(series))
+	_, _, err = schema.validate("monitor", metric, false)
+	assert.ErrorContains(t, err, "column created_at of table monitor: timestamp column is ts in the table")
+}
+
+func TestSchemaValidateCoercion(t *testing.T) {
+	schema := monitorSchema()
+
+	metric := Metric{}
+	for i := 0; i < 2; i++ {
+		series := Series{}
+		assert.Nil(t, series.AddTag("host", "a"))
+		assert.Nil(t, series.AddField("cpu", int64(i)))
+		if i == 1 {
+			assert.Nil(t, series.AddField("memory", uint64(1024)))
+		}
+		assert.Nil(t, series.SetTimestamp(time.Now()))
+		assert.Nil(t, metric.AddSeries(series))
+	}
+
+	coerced, _, err := schema.validate("monitor", metric, true)
+	assert.Nil(t, err)
+	cpu, _ := coerced.GetSeries()[1].Get("cpu")
+	assert.Equal(t, float64(1), cpu)
+	memory, _ := coerced.GetSeries()[1].Get("memory")
+	assert.Equal(t, uint32(1024), memory)
+	_, ok := coerced.GetSeries()[0].Get("memory")
+	assert.False(t, ok)
+	assert.Equal(t, greptimepb.ColumnDataType_FLOAT64, coerced.columns["cpu"].typ)
+
+	// the metric of the caller is not modified
+	cpu, _ = metric.GetSeries()[1].Get("cpu")
+	assert.Equal(t, int64(1), cpu)
+
+	// the columns built from the coerced metric
+	columns, err := coerced.intoGreptimeColumn(ZeroTimestampAsIs)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{0, 1}, columns[1].Values.F64Values)
+	assert.Equal(t, []uint32{1024}, columns[2].Values.U32Values)
+
+	// out of range
+	series := Series{}
+	assert.Nil(t, series.AddField("memory", uint64(math.MaxUint32+1)))
+	assert.Nil(t, series.SetTimestamp(time.Now()))
+	assert.Nil(t, metric.AddSeries(series))
+	_, _, err = schema.validate("monitor", metric, true)
+	assert.ErrorContains(t, err, "column memory of table monitor: 4294967296 can not be coerced into UINT32")
+}
+
+func TestCoerceNumber(t *testing.T) {
+	cases := []struct {
+		value    any
+		typ      greptimepb.ColumnDataType
+		expected any
+	}{
+		{int64(1), greptimepb.ColumnDataType_INT8, int8(1)},
+		{int64(128), greptimepb.ColumnDataType_INT8, nil},
+		{uint8(255), greptimepb.ColumnDataType_INT16, int16(255)},
+		{uint64(math.MaxUint64), greptimepb.ColumnDataType_INT64, nil},
+		{int32(-1), greptimepb.ColumnDataType_UINT32, nil},
+		{int64(7), greptimepb.ColumnDataType_UINT64, uint64(7)},
+		{float64(1), greptimepb.ColumnDataType_INT64, nil},
+		{float32(0.5), greptimepb.ColumnDataType_FLOAT64, float64(0.5)},
+		{0.1, greptimepb.ColumnDataType_FLOAT32, nil},
+		{0.5, greptimepb.ColumnDataType_FLOAT32, float32(0.5)},
+		{int64(1 << 53), greptimepb.ColumnDataType_FLOAT64, float64(1 << 53)},
+		{int64(1<<53 + 1), greptimepb.ColumnDataType_FLOAT64, nil},
+		{"1", greptimepb.ColumnDataType_INT64, nil},
+	}
+	for _, c := range cases {
+		v, ok := coerceNumber(c.value, c.typ)
+		assert.Equal(t, c.expected != nil, ok, "%v into %s", c.value, c.typ)
+		assert.Equal(t, c.expected, v, "%v into %s", c.value, c.typ)
+	}
+}
+
+func TestParseDataType(t *testing.T) {
+	for name, expected := range map[string]greptimepb.ColumnDataType{
+		"TimestampMillisecond": greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND,
+		"timestamp(9)":         greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND,
+		"UInt64":               greptimepb.ColumnDataType_UINT64,
+		"Double":               greptimepb.ColumnDataType_FLOAT64,
+		"String":               greptimepb.ColumnDataType_STRING,
+	} {
+		typ, ok := parseDataType(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, expected, typ, name)
+	}
+
+	_, ok := parseDataType("Date")
+	assert.False(t, ok)
+}
+
+func TestSchemaCache(t *testing.T) {
+	cache := schemaCache{}
+	_, ok := cache.get("public.monitor", 0)
+	assert.False(t, ok)
+
+	cache.set("public.monitor", &tableSchema{fetchedAt: time.Now().Add(-time.Hour)})
+	_, ok = cache.get("public.monitor", 0)
+	assert.True(t, ok)
+	_, ok = cache.get("public.monitor", time.Minute)
+	assert.False(t, ok)
+
+	cache.invalidate("public.monitor")
+	_, ok = cache.get("public.monitor", 0)
+	assert.False(t, ok)
+}
@@ -16,6 +16,7 @@ package greptime
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
@@ -44,10 +45,17 @@ func checkColumnEquality(key string, col1, col2 column) error {
 //
 // you do not need to create schema in advance, it will be created based on Series.
 // But once the schema is created, [Client] has no ability to alert it.
+//
+// The names of columns are converted into snake_case by default, see
+// [Series.SetNamingPolicy].
 type Series struct {
+	naming  NamingPolicy
 	orders  []string
 	columns map[string]column
 	vals    map[string]any
+	// names are the names as they are added, which are only kept if different
+	// from the keys, so that [Metric] can normalize them via its own policy
+	names map[string]string
 
 	timestamp time.Time // required for inserting
 }
@@ -199,8 +207,19 @@ func (s *Series) GetTimestamp(key string) (time.Time, bool) {
 	return v, ok
 }
 
+// SetNamingPolicy helps to specify how the names of columns are normalized, it
+// MUST be set before adding columns. If it is not set, the policy of [Metric]
+// is used when the Series is added into the Metric, which is snake_case by default.
+func (s *Series) SetNamingPolicy(naming NamingPolicy) error {
+	if len(s.orders) > 0 {
+		return ErrNamingPolicyAfterColumns
+	}
+	s.naming = naming
+	return nil
+}
+
 func (s *Series) add(name string, val any, semantic greptimepb.SemanticType) error {
-	key, err := toColumnName(name)
+	key, err := s.naming.normalize(name)
 	if err != nil {
 		return err
 	}
@@ -218,23 +237,185 @@ func (s *Series) add(name string, val any, semantic greptimepb.SemanticType) err
 		typ:      v.typ,
 		semantic: semantic,
 	}
-	if col, seen := s.columns[key]; seen {
+	col, seen := s.columns[key]
+	if seen {
 		if err := checkColumnEquality(key, col, newCol); err != nil {
 			return err
 		}
+	} else {
+		s.orders = append(s.orders, key)
 	}
 	s.columns[key] = newCol
-	s.orders = append(s.orders, key)
 
 	if s.vals == nil {
 		s.vals = map[string]any{}
 	}
 	s.vals[key] = v.val
 
+	if name = strings.TrimSpace(name); name != key {
+		if s.names == nil {
+			s.names = map[string]string{}
+		}
+		s.names[key] = name
+	} else {
+		delete(s.names, key)
+	}
+
 	return nil
 }
 
-// AddTag prepare tag column, and old value will be replaced if same tag is set.
+// Remove helps to remove the tag or field of the key, the same key as [Series.Get].
+// It returns false if the key is absent.
+func (s *Series) Remove(key string) bool {
+	if _, exist := s.columns[key]; !exist {
+		return false
+	}
+
+	delete(s.columns, key)
+	delete(s.vals, key)
+	delete(s.names, key)
+	for i, k := range s.orders {
+		if k == key {
+			s.orders = append(s.orders[:i:i], s.orders[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Clone helps to copy the Series, so that the copy can be modified without
+// affecting the origin. The values of []byte are shared.
+func (s *Series) Clone() Series {
+	clone := Series{
+		naming:    s.naming,
+		orders:    append([]string(nil), s.orders...),
+		columns:   make(map[string]column, len(s.columns)),
+		vals:      make(map[string]any, len(s.vals)),
+		timestamp: s.timestamp,
+	}
+	for key, col := range s.columns {
+		clone.columns[key] = col
+	}
+	for key, val := range s.vals {
+		clone.vals[key] = val
+	}
+	if len(s.names) > 0 {
+		clone.names = make(map[string]string, len(s.names))
+		for key, name := range s.names {
+			clone.names[key] = name
+		}
+	}
+	return clone
+}
+
+// check reports the problems of the Series itself, like a value not matching
+// the type of its column, which can not happen unless the Series is modified
+// after being copied, since the maps are shared between the copies
+func (s *Series) check() []error {
+	var errs []error
+	seen := make(map[string]bool, len(s.orders))
+	for _, key := range s.orders {
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("column %q is duplicated", key))
+			continue
+		}
+		seen[key] = true
+
+		col, exist := s.columns[key]
+		if !exist {
+			errs = append(errs, fmt.Errorf("column %q has no type", key))
+			continue
+		}
+		if val, exist := s.vals[key]; exist && !isValueOf(val, col.typ) {
+			errs = append(errs, fmt.Errorf("value of column %q is %T, but %s is expected", key, val, col.typ))
+		}
+	}
+	if len(seen) != len(s.columns) {
+		errs = append(errs, fmt.Errorf("%d columns are typed, but %d are ordered", len(s.columns), len(seen)))
+	}
+	for key := range s.vals {
+		if !seen[key] {
+			errs = append(errs, fmt.Errorf("value of column %q is not ordered", key))
+		}
+	}
+	return errs
+}
+
+// isValueOf checks whether the value is of the type setColumn accepts for the data type
+func isValueOf(val any, typ greptimepb.ColumnDataType) bool {
+	var ok bool
+	switch typ {
+	case greptimepb.ColumnDataType_INT8:
+		_, ok = val.(int8)
+	case greptimepb.ColumnDataType_INT16:
+		_, ok = val.(int16)
+	case greptimepb.ColumnDataType_INT32:
+		_, ok = val.(int32)
+	case greptimepb.ColumnDataType_INT64, greptimepb.ColumnDataType_TIMESTAMP_SECOND,
+		greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND, greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND,
+		greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:
+		_, ok = val.(int64)
+	case greptimepb.ColumnDataType_UINT8:
+		_, ok = val.(uint8)
+	case greptimepb.ColumnDataType_UINT16:
+		_, ok = val.(uint16)
+	case greptimepb.ColumnDataType_UINT32:
+		_, ok = val.(uint32)
+	case greptimepb.ColumnDataType_UINT64:
+		_, ok = val.(uint64)
+	case greptimepb.ColumnDataType_FLOAT32:
+		_, ok = val.(float32)
+	case greptimepb.ColumnDataType_FLOAT64:
+		_, ok = val.(float64)
+	case greptimepb.ColumnDataType_BOOLEAN:
+		_, ok = val.(bool)
+	case greptimepb.ColumnDataType_STRING:
+		_, ok = val.(string)
+	case greptimepb.ColumnDataType_BINARY:
+		_, ok = val.([]byte)
+	case greptimepb.ColumnDataType_DATETIME:
+		_, ok = val.(time.Time)
+	}
+	return ok
+}
+
+// rename returns a copy of the Series whose names of columns are normalized via
+// the policy again from the names as they are added. It fails if two columns are
+// normalized into the same name, so that no value is dropped.
+func (s *Series) rename(naming NamingPolicy) (Series, error) {
+	renamed := Series{
+		naming:    naming,
+		orders:    make([]string, 0, len(s.orders)),
+		columns:   make(map[string]column, len(s.columns)),
+		vals:      make(map[string]any, len(s.vals)),
+		timestamp: s.timestamp,
+	}
+	origins := make(map[string]string, len(s.orders))
+	for _, key := range s.orders {
+		name, ok := s.names[key]
+		if !ok {
+			name = key
+		}
+		newKey, err := naming.normalize(name)
+		if err != nil {
+			return Series{}, err
+		}
+		if origin, seen := origins[newKey]; seen {
+			return Series{}, fmt.Errorf("column '%s' and '%s' are both named '%s' by the naming policy", origin, name, newKey)
+		}
+		origins[newKey] = name
+		renamed.orders = append(renamed.orders, newKey)
+		renamed.columns[newKey] = s.columns[key]
+		if val, exist := s.vals[key]; exist {
+			renamed.vals[newKey] = val
+		}
+	}
+	return renamed, nil
+}
+
+// AddTag prepare tag column, and old value will be replaced if same tag is set,
+// the order of the tag is kept. It is rejected if the key has been added as a
+// field or a tag of different type.
 // the length of key CAN NOT be longer than 100.
 // If you want to constrain the column type, you can directly use like:
 //   - [Series.AddFloatTag]
@@ -286,7 +467,9 @@ func (s *Series) AddBytesTag(key string, val []byte) error {
 	return s.AddTag(key, val)
 }
 
-// AddField prepare field column, and old value will be replaced if same field is set.
+// AddField prepare field column, and old value will be replaced if same field is set,
+// the order of the field is kept. It is rejected if the key has been added as a
+// tag or a field of different type.
 // the length of key CAN NOT be longer than 100
 func (s *Series) AddField(key string, val any) error {
 	return s.add(key, val, greptimepb.SemanticType_FIELD)
@@ -339,3 +522,9 @@ func (s *Series) SetTimestamp(t time.Time) error {
 	s.timestamp = t
 	return nil
 }
+
+// Timestamp helps to get the timestamp set via [Series.SetTimestamp]. The Series
+// of the query result has no such timestamp, use [Series.GetTimestamp] instead.
+func (s *Series) Timestamp() time.Time {
+	return s.timestamp
+}
@@ -170,3 +170,64 @@ func TestSeriesAddTypedTagAndField(t *testing.T) {
 	assert.Nil(t, err)
 
 }
+
+func TestSeriesDuplicateKey(t *testing.T) {
+	s := Series{}
+	assert.Nil(t, s.AddTag("host", "a"))
+	assert.Nil(t, s.AddField("cpu", 0.5))
+	assert.Nil(t, s.AddTag("Host", "b"))
+	assert.Equal(t, []string{"host", "cpu"}, s.GetTagsAndFields())
+	host, _ := s.GetString("host")
+	assert.Equal(t, "b", host)
+
+	// rejected and nothing changed
+	assert.NotNil(t, s.AddField("host", "c"))
+	assert.NotNil(t, s.AddTag("host", 1))
+	assert.Equal(t, []string{"host", "cpu"}, s.GetTagsAndFields())
+	host, _ = s.GetString("host")
+	assert.Equal(t, "b", host)
+	assert.Empty(t, s.check())
+}
+
+func TestSeriesRemove(t *testing.T) {
+	s := Series{}
+	assert.Nil(t, s.AddTag("host", "a"))
+	assert.Nil(t, s.AddField("cpu", 0.5))
+	assert.Nil(t, s.AddField("memory", uint64(1024)))
+
+	assert.True(t, s.Remove("cpu"))
+	assert.False(t, s.Remove("cpu"))
+	assert.Equal(t, []string{"host", "memory"}, s.GetTagsAndFields())
+	_, ok := s.Get("cpu")
+	assert.False(t, ok)
+
+	// added again as a different type
+	assert.Nil(t, s.AddTag("cpu", "high"))
+	assert.Equal(t, []string{"host", "memory", "cpu"}, s.GetTagsAndFields())
+	assert.Empty(t, s.check())
+}
+
+func TestSeriesClone(t *testing.T) {
+	s := Series{}
+	assert.Nil(t, s.AddTag("HostName", "a"))
+	assert.Nil(t, s.AddField("cpu", 0.5))
+	timestamp := time.UnixMilli(1)
+	assert.Nil(t, s.SetTimestamp(timestamp))
+
+	clone := s.Clone()
+	assert.Nil(t, clone.AddField("cpu", 0.8))
+	assert.Nil(t, clone.AddField("memory", uint64(1024)))
+	assert.True(t, clone.Remove("host_name"))
+
+	assert.Equal(t, []string{"host_name", "cpu"}, s.GetTagsAndFields())
+	cpu, _ := s.GetFloat("cpu")
+	assert.Equal(t, 0.5, cpu)
+	assert.Equal(t, []string{"cpu", "memory"}, clone.GetTagsAndFields())
+	assert.Equal(t, timestamp, clone.Timestamp())
+
+	// the names are kept for the naming policy of Metric
+	metric := Metric{}
+	assert.Nil(t, metric.SetNamingPolicy(NamingPreserve))
+	assert.Nil(t, metric.AddSeries(s.Clone()))
+	assert.Equal(t, []string{"HostName", "cpu"}, metric.GetTagsAndFields())
+}
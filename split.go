@@ -0,0 +1,303 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// InsertRange is the rows [From, To) of the Index-th insert in [InsertsRequest]
+type InsertRange struct {
+	Index int
+	Table string
+	From  int
+	To    int
+}
+
+func (r InsertRange) String() string {
+	return fmt.Sprintf("%s[%d:%d]", r.Table, r.From, r.To)
+}
+
+// PartialInsertError is returned by [Client.Insert] if the request is split
+// into multiple requests, see [Config.WithMaxRequestSize], and one of them
+// fails. The requests are sent in order, and the ones after the failed request
+// are not sent, so that the rows can be retried precisely:
+//
+//	var e *greptime.PartialInsertError
+//	if errors.As(err, &e) {
+//		for _, r := range e.Failed {
+//			... // retry the rows [r.From, r.To) of the r.Index-th insert
+//		}
+//	}
+//
+// Err is the error of the failed request, which can be retrieved via errors.As,
+// like [*Error].
+type PartialInsertError struct {
+	AffectedRows uint32        // the rows inserted by the succeeded requests
+	Inserted     []InsertRange // the rows inserted
+	Failed       []InsertRange // the rows failed or not sent
+	Err          error
+}
+
+func (e *PartialInsertError) Error() string {
+	failed := make([]string, 0, len(e.Failed))
+	for _, r := range e.Failed {
+		failed = append(failed, r.String())
+	}
+	return fmt.Sprintf("%d rows are inserted, but %s are not: %v", e.AffectedRows, strings.Join(failed, ", "), e.Err)
+}
+
+func (e *PartialInsertError) Unwrap() error {
+	return e.Err
+}
+
+// insertPart is the rows of an insert in a split request
+type insertPart struct {
+	InsertRange
+	req *greptimepb.InsertRequest
+}
+
+// splitRequest splits the insert request into the requests no larger than
+// maxSize bytes. The inserts are packed in order, and the insert too large for
+// one request is split by row ranges.
+func splitRequest(request *greptimepb.GreptimeRequest, maxSize int) ([]*greptimepb.GreptimeRequest, [][]InsertRange, error) {
+	empty := &greptimepb.GreptimeRequest{
+		Header:  request.GetHeader(),
+		Request: &greptimepb.GreptimeRequest_Inserts{Inserts: &greptimepb.InsertRequests{}},
+	}
+	// the length prefixes of the nested messages are at most 5 bytes for each
+	budget := maxSize - proto.Size(empty) - 10
+	if budget <= 0 {
+		return nil, nil, fmt.Errorf("%w: %d bytes leave no room for the inserts", ErrRequestTooLarge, maxSize)
+	}
+
+	var parts []insertPart
+	for i, insert := range request.GetInserts().GetInserts() {
+		whole := insertPart{
+			InsertRange: InsertRange{Index: i, Table: insert.GetTableName(), To: int(insert.GetRowCount())},
+			req:         insert,
+		}
+		split, err := splitInsert(whole, budget)
+		if err != nil {
+			return nil, nil, err
+		}
+		parts = append(parts, split...)
+	}
+
+	var requests []*greptimepb.GreptimeRequest
+	var ranges [][]InsertRange
+	var inserts []*greptimepb.InsertRequest
+	var rs []InsertRange
+	size := 0
+	flush := func() {
+		if len(inserts) == 0 {
+			return
+		}
+		requests = append(requests, &greptimepb.GreptimeRequest{
+			Header:  request.GetHeader(),
+			Request: &greptimepb.GreptimeRequest_Inserts{Inserts: &greptimepb.InsertRequests{Inserts: inserts}},
+		})
+		ranges = append(ranges, rs)
+		inserts, rs, size = nil, nil, 0
+	}
+	for _, part := range parts {
+		n := sizeOfInsert(part.req)
+		if size+n > budget {
+			flush()
+		}
+		inserts, rs, size = append(inserts, part.req), append(rs, part.InsertRange), size+n
+	}
+	flush()
+	return requests, ranges, nil
+}
+
+// sizeOfInsert is the encoded size of the insert in InsertRequests, including
+// the tag and length prefix
+func sizeOfInsert(insert *greptimepb.InsertRequest) int {
+	return protowire.SizeBytes(proto.Size(insert)) + 1
+}
+
+// splitInsert splits the insert by row ranges, so that each part is no larger
+// than budget bytes
+func splitInsert(part insertPart, budget int) ([]insertPart, error) {
+	size := sizeOfInsert(part.req)
+	if size <= budget {
+		return []insertPart{part}, nil
+	}
+
+	rows := part.To - part.From
+	if rows <= 1 {
+		return nil, fmt.Errorf("%w: the row %d of table %s is %d bytes", ErrRequestTooLarge, part.From, part.Table, size)
+	}
+
+	n := (size + budget - 1) / budget
+	step := (rows + n - 1) / n
+	var parts []insertPart
+	for from := 0; from < rows; from += step {
+		to := from + step
+		if to > rows {
+			to = rows
+		}
+		req, err := sliceInsert(part.req, from, to)
+		if err != nil {
+			return nil, err
+		}
+		sub := insertPart{
+			InsertRange: InsertRange{Index: part.Index, Table: part.Table, From: part.From + from, To: part.From + to},
+			req:         req,
+		}
+		split, err := splitInsert(sub, budget)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, split...)
+	}
+	return parts, nil
+}
+
+// sliceInsert returns the rows [from, to) of the insert
+func sliceInsert(insert *greptimepb.InsertRequest, from, to int) (*greptimepb.InsertRequest, error) {
+	columns := make([]*greptimepb.Column, 0, len(insert.GetColumns()))
+	for _, col := range insert.GetColumns() {
+		// the values are present only for the rows not NULL
+		start, end := 0, 0
+		nullMask := mask{}
+		for row := 0; row < to; row++ {
+			null := isNullAt(col.GetNullMask(), row)
+			switch {
+			case row < from:
+				if !null {
+					start++
+				}
+			case null:
+				nullMask.set(uint(row - from))
+			default:
+				end++
+			}
+		}
+		end += start
+
+		values, err := sliceColumnValues(col, start, end)
+		if err != nil {
+			return nil, err
+		}
+		b, err := nullMask.shrink(nullMaskByteSize(to - from))
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, &greptimepb.Column{
+			ColumnName:   col.GetColumnName(),
+			SemanticType: col.GetSemanticType(),
+			Datatype:     col.GetDatatype(),
+			Values:       values,
+			NullMask:     b,
+		})
+	}
+
+	return &greptimepb.InsertRequest{
+		TableName: insert.GetTableName(),
+		Columns:   columns,
+		RowCount:  uint32(to - from),
+	}, nil
+}
+
+// sliceColumnValues returns the values [from, to) of the column, which are of
+// the data types setColumn supports
+func sliceColumnValues(col *greptimepb.Column, from, to int) (*greptimepb.Column_Values, error) {
+	v := col.GetValues()
+	switch col.GetDatatype() {
+	case greptimepb.ColumnDataType_INT8:
+		return &greptimepb.Column_Values{I8Values: v.GetI8Values()[from:to]}, nil
+	case greptimepb.ColumnDataType_INT16:
+		return &greptimepb.Column_Values{I16Values: v.GetI16Values()[from:to]}, nil
+	case greptimepb.ColumnDataType_INT32:
+		return &greptimepb.Column_Values{I32Values: v.GetI32Values()[from:to]}, nil
+	case greptimepb.ColumnDataType_INT64:
+		return &greptimepb.Column_Values{I64Values: v.GetI64Values()[from:to]}, nil
+	case greptimepb.ColumnDataType_UINT8:
+		return &greptimepb.Column_Values{U8Values: v.GetU8Values()[from:to]}, nil
+	case greptimepb.ColumnDataType_UINT16:
+		return &greptimepb.Column_Values{U16Values: v.GetU16Values()[from:to]}, nil
+	case greptimepb.ColumnDataType_UINT32:
+		return &greptimepb.Column_Values{U32Values: v.GetU32Values()[from:to]}, nil
+	case greptimepb.ColumnDataType_UINT64:
+		return &greptimepb.Column_Values{U64Values: v.GetU64Values()[from:to]}, nil
+	case greptimepb.ColumnDataType_FLOAT32:
+		return &greptimepb.Column_Values{F32Values: v.GetF32Values()[from:to]}, nil
+	case greptimepb.ColumnDataType_FLOAT64:
+		return &greptimepb.Column_Values{F64Values: v.GetF64Values()[from:to]}, nil
+	case greptimepb.ColumnDataType_BOOLEAN:
+		return &greptimepb.Column_Values{BoolValues: v.GetBoolValues()[from:to]}, nil
+	case greptimepb.ColumnDataType_STRING:
+		return &greptimepb.Column_Values{StringValues: v.GetStringValues()[from:to]}, nil
+	case greptimepb.ColumnDataType_BINARY:
+		return &greptimepb.Column_Values{BinaryValues: v.GetBinaryValues()[from:to]}, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_SECOND:
+		return &greptimepb.Column_Values{TimestampSecondValues: v.GetTimestampSecondValues()[from:to]}, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND:
+		return &greptimepb.Column_Values{TimestampMillisecondValues: v.GetTimestampMillisecondValues()[from:to]}, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND:
+		return &greptimepb.Column_Values{TimestampMicrosecondValues: v.GetTimestampMicrosecondValues()[from:to]}, nil
+	case greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:
+		return &greptimepb.Column_Values{TimestampNanosecondValues: v.GetTimestampNanosecondValues()[from:to]}, nil
+	default:
+		return nil, fmt.Errorf("unsupported data type %s of column %q", col.GetDatatype(), col.GetColumnName())
+	}
+}
+
+// insertSplit sends the requests split from the request in order, and stops at
+// the first failure
+func (c *Client) insertSplit(ctx context.Context, requests []*greptimepb.GreptimeRequest, ranges [][]InsertRange) (*greptimepb.GreptimeResponse, error) {
+	c.cfg.getLogger().Debug("insert request is split", "requests", len(requests))
+
+	var rows uint32
+	var header *greptimepb.ResponseHeader
+	for i, request := range requests {
+		resp, err := c.handle(ctx, OperationInsert, request)
+		if err == nil {
+			err = ParseRespHeader(resp).Err()
+		}
+		if err != nil {
+			if e, ok := err.(*Error); ok && len(ranges[i]) == 1 {
+				e.Table = ranges[i][0].Table
+			}
+			partial := &PartialInsertError{AffectedRows: rows, Err: err}
+			for j, rs := range ranges {
+				if j < i {
+					partial.Inserted = append(partial.Inserted, rs...)
+				} else {
+					partial.Failed = append(partial.Failed, rs...)
+				}
+			}
+			return newAffectedRowsResponse(resp.GetHeader(), rows), partial
+		}
+		rows += resp.GetAffectedRows().GetValue()
+		header = resp.GetHeader()
+	}
+	return newAffectedRowsResponse(header, rows), nil
+}
+
+func newAffectedRowsResponse(header *greptimepb.ResponseHeader, rows uint32) *greptimepb.GreptimeResponse {
+	return &greptimepb.GreptimeResponse{
+		Header:   header,
+		Response: &greptimepb.GreptimeResponse_AffectedRows{AffectedRows: &greptimepb.AffectedRows{Value: rows}},
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func splitMetric(t *testing.T, rows int) Metric {
+	metric := Metric{}
+	for i := 0; i < rows; i++ {
+		series := Series{}
+		assert.Nil(t, series.AddTag("host", fmt.Sprintf("127.0.0.%d", i)))
+		if i%3 != 0 {
+			assert.Nil(t, series.AddField("cpu", float64(i)))
+		}
+		if i%4 == 0 {
+			assert.Nil(t, series.AddField("payload", []byte(strings.Repeat("x", i))))
+		}
+		assert.Nil(t, series.SetTimestamp(time.UnixMilli(int64(i))))
+		assert.Nil(t, metric.AddSeries(series))
+	}
+	return metric
+}
+
+func TestSliceInsert(t *testing.T) {
+	metric := splitMetric(t, 30)
+	req, err := (&InsertRequest{}).WithTable("monitor").WithMetric(metric).build(&Config{})
+	assert.Nil(t, err)
+
+	for _, r := range [][2]int{{0, 30}, {0, 1}, {7, 19}, {29, 30}} {
+		sliced, err := sliceInsert(req, r[0], r[1])
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(r[1]-r[0]), sliced.RowCount)
+
+		decoded, err := DecodeInsertRequest(sliced)
+		assert.Nil(t, err)
+		decodedMetric := decoded.GetMetric()
+		for i, series := range decodedMetric.GetSeries() {
+			expected := metric.GetSeries()[r[0]+i]
+			assert.Equal(t, expected.vals, series.vals)
+			assert.True(t, expected.Timestamp().Equal(series.Timestamp()))
+		}
+	}
+
+	whole, err := sliceInsert(req, 0, 30)
+	assert.Nil(t, err)
+	assert.True(t, proto.Equal(req, whole))
+}
+
+func TestSplitRequest(t *testing.T) {
+	inserts := InsertsRequest{}
+	inserts.Append(*(&InsertRequest{}).WithTable("small").WithMetric(splitMetric(t, 2)))
+	inserts.Append(*(&InsertRequest{}).WithTable("monitor").WithMetric(splitMetric(t, 100)))
+	inserts.Append(*(&InsertRequest{}).WithTable("tiny").WithMetric(splitMetric(t, 1)))
+	request, err := inserts.WithDatabase("public").build(&Config{})
+	assert.Nil(t, err)
+
+	maxSize := proto.Size(request) / 4
+	requests, ranges, err := splitRequest(request, maxSize)
+	assert.Nil(t, err)
+	assert.Greater(t, len(requests), 4)
+	assert.Len(t, ranges, len(requests))
+
+	// the rows are covered in order
+	var covered []InsertRange
+	for i, r := range requests {
+		assert.LessOrEqual(t, proto.Size(r), maxSize)
+		assert.Equal(t, "public", r.GetHeader().GetDbname())
+		assert.Len(t, r.GetInserts().GetInserts(), len(ranges[i]))
+		for j, insert := range r.GetInserts().GetInserts() {
+			assert.Equal(t, ranges[i][j].Table, insert.GetTableName())
+			assert.Equal(t, uint32(ranges[i][j].To-ranges[i][j].From), insert.GetRowCount())
+		}
+		covered = append(covered, ranges[i]...)
+	}
+	assert.Equal(t, InsertRange{Index: 0, Table: "small", From: 0, To: 2}, covered[0])
+	assert.Equal(t, InsertRange{Index: 2, Table: "tiny", From: 0, To: 1}, covered[len(covered)-1])
+	next := 0
+	for _, r := range covered[1 : len(covered)-1] {
+		assert.Equal(t, 1, r.Index)
+		assert.Equal(t, next, r.From)
+		next = r.To
+	}
+	assert.Equal(t, 100, next)
+
+	// the row is larger than the size
+	_, _, err = splitRequest(request, 100)
+	assert.ErrorIs(t, err, ErrRequestTooLarge)
+
+	// no room for the inserts at all
+	empty := proto.Size(&greptimepb.GreptimeRequest{
+		Header:  request.GetHeader(),
+		Request: &greptimepb.GreptimeRequest_Inserts{Inserts: &greptimepb.InsertRequests{}},
+	})
+	for _, size := range []int{0, empty, empty + 10} {
+		_, _, err = splitRequest(request, size)
+		assert.ErrorIs(t, err, ErrRequestTooLarge)
+	}
+	_, _, err = splitRequest(request, empty+11)
+	assert.ErrorIs(t, err, ErrRequestTooLarge)
+}
+
+func TestSplitRequestLargeBatchNulls(t *testing.T) {
+	metric := Metric{}
+	for i := 0; i < 2000; i++ {
+		series := Series{}
+		assert.Nil(t, series.AddTag("host", "127.0.0.1"))
+		// NULL only near the start and at the end
+		if i != 0 && i != 1999 {
+			assert.Nil(t, series.AddField("cpu", float64(i)))
+		}
+		assert.Nil(t, series.SetTimestamp(time.UnixMilli(int64(i))))
+		assert.Nil(t, metric.AddSeries(series))
+	}
+	inserts := InsertsRequest{}
+	inserts.Append(*(&InsertRequest{}).WithTable("monitor").WithMetric(metric))
+	request, err := inserts.WithDatabase("public").build(&Config{})
+	assert.Nil(t, err)
+
+	requests, ranges, err := splitRequest(request, 20000)
+	assert.Nil(t, err)
+	assert.Greater(t, len(requests), 1)
+	assert.Greater(t, ranges[0][0].To, 512)
+
+	next := 0
+	for i, r := range requests {
+		assert.LessOrEqual(t, proto.Size(r), 20000)
+		insert := r.GetInserts().GetInserts()[0]
+		rows := ranges[i][0].To - ranges[i][0].From
+		assert.Equal(t, next, ranges[i][0].From)
+		next = ranges[i][0].To
+
+		decoded, err := DecodeInsertRequest(insert)
+		assert.Nil(t, err)
+		decodedMetric := decoded.GetMetric()
+		assert.Len(t, decodedMetric.GetSeries(), rows)
+		for j, series := range decodedMetric.GetSeries() {
+			row := ranges[i][0].From + j
+			_, ok := series.Get("cpu")
+			assert.Equal(t, row != 0 && row != 1999, ok, "row %d", row)
+		}
+	}
+	assert.Equal(t, 2000, next)
+}
+
+func TestPartialInsertError(t *testing.T) {
+	err := error(&PartialInsertError{
+		AffectedRows: 10,
+		Inserted:     []InsertRange{{Index: 0, Table: "monitor", From: 0, To: 10}},
+		Failed:       []InsertRange{{Index: 0, Table: "monitor", From: 10, To: 20}, {Index: 1, Table: "cpu", From: 0, To: 5}},
+		Err:          &Error{Code: StatusRateLimited, Msg: "too many requests"},
+	})
+	assert.Equal(t, "10 rows are inserted, but monitor[10:20], cpu[0:5] are not: code: 6001 (RateLimited), msg: too many requests", err.Error())
+	assert.ErrorIs(t, err, StatusRateLimited)
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+}
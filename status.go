@@ -0,0 +1,167 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// the trailers of the gRPC status greptimedb responds, which carry the status
+// code and message of the failure
+const (
+	headerErrorCode = "x-greptime-err-code"
+	headerErrorMsg  = "x-greptime-err-msg"
+)
+
+// StatusCode is the status code in the response header of greptimedb. It is also
+// an error, so that [Error] can be checked via errors.Is(err, StatusTableNotFound).
+type StatusCode uint32
+
+// the status codes defined by greptimedb
+const (
+	StatusSuccess StatusCode = 0
+
+	// unknown and internal errors
+	StatusUnknown          StatusCode = 1000
+	StatusUnsupported      StatusCode = 1001
+	StatusUnexpected       StatusCode = 1002
+	StatusInternal         StatusCode = 1003
+	StatusInvalidArguments StatusCode = 1004
+	StatusCancelled        StatusCode = 1005
+
+	// errors of SQL parser
+	StatusInvalidSyntax StatusCode = 2000
+
+	// errors of query engine
+	StatusPlanQuery          StatusCode = 3000
+	StatusEngineExecuteQuery StatusCode = 3001
+
+	// errors of catalog and table
+	StatusTableAlreadyExists  StatusCode = 4000
+	StatusTableNotFound       StatusCode = 4001
+	StatusTableColumnNotFound StatusCode = 4002
+	StatusTableColumnExists   StatusCode = 4003
+	StatusDatabaseNotFound    StatusCode = 4004
+	StatusRegionNotFound      StatusCode = 4005
+	StatusRegionAlreadyExists StatusCode = 4006
+	StatusRegionReadonly      StatusCode = 4007
+
+	// errors of storage
+	StatusStorageUnavailable StatusCode = 5000
+
+	// errors of server
+	StatusRuntimeResourcesExhausted StatusCode = 6000
+	StatusRateLimited               StatusCode = 6001
+
+	// errors of authentication and authorization
+	StatusUserNotFound            StatusCode = 7000
+	StatusUnsupportedPasswordType StatusCode = 7001
+	StatusUserPasswordMismatch    StatusCode = 7002
+	StatusAuthHeaderNotFound      StatusCode = 7003
+	StatusInvalidAuthHeader       StatusCode = 7004
+	StatusAccessDenied            StatusCode = 7005
+	StatusPermissionDenied        StatusCode = 7006
+)
+
+var statusNames = map[StatusCode]string{
+	StatusSuccess:                   "Success",
+	StatusUnknown:                   "Unknown",
+	StatusUnsupported:               "Unsupported",
+	StatusUnexpected:                "Unexpected",
+	StatusInternal:                  "Internal",
+	StatusInvalidArguments:          "InvalidArguments",
+	StatusCancelled:                 "Cancelled",
+	StatusInvalidSyntax:             "InvalidSyntax",
+	StatusPlanQuery:                 "PlanQuery",
+	StatusEngineExecuteQuery:        "EngineExecuteQuery",
+	StatusTableAlreadyExists:        "TableAlreadyExists",
+	StatusTableNotFound:             "TableNotFound",
+	StatusTableColumnNotFound:       "TableColumnNotFound",
+	StatusTableColumnExists:         "TableColumnExists",
+	StatusDatabaseNotFound:          "DatabaseNotFound",
+	StatusRegionNotFound:            "RegionNotFound",
+	StatusRegionAlreadyExists:       "RegionAlreadyExists",
+	StatusRegionReadonly:            "RegionReadonly",
+	StatusStorageUnavailable:        "StorageUnavailable",
+	StatusRuntimeResourcesExhausted: "RuntimeResourcesExhausted",
+	StatusRateLimited:               "RateLimited",
+	StatusUserNotFound:              "UserNotFound",
+	StatusUnsupportedPasswordType:   "UnsupportedPasswordType",
+	StatusUserPasswordMismatch:      "UserPasswordMismatch",
+	StatusAuthHeaderNotFound:        "AuthHeaderNotFound",
+	StatusInvalidAuthHeader:         "InvalidAuthHeader",
+	StatusAccessDenied:              "AccessDenied",
+	StatusPermissionDenied:          "PermissionDenied",
+}
+
+// String returns the name of the status code, like `TableNotFound`
+func (c StatusCode) String() string {
+	if name, ok := statusNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("StatusCode(%d)", uint32(c))
+}
+
+func (c StatusCode) Error() string {
+	return c.String()
+}
+
+// IsRetryable returns true if the request failed with the code may succeed if
+// it is retried later, like the server is busy or the storage is unavailable
+func (c StatusCode) IsRetryable() bool {
+	switch c {
+	case StatusInternal, StatusStorageUnavailable, StatusRuntimeResourcesExhausted, StatusRateLimited:
+		return true
+	default:
+		return false
+	}
+}
+
+// statusErrorOf converts the gRPC status error into [*Error], so that the failed
+// queries can be checked like the failed inserts. Only the status code greptimedb
+// responds in the trailers is trusted, since the gRPC code, like NotFound, can not
+// tell which one is missing. The other errors are returned as they are.
+func statusErrorOf(err error, trailer metadata.MD) error {
+	var e *Error
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	values := trailer.Get(headerErrorCode)
+	if len(values) == 0 {
+		return err
+	}
+	code, perr := strconv.ParseUint(values[0], 10, 32)
+	if perr != nil || code == 0 {
+		return err
+	}
+
+	msg := st.Message()
+	if values := trailer.Get(headerErrorMsg); len(values) > 0 && len(values[0]) > 0 {
+		msg = values[0]
+	}
+	e = newError(uint32(code), msg)
+	e.status = st
+	return e
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, "TableNotFound", StatusTableNotFound.String())
+	assert.Equal(t, "PermissionDenied", StatusPermissionDenied.Error())
+	assert.Equal(t, "StatusCode(9999)", StatusCode(9999).String())
+
+	assert.True(t, StatusRateLimited.IsRetryable())
+	assert.True(t, StatusStorageUnavailable.IsRetryable())
+	assert.False(t, StatusInvalidSyntax.IsRetryable())
+	assert.False(t, StatusCode(9999).IsRetryable())
+}
+
+func TestError(t *testing.T) {
+	assert.Nil(t, newError(0, ""))
+
+	e := newError(uint32(StatusTableColumnNotFound), "column not found")
+	e.Table, e.Column = "monitor", "host"
+	assert.EqualError(t, e, "code: 4002 (TableColumnNotFound), msg: column not found, table: monitor, column: host")
+	assert.False(t, e.Retryable)
+
+	err := fmt.Errorf("failed to execute, %w", e)
+	assert.ErrorIs(t, err, StatusTableColumnNotFound)
+	assert.ErrorIs(t, err, &Error{Code: StatusTableColumnNotFound})
+	assert.NotErrorIs(t, err, StatusTableNotFound)
+	assert.NotErrorIs(t, err, ErrEmptyTable)
+
+	var target *Error
+	assert.True(t, errors.As(err, &target))
+	assert.Equal(t, "host", target.Column)
+
+	assert.True(t, newError(uint32(StatusRateLimited), "").Retryable)
+}
+
+func TestInsertError(t *testing.T) {
+	cfg := NewCfg("localhost").WithDatabase("public")
+	greptimeClient := &fakeGreptimeClient{
+		header: &greptimepb.ResponseHeader{Status: &greptimepb.Status{StatusCode: uint32(StatusTableNotFound), ErrMsg: "table not found"}},
+	}
+	client := &Client{cfg: cfg, greptimeClient: greptimeClient}
+
+	series := Series{}
+	series.AddTag("host", "localhost")
+	series.AddField("cpu", 0.5)
+	series.SetTimestamp(time.Now())
+	metric := Metric{}
+	metric.AddSeries(series)
+
+	inserts := InsertsRequest{}
+	inserts.Append(*(&InsertRequest{}).WithTable("monitor").WithMetric(metric))
+	resp, err := client.Insert(context.Background(), inserts)
+	assert.NotNil(t, resp)
+	assert.ErrorIs(t, err, StatusTableNotFound)
+
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "monitor", e.Table)
+	assert.Equal(t, "table not found", e.Msg)
+
+	// the table is unknown if multiple tables are inserted
+	inserts.Append(*(&InsertRequest{}).WithTable("cpu").WithMetric(metric))
+	_, err = client.Insert(context.Background(), inserts)
+	assert.True(t, errors.As(err, &e))
+	assert.Empty(t, e.Table)
+
+	greptimeClient.header = &greptimepb.ResponseHeader{Status: &greptimepb.Status{}}
+	_, err = client.Insert(context.Background(), inserts)
+	assert.Nil(t, err)
+}
+
+func TestStatusErrorOf(t *testing.T) {
+	assert.Nil(t, statusErrorOf(nil, nil))
+
+	// the status code in the trailers is converted
+	var e *Error
+	trailer := metadata.Pairs(headerErrorCode, "6001", headerErrorMsg, "too many requests")
+	err := statusErrorOf(status.Error(codes.ResourceExhausted, "rate limited"), trailer)
+	assert.ErrorIs(t, err, StatusRateLimited)
+	assert.True(t, errors.As(err, &e))
+	assert.True(t, e.Retryable)
+	assert.Equal(t, "too many requests", e.Msg)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// the gRPC code can not tell which one is not found, so it is kept without trailers
+	notFound := status.Error(codes.NotFound, "database foo not found")
+	assert.Same(t, notFound, statusErrorOf(notFound, nil))
+	assert.False(t, errors.Is(statusErrorOf(notFound, nil), StatusTableNotFound))
+	exists := status.Error(codes.AlreadyExists, "column cpu already exists")
+	assert.Same(t, exists, statusErrorOf(exists, metadata.Pairs(headerErrorMsg, "column cpu already exists")))
+
+	// the failures of the transport and the other errors are kept
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	assert.Same(t, unavailable, statusErrorOf(unavailable, nil))
+	plain := errors.New("failed")
+	assert.Same(t, plain, statusErrorOf(plain, nil))
+	wrapped := fmt.Errorf("wrapped: %w", &Error{Code: StatusInvalidSyntax})
+	assert.Same(t, wrapped, statusErrorOf(wrapped, nil))
+}
+
+func TestQueryError(t *testing.T) {
+	cfg := NewCfg("localhost").WithDatabase("public")
+	client := &Client{cfg: cfg, greptimeClient: &statusGreptimeClient{}}
+
+	_, err := client.execute(context.Background(), *NewQueryRequest().WithSql("ALTER TABLE monitor ADD COLUMN cpu DOUBLE"))
+	assert.ErrorIs(t, err, StatusTableNotFound)
+}
+
+// statusGreptimeClient fails the requests with the gRPC status like greptimedb
+type statusGreptimeClient struct {
+	greptimepb.GreptimeDatabaseClient
+}
+
+func (c *statusGreptimeClient) Handle(ctx context.Context, in *greptimepb.GreptimeRequest, opts ...grpc.CallOption) (*greptimepb.GreptimeResponse, error) {
+	for _, opt := range opts {
+		if trailer, ok := opt.(grpc.TrailerCallOption); ok {
+			*trailer.TrailerAddr = metadata.Pairs(headerErrorCode, "4001", headerErrorMsg, "table not found")
+		}
+	}
+	return nil, status.Error(codes.NotFound, "table not found")
+}
@@ -19,28 +19,40 @@ import (
 
 	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 )
 
 // StreamClient is only for inserting
 type StreamClient struct {
-	client greptimepb.GreptimeDatabase_HandleRequestsClient
-	cfg    *Config
+	client  greptimepb.GreptimeDatabase_HandleRequestsClient
+	cfg     *Config
+	metrics *clientMetrics
+	tracer  *clientTracer
 }
 
 // NewStreamClient helps to create a stream insert client.
 // If Client has performance issue, you can try the stream client.
 func NewStreamClient(cfg *Config) (*StreamClient, error) {
+	metrics, err := cfg.newMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	logger := cfg.getLogger()
 	conn, err := grpc.Dial(cfg.getGRPCAddr(), cfg.DialOptions...)
 	if err != nil {
+		logger.Error("failed to connect", "addr", cfg.getGRPCAddr(), "error", err)
 		return nil, err
 	}
 
 	client, err := greptimepb.NewGreptimeDatabaseClient(conn).HandleRequests(context.Background(), cfg.CallOptions...)
 	if err != nil {
+		logger.Error("failed to open the stream", "addr", cfg.getGRPCAddr(), "error", err)
 		return nil, err
 	}
 
-	return &StreamClient{client: client, cfg: cfg}, nil
+	logger.Info("stream client is created", "addr", cfg.getGRPCAddr(), "database", cfg.Database)
+	return &StreamClient{client: client, cfg: cfg, metrics: metrics, tracer: cfg.newTracer()}, nil
 }
 
 func (c *StreamClient) Send(ctx context.Context, req InsertsRequest) error {
@@ -49,14 +61,34 @@ func (c *StreamClient) Send(ctx context.Context, req InsertsRequest) error {
 		return err
 	}
 
-	return c.client.Send(request)
+	// the trace context of the stream is propagated when it is created, so the span
+	// is only for the sending
+	_, err = c.observer().invoke(ctx, OperationStreamInsert, request, func(ctx context.Context, call *Call) (proto.Message, error) {
+		request, err := greptimeRequestOf(call)
+		if err != nil {
+			return nil, err
+		}
+		return nil, c.client.Send(request)
+	})
+	return err
 }
 
+// CloseAndRecv closes the stream and receives the affected rows, an [*Error] is
+// returned if greptimedb responds failure.
 func (c *StreamClient) CloseAndRecv(ctx context.Context) (*greptimepb.AffectedRows, error) {
 	resp, err := c.client.CloseAndRecv()
 	if err != nil {
+		err = statusErrorOf(err, c.client.Trailer())
+		c.cfg.getLogger().Error("failed to close the stream", "error", err)
 		return nil, err
 	}
 
+	header := ParseRespHeader(resp)
+	if err := header.Err(); err != nil {
+		c.cfg.getLogger().Error("greptimedb responded error", "operation", OperationStreamInsert,
+			"code", header.Code, "msg", header.Msg)
+		return resp.GetAffectedRows(), err
+	}
+	c.cfg.getLogger().Info("stream is closed", "affected_rows", resp.GetAffectedRows().GetValue())
 	return resp.GetAffectedRows(), nil
 }
@@ -0,0 +1,144 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"fmt"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+)
+
+// ZeroTimestampPolicy is how the Series whose timestamp is not set is inserted,
+// see [Config.WithTimestampValidation]
+type ZeroTimestampPolicy int
+
+const (
+	// ZeroTimestampAsIs inserts the zero time.Time as is, which is a negative epoch
+	// far before 1970. It is the default for compatibility.
+	ZeroTimestampAsIs ZeroTimestampPolicy = iota
+
+	// ZeroTimestampReject rejects the insert with [ErrEmptyTimestamp]
+	ZeroTimestampReject
+
+	// ZeroTimestampServerNow inserts the timestamp as NULL, so that greptimedb
+	// assigns the current time. The timestamp column MUST be created with
+	// `DEFAULT CURRENT_TIMESTAMP()`, otherwise greptimedb rejects the insert.
+	ZeroTimestampServerNow
+)
+
+// TruncationPolicy is how the timestamp finer than the precision of [Metric] is
+// inserted, like 1.5s in second, see [Config.WithTimestampValidation]
+type TruncationPolicy int
+
+const (
+	// TruncationAllowed truncates the timestamp silently, which is the default
+	TruncationAllowed TruncationPolicy = iota
+
+	// TruncationWarn truncates the timestamp, and logs a warning via [Config.WithLogger]
+	TruncationWarn
+
+	// TruncationReject rejects the insert with [ErrTimestampTruncated]
+	TruncationReject
+)
+
+// precision returns the precision of the timestamps, default is millisecond
+func (m *Metric) precision() time.Duration {
+	if m.timestampPrecision == 0 {
+		return time.Millisecond
+	}
+	return m.timestampPrecision
+}
+
+// checkTimestamps validates the timestamps of the series against the policies,
+// and returns the number of the series whose timestamps will be truncated
+func (m *Metric) checkTimestamps(zero ZeroTimestampPolicy, truncation TruncationPolicy) (int, error) {
+	return checkTimestamps(len(m.series), func(i int) time.Time { return m.series[i].timestamp },
+		m.precision(), zero, truncation)
+}
+
+// checkTimestamps validates the n timestamps against the policies, and returns
+// the number of the timestamps which will be truncated
+func checkTimestamps(n int, at func(i int) time.Time, precision time.Duration,
+	zero ZeroTimestampPolicy, truncation TruncationPolicy) (int, error) {
+	truncated := 0
+	for i := 0; i < n; i++ {
+		t := at(i)
+		if t.IsZero() {
+			if zero == ZeroTimestampReject {
+				return 0, fmt.Errorf("%w: series %d", ErrEmptyTimestamp, i)
+			}
+			continue
+		}
+
+		// the precision divides one second, so the nanoseconds are enough
+		if t.Nanosecond()%int(precision) == 0 {
+			continue
+		}
+		if truncation == TruncationReject {
+			return 0, fmt.Errorf("%w: series %d at %s in %s", ErrTimestampTruncated, i,
+				t.Format(time.RFC3339Nano), precision)
+		}
+		truncated++
+	}
+	return truncated, nil
+}
+
+// intoTimestampColumn builds the timestamp column of the n timestamps, the zero
+// timestamps are NULL if the policy is ZeroTimestampServerNow
+func intoTimestampColumn(alias string, precision time.Duration, n int, at func(i int) time.Time,
+	zero ZeroTimestampPolicy) (*greptimepb.Column, error) {
+	datatype, err := precisionToDataType(precision)
+	if err != nil {
+		return nil, err
+	}
+
+	var unix func(t time.Time) int64
+	values := &greptimepb.Column_Values{}
+	var dst *[]int64
+	switch datatype {
+	case greptimepb.ColumnDataType_TIMESTAMP_SECOND:
+		unix, dst = time.Time.Unix, &values.TimestampSecondValues
+	case greptimepb.ColumnDataType_TIMESTAMP_MICROSECOND:
+		unix, dst = time.Time.UnixMicro, &values.TimestampMicrosecondValues
+	case greptimepb.ColumnDataType_TIMESTAMP_NANOSECOND:
+		unix, dst = time.Time.UnixNano, &values.TimestampNanosecondValues
+	default: // greptimepb.ColumnDataType_TIMESTAMP_MILLISECOND
+		unix, dst = time.Time.UnixMilli, &values.TimestampMillisecondValues
+	}
+
+	*dst = make([]int64, 0, n)
+	nullMask := mask{}
+	for i := 0; i < n; i++ {
+		t := at(i)
+		if zero == ZeroTimestampServerNow && t.IsZero() {
+			nullMask.set(uint(i))
+			continue
+		}
+		*dst = append(*dst, unix(t))
+	}
+
+	b, err := nullMask.shrink(nullMaskByteSize(n))
+	if err != nil {
+		return nil, err
+	}
+	return &greptimepb.Column{
+		ColumnName:   alias,
+		SemanticType: greptimepb.SemanticType_TIMESTAMP,
+		Datatype:     datatype,
+		Values:       values,
+		NullMask:     b,
+	}, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func timestampMetric(t *testing.T, precision time.Duration, timestamps ...time.Time) Metric {
+	metric := Metric{}
+	if precision > 0 {
+		assert.Nil(t, metric.SetTimePrecision(precision))
+	}
+	for _, ts := range timestamps {
+		series := Series{}
+		assert.Nil(t, series.AddField("cpu", 0.5))
+		assert.Nil(t, series.SetTimestamp(ts))
+		assert.Nil(t, metric.AddSeries(series))
+	}
+	return metric
+}
+
+func TestCheckTimestamps(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	// zero timestamp
+	metric := timestampMetric(t, 0, start, time.Time{})
+	truncated, err := metric.checkTimestamps(ZeroTimestampAsIs, TruncationReject)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, truncated)
+	_, err = metric.checkTimestamps(ZeroTimestampServerNow, TruncationReject)
+	assert.Nil(t, err)
+	_, err = metric.checkTimestamps(ZeroTimestampReject, TruncationAllowed)
+	assert.ErrorIs(t, err, ErrEmptyTimestamp)
+	assert.ErrorContains(t, err, "series 1")
+
+	// truncated in second
+	metric = timestampMetric(t, time.Second, start, start.Add(1500*time.Millisecond), start.Add(time.Nanosecond))
+	truncated, err = metric.checkTimestamps(ZeroTimestampReject, TruncationAllowed)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, truncated)
+	truncated, err = metric.checkTimestamps(ZeroTimestampReject, TruncationWarn)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, truncated)
+	_, err = metric.checkTimestamps(ZeroTimestampReject, TruncationReject)
+	assert.ErrorIs(t, err, ErrTimestampTruncated)
+	assert.ErrorContains(t, err, "series 1 at 2023-11-14T22:13:21.5Z in 1s")
+
+	// millisecond by default, and nanosecond is never truncated
+	metric = timestampMetric(t, 0, start.Add(time.Microsecond))
+	_, err = metric.checkTimestamps(ZeroTimestampReject, TruncationReject)
+	assert.ErrorIs(t, err, ErrTimestampTruncated)
+	metric = timestampMetric(t, time.Nanosecond, start.Add(time.Nanosecond))
+	_, err = metric.checkTimestamps(ZeroTimestampReject, TruncationReject)
+	assert.Nil(t, err)
+}
+
+func TestServerNowTimestamp(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	metric := timestampMetric(t, 0, start, time.Time{}, start.Add(time.Second))
+
+	cols, err := metric.intoGreptimeColumn(ZeroTimestampServerNow)
+	assert.Nil(t, err)
+	ts := cols[len(cols)-1]
+	assert.Equal(t, []int64{start.UnixMilli(), start.Add(time.Second).UnixMilli()}, ts.Values.TimestampMillisecondValues)
+	assert.Equal(t, []byte{0b010}, ts.NullMask)
+
+	// decoded as zero
+	cfg := &Config{ZeroTimestamp: ZeroTimestampServerNow}
+	req, err := (&InsertRequest{}).WithTable("monitor").WithMetric(metric).build(cfg)
+	assert.Nil(t, err)
+	insert, err := DecodeInsertRequest(req)
+	assert.Nil(t, err)
+	decoded := insert.GetMetric()
+	assert.True(t, decoded.GetSeries()[1].Timestamp().IsZero())
+
+	// the zero timestamp is inserted as is by default
+	cols, err = metric.intoGreptimeColumn(ZeroTimestampAsIs)
+	assert.Nil(t, err)
+	assert.Len(t, cols[len(cols)-1].Values.TimestampMillisecondValues, 3)
+	assert.Empty(t, cols[len(cols)-1].NullMask)
+}
+
+func TestServerNowTimestampLargeBatch(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	timestamps := []time.Time{time.Time{}}
+	for i := 1; i < 600; i++ {
+		timestamps = append(timestamps, start.Add(time.Duration(i)*time.Second))
+	}
+	metric := timestampMetric(t, 0, timestamps...)
+
+	cols, err := metric.intoGreptimeColumn(ZeroTimestampServerNow)
+	assert.Nil(t, err)
+	ts := cols[len(cols)-1]
+	assert.Len(t, ts.Values.TimestampMillisecondValues, 599)
+	assert.Len(t, ts.NullMask, 75)
+	assert.Equal(t, byte(1), ts.NullMask[0])
+
+	cfg := &Config{ZeroTimestamp: ZeroTimestampServerNow}
+	req, err := (&InsertRequest{}).WithTable("monitor").WithMetric(metric).build(cfg)
+	assert.Nil(t, err)
+	insert, err := DecodeInsertRequest(req)
+	assert.Nil(t, err)
+	decoded := insert.GetMetric()
+	assert.Len(t, decoded.GetSeries(), 600)
+	assert.True(t, decoded.GetSeries()[0].Timestamp().IsZero())
+	assert.Equal(t, start.Add(599*time.Second), decoded.GetSeries()[599].Timestamp())
+}
+
+func TestInsertTimestampValidation(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	logger := &recordLogger{}
+	cfg := NewCfg("localhost").WithDatabase("public").WithLogger(logger).
+		WithTimestampValidation(ZeroTimestampReject, TruncationWarn)
+
+	build := func(metric Metric) error {
+		inserts := InsertsRequest{}
+		inserts.Append(*(&InsertRequest{}).WithTable("monitor").WithMetric(metric))
+		_, err := inserts.build(cfg)
+		return err
+	}
+
+	assert.Nil(t, build(timestampMetric(t, time.Second, start, start.Add(time.Millisecond))))
+	assert.Equal(t, []string{"warn: timestamps are truncated [table monitor series 1 precision 1s]"}, logger.logs)
+
+	err := build(timestampMetric(t, time.Second, time.Time{}))
+	assert.ErrorIs(t, err, ErrEmptyTimestamp)
+	assert.ErrorContains(t, err, "invalid timestamp of table monitor")
+
+	cfg.WithTimestampValidation(ZeroTimestampReject, TruncationReject)
+	assert.ErrorIs(t, build(timestampMetric(t, time.Second, start.Add(time.Millisecond))), ErrTimestampTruncated)
+}
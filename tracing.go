@@ -0,0 +1,232 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const tracerName = "github.com/GreptimeTeam/greptimedb-client-go"
+
+// attributes of spans not defined in semantic conventions
+const (
+	attrTables     = attribute.Key("greptime.tables")
+	attrRows       = attribute.Key("greptime.rows")
+	attrStatusCode = attribute.Key("greptime.status_code")
+)
+
+// StatementRedaction decides how the SQL or PromQL is recorded in the `db.statement`
+// attribute of spans, see [Config.WithStatementRedaction].
+type StatementRedaction int
+
+const (
+	// RedactNone records the statement as is
+	RedactNone StatementRedaction = iota
+	// RedactLiterals replaces the string literals with `?`, like `host = '?'` in SQL
+	// and `up{host="?"}` in PromQL
+	RedactLiterals
+	// RedactAll does not record the statement
+	RedactAll
+)
+
+// statement is the SQL or PromQL in the request
+type statement struct {
+	text   string
+	promql bool
+}
+
+func statementOf(request proto.Message) statement {
+	switch r := request.(type) {
+	case *greptimepb.GreptimeRequest:
+		if rq := r.GetQuery().GetPromRangeQuery(); rq != nil {
+			return statement{text: rq.GetQuery(), promql: true}
+		}
+		return statement{text: r.GetQuery().GetSql()}
+	case *greptimepb.PromqlRequest:
+		if rq := r.GetRangeQuery(); rq != nil {
+			return statement{text: rq.GetQuery(), promql: true}
+		}
+		return statement{text: r.GetInstantQuery().GetQuery(), promql: true}
+	default:
+		return statement{}
+	}
+}
+
+func (s statement) redact(redaction StatementRedaction) string {
+	switch redaction {
+	case RedactNone:
+		return s.text
+	case RedactLiterals:
+		if s.promql {
+			return redactLiterals(s.text, `"'`+"`", "", true)
+		}
+		// double quoted is identifier in sql
+		return redactLiterals(s.text, "'", `"`, false)
+	default:
+		return ""
+	}
+}
+
+// redactLiterals replaces the literals quoted by redacted with `?`, and leaves the
+// identifiers quoted by kept as is. The quote is escaped by backslash in promql,
+// and by doubling it in sql.
+func redactLiterals(s, redacted, kept string, backslash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if strings.IndexByte(redacted, c) < 0 && strings.IndexByte(kept, c) < 0 {
+			b.WriteByte(c)
+			continue
+		}
+
+		// backtick quoted string in promql is raw
+		end := closingQuote(s, i, backslash && c != '`')
+		if strings.IndexByte(kept, c) >= 0 {
+			b.WriteString(s[i:end])
+		} else {
+			b.WriteByte(c)
+			b.WriteByte('?')
+			b.WriteByte(c)
+		}
+		i = end - 1
+	}
+	return b.String()
+}
+
+// closingQuote returns the index after the closing quote of the literal starting
+// at i, or len(s) if it is not closed
+func closingQuote(s string, i int, backslash bool) int {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case backslash && s[j] == '\\':
+			j++
+		case s[j] == quote:
+			if !backslash && j+1 < len(s) && s[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// metadataCarrier injects the trace context into gRPC metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// clientTracer starts the spans of requests, see [Config.WithTracing].
+// The methods are no-op if it is nil.
+type clientTracer struct {
+	tracer    trace.Tracer
+	redaction StatementRedaction
+}
+
+// newTracer creates the tracer if TracerProvider is specified
+func (c *Config) newTracer() *clientTracer {
+	if c.TracerProvider == nil {
+		return nil
+	}
+	return &clientTracer{
+		tracer:    c.TracerProvider.Tracer(tracerName),
+		redaction: c.StatementRedaction,
+	}
+}
+
+// start starts the span of the request, and propagates the trace context in the
+// outgoing gRPC metadata in W3C format
+func (t *clientTracer) start(ctx context.Context, op string, request proto.Message, database string, stats []requestStat) (context.Context, trace.Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemKey.String("greptimedb"),
+		semconv.DBName(database),
+		semconv.DBOperation(op),
+	}
+	if s := statementOf(request).redact(t.redaction); len(s) > 0 {
+		attrs = append(attrs, semconv.DBStatement(s))
+	}
+
+	var tables []string
+	var rows int64
+	for _, stat := range stats {
+		if len(stat.table) > 0 {
+			tables = append(tables, stat.table)
+		}
+		rows += int64(stat.rows)
+	}
+	if len(tables) > 0 {
+		attrs = append(attrs, attrTables.StringSlice(tables), attrRows.Int64(rows))
+	}
+
+	ctx, span := t.tracer.Start(ctx, fmt.Sprintf("%s %s", op, database),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	propagation.TraceContext{}.Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+// end records the status of the response and ends the span
+func (t *clientTracer) end(span trace.Span, header RespHeader, err error) {
+	if t == nil || span == nil {
+		return
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attrStatusCode.Int64(int64(header.Code)))
+		if !header.IsSuccess() {
+			span.SetStatus(codes.Error, header.Err().Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Greptime Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package greptime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+)
+
+// fakeGreptimeClient responds the header to Handle
+type fakeGreptimeClient struct {
+	greptimepb.GreptimeDatabaseClient
+	requests []*greptimepb.GreptimeRequest
+	header   *greptimepb.ResponseHeader
+}
+
+func (c *fakeGreptimeClient) Handle(ctx context.Context, in *greptimepb.GreptimeRequest, opts ...grpc.CallOption) (*greptimepb.GreptimeResponse, error) {
+	c.requests = append(c.requests, in)
+	return &greptimepb.GreptimeResponse{Header: c.header}, nil
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range span.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func TestRedactStatement(t *testing.T) {
+	sql := statement{text: `SELECT * FROM "it's" WHERE host = 'a''b' AND region IN ('a', 'b') AND cpu > 0.5`}
+	assert.Equal(t, sql.text, sql.redact(RedactNone))
+	assert.Equal(t, `SELECT * FROM "it's" WHERE host = '?' AND region IN ('?', '?') AND cpu > 0.5`, sql.redact(RedactLiterals))
+	assert.Empty(t, sql.redact(RedactAll))
+
+	promql := statement{text: "sum(rate(http{host=\"a\\\"b\", job='x', path=~`/api/.*`}[5m])) > 10", promql: true}
+	assert.Equal(t, "sum(rate(http{host=\"?\", job='?', path=~`?`}[5m])) > 10", promql.redact(RedactLiterals))
+
+	// unclosed literal
+	assert.Equal(t, "SELECT '?'", statement{text: "SELECT 'abc"}.redact(RedactLiterals))
+}
+
+func TestClientTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	cfg := NewCfg("localhost").WithDatabase("public").WithTracing(tp).WithStatementRedaction(RedactLiterals)
+
+	promqlClient := &fakePromqlClient{}
+	greptimeClient := &fakeGreptimeClient{
+		header: &greptimepb.ResponseHeader{Status: &greptimepb.Status{StatusCode: uint32(StatusRateLimited), ErrMsg: "banned"}},
+	}
+	client := &Client{cfg: cfg, promqlClient: promqlClient, greptimeClient: greptimeClient, tracer: cfg.newTracer()}
+
+	req := NewQueryRequest().WithInstantPromql(NewInstantPromql(`up{host="localhost"}`).WithTime(time.Unix(100, 0)))
+	_, err := client.PromqlQuery(context.Background(), *req)
+	assert.Nil(t, err)
+
+	series := Series{}
+	series.AddTag("host", "localhost")
+	series.AddField("cpu", 0.5)
+	series.SetTimestamp(time.Now())
+	metric := Metric{}
+	metric.AddSeries(series)
+	metric.AddSeries(series)
+	inserts := InsertsRequest{}
+	inserts.Append(*(&InsertRequest{}).WithTable("monitor").WithMetric(metric))
+	inserts.Append(*(&InsertRequest{}).WithTable("cpu").WithMetric(metric))
+	_, err = client.Insert(context.Background(), inserts)
+	assert.ErrorIs(t, err, StatusRateLimited)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+
+	span := spans[0]
+	assert.Equal(t, "promql public", span.Name())
+	attrs := spanAttributes(span)
+	assert.Equal(t, "greptimedb", attrs["db.system"].AsString())
+	assert.Equal(t, "public", attrs["db.name"].AsString())
+	assert.Equal(t, `up{host="?"}`, attrs["db.statement"].AsString())
+	assert.Equal(t, int64(0), attrs[attrStatusCode].AsInt64())
+	assert.Equal(t, codes.Unset, span.Status().Code)
+
+	// the trace context is propagated
+	traceparent := promqlClient.md.Get("traceparent")
+	assert.Len(t, traceparent, 1)
+	assert.Contains(t, traceparent[0], span.SpanContext().TraceID().String())
+
+	span = spans[1]
+	assert.Equal(t, "insert public", span.Name())
+	attrs = spanAttributes(span)
+	assert.Equal(t, []string{"monitor", "cpu"}, attrs[attrTables].AsStringSlice())
+	assert.Equal(t, int64(4), attrs[attrRows].AsInt64())
+	assert.Equal(t, int64(uint32(StatusRateLimited)), attrs[attrStatusCode].AsInt64())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Contains(t, span.Status().Description, "banned")
+}
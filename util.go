@@ -20,7 +20,6 @@ import (
 	"time"
 
 	greptimepb "github.com/GreptimeTeam/greptime-proto/go/greptime/v1"
-	"github.com/stoewer/go-strcase"
 )
 
 type value struct {
@@ -134,16 +133,7 @@ func isEmptyString(s string) bool {
 	return len(strings.TrimSpace(s)) == 0
 }
 
+// toColumnName normalizes the name via the default policy, see [NamingSnakeCase]
 func toColumnName(s string) (string, error) {
-	s = strings.TrimSpace(s)
-
-	if len(s) == 0 {
-		return "", ErrEmptyKey
-	}
-
-	if len(s) >= 100 {
-		return "", fmt.Errorf("the length of column name CAN NOT be longer than 100. %s", s)
-	}
-
-	return strings.ToLower(strcase.SnakeCase(s)), nil
+	return NamingPolicy(nil).normalize(s)
 }